@@ -0,0 +1,174 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// unixTime formats t as fractional seconds since the epoch, the same as
+// Status times. The fraction is formatted exactly, so that times round-trip
+// at the appliance's precision.
+func unixTime(t time.Time) string {
+	if t.Unix() < 0 {
+		return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%09d", t.Nanosecond()), "0")
+	if frac == "" {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return strconv.FormatInt(t.Unix(), 10) + "." + frac
+}
+
+// Chmod sets the permission bits of an object or directory. Bits other than
+// the permission, setuid, setgid and sticky bits are ignored.
+func (tp *TriparClient) Chmod(ctx context.Context, path string, mode int32) (err error) {
+	defer tp.observe(ctx, "Chmod", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "Chmod", Path: path}) {
+		return nil
+	}
+
+	params := tp.cmd("chmod")
+	params.Set("mode", strconv.FormatInt(int64(mode&07777), 8))
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "POST",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("chmod", err)
+		return xerrors.Errorf("chmod request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		tp.caps.observe("chmod", err)
+		return xerrors.Errorf("chmod response error: %w", err)
+	}
+
+	return nil
+}
+
+// Chown sets the owner and group of an object or directory. Changing the
+// owner usually requires the client to be authenticated as a superuser.
+func (tp *TriparClient) Chown(ctx context.Context, path string, uid int32, gid int32) (err error) {
+	defer tp.observe(ctx, "Chown", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "Chown", Path: path}) {
+		return nil
+	}
+
+	params := tp.cmd("chown")
+	params.Set("uid", strconv.FormatInt(int64(uid), 10))
+	params.Set("gid", strconv.FormatInt(int64(gid), 10))
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "POST",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("chown", err)
+		return xerrors.Errorf("chown request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		tp.caps.observe("chown", err)
+		return xerrors.Errorf("chown response error: %w", err)
+	}
+
+	return nil
+}
+
+// Utime sets the access and modification times of an object or directory.
+func (tp *TriparClient) Utime(ctx context.Context, path string, atime time.Time, mtime time.Time) (err error) {
+	defer tp.observe(ctx, "Utime", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "Utime", Path: path}) {
+		return nil
+	}
+
+	params := tp.cmd("utime")
+	params.Set("atime", unixTime(atime))
+	params.Set("mtime", unixTime(mtime))
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "POST",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("utime", err)
+		return xerrors.Errorf("utime request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		tp.caps.observe("utime", err)
+		return xerrors.Errorf("utime response error: %w", err)
+	}
+
+	return nil
+}
+
+// Touch sets the access and modification times of path to now, creating an
+// empty object if it does not exist. The API has no conditional PUT, so a
+// missing object is created by copying an empty temporary object next to it
+// without overwriting, which can't truncate an object another client created
+// in the meantime. The temporary object is briefly visible in listings.
+func (tp *TriparClient) Touch(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "Touch", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "Touch", Path: path}) {
+		return nil
+	}
+
+	now := time.Now()
+	err = tp.Utime(ctx, path, now, now)
+	if errors.Is(err, ErrNotFound) {
+		err = tp.createEmpty(ctx, path)
+		if errors.Is(err, ErrAlreadyExists) {
+			// created concurrently
+			err = tp.Utime(ctx, path, now, now)
+		}
+		if err != nil {
+			return xerrors.Errorf("touch create error: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("touch error: %w", err)
+	}
+
+	return nil
+}
+
+// createEmpty creates an empty object at path, or fails with
+// ErrAlreadyExists if path exists.
+func (tp *TriparClient) createEmpty(ctx context.Context, path string) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := path + ".tmp-" + hex.EncodeToString(suffix)
+
+	if err := tp.putChunk(ctx, tmp, 0, bytes.NewReader(nil), 0); err != nil {
+		return err
+	}
+	defer tp.deleteObject(ctx, tmp)
+
+	return tp.copyObject(ctx, tmp, path, false)
+}
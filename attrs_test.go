@@ -0,0 +1,130 @@
+package triparclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Attributes", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+	})
+
+	Describe("Chmod", func() {
+		It("should set permission bits", func() {
+			Expect(client.Chmod(ctx, "/root/object", 0100600)).To(Succeed())
+
+			info, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.Mode).To(Equal(int32(0100600)))
+		})
+	})
+
+	Describe("Utime", func() {
+		It("should set times", func() {
+			mtime := time.Unix(1500000000, 500000000)
+			Expect(client.Utime(ctx, "/root/object", mtime, mtime)).To(Succeed())
+
+			info, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.Mtime).To(Equal(1500000000.5))
+		})
+
+		It("should round-trip nanoseconds", func() {
+			mtime := time.Unix(1700000000, 123456789)
+			Expect(client.Utime(ctx, "/root/object", mtime, mtime)).To(Succeed())
+
+			info, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.ModTime().Equal(mtime)).To(BeTrue())
+			Expect(info.Status.Mtime).To(BeNumerically("~", 1700000000.123456789, 1e-6))
+		})
+
+		It("should fail for missing objects", func() {
+			Expect(client.Utime(ctx, "/root/missing", time.Now(), time.Now())).To(MatchError(ErrNotFound))
+		})
+	})
+
+	Describe("Status times", func() {
+		It("should parse nanoseconds", func() {
+			var status Status
+			Expect(json.Unmarshal([]byte(`{"atime": 1700000000.000000001, "mtime": 1700000000.5, "ctime": 1700000000}`), &status)).To(Succeed())
+			Expect(status.AccessTime().UnixNano()).To(Equal(int64(1700000000000000001)))
+			Expect(status.ModTime().UnixNano()).To(Equal(int64(1700000000500000000)))
+			Expect(status.ChangeTime().UnixNano()).To(Equal(int64(1700000000000000000)))
+		})
+
+		It("should fall back to the float fields", func() {
+			status := Status{Mtime: 1500000000.25}
+			Expect(status.ModTime().UnixNano()).To(Equal(int64(1500000000250000000)))
+
+			Expect(json.Unmarshal([]byte(`{"mtime": 1.5e9}`), &status)).To(Succeed())
+			Expect(status.ModTime().Unix()).To(Equal(int64(1500000000)))
+		})
+	})
+
+	Describe("Touch", func() {
+		It("should update the mtime of existing objects", func() {
+			start := float64(time.Now().Unix())
+			Expect(client.Touch(ctx, "/root/object")).To(Succeed())
+
+			info, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.Mtime).To(BeNumerically(">=", start))
+			data, _ := fake.File("/root/object")
+			Expect(string(data)).To(Equal("12345"))
+		})
+
+		It("should create missing objects", func() {
+			Expect(client.Touch(ctx, "/root/marker")).To(Succeed())
+			data, ok := fake.File("/root/marker")
+			Expect(ok).To(BeTrue())
+			Expect(data).To(BeEmpty())
+
+			requests := fake.Requests()
+			Expect(requests).To(HaveLen(4))
+			Expect(requests[0]).To(Equal("POST /root/marker utime"))
+			Expect(requests[1]).To(HavePrefix("PUT /root/marker.tmp-"))
+			Expect(requests[2]).To(HavePrefix("PUT /root/marker.tmp-"))
+			Expect(requests[2]).To(HaveSuffix(" cp"))
+			Expect(requests[3]).To(HavePrefix("DELETE /root/marker.tmp-"))
+			Expect(fake.Exists(strings.Fields(requests[1])[1])).To(BeFalse())
+		})
+
+		It("should not truncate objects created concurrently", func() {
+			client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+				rsp, err := fake.RoundTrip(r)
+				if r.URL.Query().Get("cmd") == "utime" && !fake.Exists("/root/marker") {
+					// another client creates the object after the utime failed
+					fake.PutFile("/root/marker", "data")
+				}
+				return rsp, err
+			}))
+
+			Expect(client.Touch(ctx, "/root/marker")).To(Succeed())
+			data, ok := fake.File("/root/marker")
+			Expect(ok).To(BeTrue())
+			Expect(string(data)).To(Equal("data"))
+		})
+
+		It("should fail if the parent does not exist", func() {
+			Expect(client.Touch(ctx, "/missing/marker")).To(MatchError(ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package triparclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// CredentialsProvider returns the current credentials. It is called when the
+// appliance rejects the client's credentials, e.g. after a password rotation.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (user string, pass string, err error)
+}
+
+// authState holds credentials refreshed with a CredentialsProvider, which
+// override the ones the client was created with.
+type authState struct {
+	mx            sync.Mutex
+	authorization string
+	user          string
+	generation    int
+}
+
+func (a *authState) get() (authorization string, user string, generation int) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return a.authorization, a.user, a.generation
+}
+
+func (tp *TriparClient) setAuthorization(req *httpclient.RequestData) (generation int) {
+	authorization, _, generation := tp.auth.get()
+	if authorization != "" {
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
+		req.Headers.Set("Authorization", authorization)
+	}
+	return generation
+}
+
+// refreshCredentials gets new credentials from the CredentialsProvider unless
+// they were already refreshed by a concurrent request since generation.
+func (tp *TriparClient) refreshCredentials(ctx context.Context, generation int) error {
+	tp.auth.mx.Lock()
+	defer tp.auth.mx.Unlock()
+
+	if tp.auth.generation != generation {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	user, pass, err := tp.CredentialsProvider.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	tp.auth.authorization = basicAuth(user, pass)
+	tp.auth.user = user
+	tp.auth.generation++
+
+	return nil
+}
+
+// doAuthenticatedRequest refreshes the credentials and repeats the request
+// once if it was rejected with 401. The request was not executed, so it is
+// safe to repeat it if the body can be rewound.
+func (tp *TriparClient) doAuthenticatedRequest(req *httpclient.RequestData) (response *http.Response, err error) {
+	generation := tp.setAuthorization(req)
+
+	response, err = tp.doRequest(req)
+	if err == nil || tp.CredentialsProvider == nil || !httpclient.IsInvalidStatusCode(err, http.StatusUnauthorized) {
+		return response, err
+	}
+
+	if req.ReqReader != nil {
+		seeker, ok := req.ReqReader.(io.Seeker)
+		if !ok {
+			return response, err
+		}
+		if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr != nil {
+			return response, err
+		}
+	}
+
+	if refreshErr := tp.refreshCredentials(req.Context, generation); refreshErr != nil {
+		return response, xerrors.Errorf("refresh credentials error: %v: %w", refreshErr, redactError(err))
+	}
+
+	tp.setAuthorization(req)
+
+	return tp.doRequest(req)
+}
@@ -0,0 +1,89 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type funcCredentialsProvider func(ctx context.Context) (string, string, error)
+
+func (f funcCredentialsProvider) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+var _ = Describe("CredentialsProvider", func() {
+	var client *TriparClient
+	var requests int32
+
+	BeforeEach(func() {
+		requests = 0
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			// basic auth for rotated:secret
+			if r.Header.Get("Authorization") != "Basic cm90YXRlZDpzZWNyZXQ=" {
+				return testResponse(http.StatusUnauthorized, "text/plain", "Unauthorized"), nil
+			}
+			return testStatResponse(5), nil
+		}))
+	})
+
+	It("should refresh credentials and repeat the request", func() {
+		calls := 0
+		client.CredentialsProvider = funcCredentialsProvider(func(ctx context.Context) (string, string, error) {
+			calls++
+			return "rotated", "secret", nil
+		})
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(1))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(1))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+
+		identity, err := client.WhoAmI(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity.User).To(Equal("rotated"))
+	})
+
+	It("should refresh credentials only once per request", func() {
+		calls := 0
+		client.CredentialsProvider = funcCredentialsProvider(func(ctx context.Context) (string, string, error) {
+			calls++
+			return "user", "wrong", nil
+		})
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrUnauthorized))
+		Expect(calls).To(Equal(1))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+	})
+
+	It("should return the provider error", func() {
+		client.CredentialsProvider = funcCredentialsProvider(func(ctx context.Context) (string, string, error) {
+			return "", "", errors.New("vault unavailable")
+		})
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrUnauthorized))
+		Expect(err.Error()).To(ContainSubstring("vault unavailable"))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should not refresh credentials without a provider", func() {
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrUnauthorized))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,200 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type BenchmarkOptions struct {
+	// Path is the scratch directory the probes use. A new directory is
+	// created in it for every run and deleted afterwards.
+	Path string
+
+	// Sizes are the object sizes to probe, 4 KiB and 1 MiB by default.
+	Sizes []int64
+
+	// Concurrency is the number of requests running at the same time, 1 by
+	// default.
+	Concurrency int
+
+	// Iterations is the number of objects written and read per size, 10 by
+	// default.
+	Iterations int
+}
+
+// BenchmarkProbe is the result of writing or reading Count objects of Size
+// bytes with Concurrency requests at a time.
+type BenchmarkProbe struct {
+	// Op is "write" or "read".
+	Op          string
+	Size        int64
+	Concurrency int
+
+	Count  int
+	Errors int
+
+	// FirstError is the first error of a failed request, if any.
+	FirstError error
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+
+	// Throughput is the number of bytes per second transferred by the
+	// successful requests over the duration of the whole probe.
+	Throughput float64
+}
+
+type BenchmarkResult struct {
+	Probes []BenchmarkProbe
+}
+
+// Benchmark writes and reads objects of different sizes in a scratch
+// directory and reports latency percentiles and throughput, to check the
+// health of the appliance and the network from the application host. It
+// returns an error only if the scratch directory can't be created, failed
+// requests are counted in the probes.
+func (tp *TriparClient) Benchmark(ctx context.Context, opts *BenchmarkOptions) (result *BenchmarkResult, err error) {
+	if opts == nil || opts.Path == "" {
+		return nil, xerrors.Errorf("benchmark path is required")
+	}
+	sizes := opts.Sizes
+	if len(sizes) == 0 {
+		sizes = []int64{4 << 10, 1 << 20}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = 10
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, xerrors.Errorf("benchmark id error: %w", err)
+	}
+	dir := joinPath(opts.Path, "triparclient-benchmark-"+hex.EncodeToString(suffix))
+
+	if err := tp.CreateDirectories(ctx, dir); err != nil {
+		return nil, xerrors.Errorf("benchmark create directory error: %w", err)
+	}
+	defer func() {
+		tp.Purge(context.WithoutCancel(ctx), dir, nil)
+		tp.DeleteDirectory(context.WithoutCancel(ctx), dir)
+	}()
+
+	result = &BenchmarkResult{}
+
+	for _, size := range sizes {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			return nil, xerrors.Errorf("benchmark data error: %w", err)
+		}
+		name := func(i int) string {
+			return joinPath(dir, fmt.Sprintf("%d-%d", size, i))
+		}
+
+		write := benchmarkProbe(ctx, "write", size, concurrency, iterations, func(i int) error {
+			return tp.PutObject(ctx, name(i), bytes.NewReader(data), PutSizeHint(size))
+		})
+		read := benchmarkProbe(ctx, "read", size, concurrency, iterations, func(i int) error {
+			n, err := tp.GetObjectTo(ctx, name(i), io.Discard)
+			if err == nil && n != size {
+				err = xerrors.Errorf("benchmark read %d of %d bytes", n, size)
+			}
+			return err
+		})
+
+		result.Probes = append(result.Probes, write, read)
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func benchmarkProbe(
+	ctx context.Context,
+	op string,
+	size int64,
+	concurrency int,
+	iterations int,
+	do func(i int) error,
+) BenchmarkProbe {
+	probe := BenchmarkProbe{
+		Op:          op,
+		Size:        size,
+		Concurrency: concurrency,
+		Count:       iterations,
+	}
+
+	var mx sync.Mutex
+	latencies := make([]time.Duration, 0, iterations)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				started := time.Now()
+				err := do(i)
+				d := time.Since(started)
+
+				mx.Lock()
+				if err != nil {
+					probe.Errors++
+					if probe.FirstError == nil {
+						probe.FirstError = err
+					}
+				} else {
+					latencies = append(latencies, d)
+				}
+				mx.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < iterations; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if len(latencies) == 0 {
+		return probe
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	probe.P50 = percentile(0.5)
+	probe.P90 = percentile(0.9)
+	probe.P99 = percentile(0.99)
+	probe.Max = latencies[len(latencies)-1]
+	if elapsed > 0 {
+		probe.Throughput = float64(size) * float64(len(latencies)) / elapsed.Seconds()
+	}
+
+	return probe
+}
@@ -0,0 +1,68 @@
+package triparclient_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Benchmark", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/scratch")
+	})
+
+	It("should probe reads and writes and clean up", func() {
+		result, err := client.Benchmark(ctx, &BenchmarkOptions{
+			Path:        "/scratch",
+			Sizes:       []int64{100, 3000},
+			Concurrency: 3,
+			Iterations:  7,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Probes).To(HaveLen(4))
+
+		for i, probe := range result.Probes {
+			Expect(probe.Op).To(Equal([]string{"write", "read"}[i%2]))
+			Expect(probe.Size).To(Equal([]int64{100, 100, 3000, 3000}[i]))
+			Expect(probe.Concurrency).To(Equal(3))
+			Expect(probe.Count).To(Equal(7))
+			Expect(probe.Errors).To(Equal(0))
+			Expect(probe.P50).To(BeNumerically("<=", probe.P90))
+			Expect(probe.P90).To(BeNumerically("<=", probe.P99))
+			Expect(probe.P99).To(BeNumerically("<=", probe.Max))
+			Expect(probe.Throughput).To(BeNumerically(">", 0))
+		}
+
+		entries, err := client.List(ctx, "/scratch")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(BeEmpty())
+	})
+
+	It("should count failed requests", func() {
+		fake.Unsupport("")
+
+		result, err := client.Benchmark(ctx, &BenchmarkOptions{
+			Path:       "/scratch",
+			Sizes:      []int64{10},
+			Iterations: 2,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Probes[0].Errors).To(Equal(2))
+		Expect(result.Probes[0].FirstError).To(MatchError(ErrNotSupported))
+		Expect(result.Probes[1].Errors).To(Equal(2))
+	})
+
+	It("should require a path", func() {
+		_, err := client.Benchmark(ctx, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
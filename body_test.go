@@ -0,0 +1,156 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+// bodyTracker wraps response bodies to detect bodies which are not closed or
+// closed more than once.
+type bodyTracker struct {
+	mx     sync.Mutex
+	closes []int
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *bodyTracker
+	i       int
+}
+
+func (b *trackedBody) Close() error {
+	b.tracker.mx.Lock()
+	b.tracker.closes[b.i]++
+	b.tracker.mx.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func (t *bodyTracker) wrap(transport http.RoundTripper) http.RoundTripper {
+	return funcTransport(func(r *http.Request) (*http.Response, error) {
+		rsp, err := transport.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		t.mx.Lock()
+		defer t.mx.Unlock()
+		rsp.Body = &trackedBody{
+			ReadCloser: rsp.Body,
+			tracker:    t,
+			i:          len(t.closes),
+		}
+		t.closes = append(t.closes, 0)
+		return rsp, nil
+	})
+}
+
+func (t *bodyTracker) Closes() []int {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return append([]int{}, t.closes...)
+}
+
+func allOnes(n int) []int {
+	ones := make([]int, n)
+	for i := range ones {
+		ones[i] = 1
+	}
+	return ones
+}
+
+var _ = Describe("Response bodies", func() {
+	var ctx context.Context
+	var tracker *bodyTracker
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		tracker = &bodyTracker{}
+	})
+
+	It("should be closed exactly once on success and tripar errors", func() {
+		client, fake := newFakeTriparClient()
+		client.HTTPClient.Client.Transport = tracker.wrap(fake)
+		fake.Mkdir("/root")
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("0123456789"))).To(Succeed())
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Stat(ctx, "/root/missing")
+		Expect(err).To(HaveOccurred())
+		_, err = client.List(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = client.GetObject(ctx, "/root/missing", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(client.CreateDirectory(ctx, "/root")).NotTo(Succeed())
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 0, End: 9}, GetChunkSize(3))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		Expect(tracker.Closes()).To(Equal(allOnes(len(tracker.Closes()))))
+	})
+
+	It("should be closed exactly once on unexpected statuses and content types", func() {
+		var rsp func() *http.Response
+		client := newTestClient(tracker.wrap(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Query().Get("cmd") == "stat" {
+				return testStatResponse(5), nil
+			}
+			return rsp(), nil
+		})))
+
+		rsp = func() *http.Response {
+			return testResponse(http.StatusInternalServerError, "text/html", "<html>error</html>")
+		}
+		_, _, err := client.GetObject(ctx, "/object", nil)
+		Expect(err).To(HaveOccurred())
+
+		rsp = func() *http.Response {
+			return testResponse(http.StatusOK, "text/html", "<html>maintenance</html>")
+		}
+		_, _, err = client.GetObject(ctx, "/object", nil)
+		Expect(err).To(MatchError(ContainSubstring("unexpected content-type")))
+
+		rsp = func() *http.Response {
+			return testResponse(http.StatusOK, "application/json", `{"ok": true}`)
+		}
+		_, _, err = client.GetObject(ctx, "/object", nil)
+		Expect(err).To(MatchError("getObjectComplete error: unexpected content-type: application/json"))
+
+		Expect(tracker.Closes()).To(Equal(allOnes(6)))
+	})
+
+	It("should be closed when a chunked get is closed early", func() {
+		client, fake := newFakeTriparClient()
+		client.HTTPClient.Client.Transport = tracker.wrap(fake)
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "0123456789")
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 0, End: 9}, GetChunkSize(3))
+		Expect(err).NotTo(HaveOccurred())
+		buf := make([]byte, 2)
+		_, err = io.ReadFull(rd, buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		Eventually(func() []int {
+			closes := tracker.Closes()
+			for i := range closes {
+				if closes[i] != 1 {
+					return closes
+				}
+			}
+			return nil
+		}).Should(BeNil())
+	})
+})
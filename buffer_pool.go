@@ -2,9 +2,17 @@ package triparclient
 
 import (
 	"container/list"
+	"context"
 	"sync"
 )
 
+const (
+	// DefaultBufferPoolCapacity and DefaultBufferSize size the pool which
+	// NewTriparClient creates if it is not given one.
+	DefaultBufferPoolCapacity = 4
+	DefaultBufferSize         = 4 * 1024 * 1024
+)
+
 type BufferPoolIface interface {
 	Get() []byte
 	Put(buffer []byte)
@@ -17,6 +25,7 @@ type BufferPool struct {
 	cond       *sync.Cond
 	bufferSize int64
 	buffers    *list.List
+	released   chan struct{}
 }
 
 func NewBufferPool(capacity int, bufferSize int64) *BufferPool {
@@ -25,6 +34,7 @@ func NewBufferPool(capacity int, bufferSize int64) *BufferPool {
 		size:       0,
 		bufferSize: bufferSize,
 		buffers:    list.New(),
+		released:   make(chan struct{}),
 	}
 
 	bp.cond = sync.NewCond(&bp.mx)
@@ -32,6 +42,21 @@ func NewBufferPool(capacity int, bufferSize int64) *BufferPool {
 	return bp
 }
 
+func (bp *BufferPool) BufferSize() int64 {
+	return bp.bufferSize
+}
+
+func (bp *BufferPool) Capacity() int {
+	return bp.cap
+}
+
+func (bp *BufferPool) InUse() int {
+	bp.mx.Lock()
+	defer bp.mx.Unlock()
+
+	return bp.size - bp.buffers.Len()
+}
+
 func (bp *BufferPool) Get() []byte {
 	bp.mx.Lock()
 	defer bp.mx.Unlock()
@@ -50,10 +75,53 @@ func (bp *BufferPool) Get() []byte {
 	return front.Value.([]byte)
 }
 
+// GetContext is like Get, but returns the context's error if it is done
+// before a buffer is available.
+func (bp *BufferPool) GetContext(ctx context.Context) ([]byte, error) {
+	for {
+		bp.mx.Lock()
+		buffer, ok := bp.tryGet()
+		released := bp.released
+		bp.mx.Unlock()
+		if ok {
+			return buffer, nil
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryGet returns a buffer if one is available without waiting.
+func (bp *BufferPool) TryGet() ([]byte, bool) {
+	bp.mx.Lock()
+	defer bp.mx.Unlock()
+
+	return bp.tryGet()
+}
+
+func (bp *BufferPool) tryGet() ([]byte, bool) {
+	if bp.buffers.Len() == 0 {
+		if bp.size >= bp.cap {
+			return nil, false
+		}
+		bp.size++
+		return make([]byte, bp.bufferSize), true
+	}
+	front := bp.buffers.Front()
+	bp.buffers.Remove(front)
+	return front.Value.([]byte), true
+}
+
 func (bp *BufferPool) Put(buffer []byte) {
 	bp.mx.Lock()
 	defer bp.mx.Unlock()
 
 	bp.buffers.PushFront(buffer)
 	bp.cond.Signal()
+	close(bp.released)
+	bp.released = make(chan struct{})
 }
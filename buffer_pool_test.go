@@ -1,6 +1,7 @@
 package triparclient
 
 import (
+	"context"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2/dsl/core"
@@ -28,4 +29,49 @@ var _ = Describe("BufferPool", func() {
 			bp.Put(b3)
 		})
 	})
+
+	Describe("GetContext", func() {
+		It("should stop waiting once the context is done", func() {
+			bp := NewBufferPool(1, 10)
+			b1 := bp.Get()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := bp.GetContext(ctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				bp.Put(b1)
+			}()
+			b2, err := bp.GetContext(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			bp.Put(b2)
+		})
+	})
+
+	Describe("TryGet", func() {
+		It("should not wait if size >= cap", func() {
+			bp := NewBufferPool(1, 10)
+
+			b1, ok := bp.TryGet()
+			Expect(ok).To(BeTrue())
+			_, ok = bp.TryGet()
+			Expect(ok).To(BeFalse())
+
+			bp.Put(b1)
+			_, ok = bp.TryGet()
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	It("should be created by NewTriparClient if none is given", func() {
+		tp, err := NewTriparClient("http://tripar.example.com", "user", "pass", "share", nil, 1024)
+		Expect(err).NotTo(HaveOccurred())
+
+		bp, ok := tp.bufferPool.(*BufferPool)
+		Expect(ok).To(BeTrue())
+		Expect(bp.Capacity()).To(Equal(DefaultBufferPoolCapacity))
+		Expect(bp.BufferSize()).To(Equal(int64(DefaultBufferSize)))
+	})
 })
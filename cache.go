@@ -0,0 +1,155 @@
+package triparclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/xerrors"
+)
+
+// cacheKey identifies a version of the object at path, so that an object
+// which is modified gets a new cache file.
+func (tp *TriparClient) cacheKey(path string, stat *Stat) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%d\n%d", tp.HTTPClient.BaseURL, tp.path(path), stat.Status.ModTime().UnixNano(), stat.Status.Size)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getObjectCached serves the object from the cache file in dir if there is
+// one. Otherwise the object is downloaded and, if the whole object is read,
+// written to the cache while it is read. Failing to write the cache does not
+// fail the read.
+func (tp *TriparClient) getObjectCached(
+	ctx context.Context,
+	path string,
+	span *ioutils.FileSpan,
+	stat *Stat,
+	chunkSize int64,
+	dir string,
+) (rd io.ReadCloser, err error) {
+	key := tp.cacheKey(path, stat)
+	cachePath := filepath.Join(dir, key[:2], key)
+
+	rd, err = openCached(cachePath, span, stat.Status.Size)
+	if err == nil {
+		stat.ContentType = octetStream
+		return rd, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, xerrors.Errorf("get object cache error: %w", err)
+	}
+
+	rd, err = tp.getObjectUncached(ctx, path, span, stat, chunkSize)
+	if err != nil || span != nil {
+		return rd, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		return rd, nil
+	}
+	file, err := os.CreateTemp(filepath.Dir(cachePath), key+".*.tmp")
+	if err != nil {
+		return rd, nil
+	}
+
+	return &cachingReadCloser{
+		ReadCloser: rd,
+		file:       file,
+		path:       cachePath,
+		size:       stat.Status.Size,
+	}, nil
+}
+
+func openCached(cachePath string, span *ioutils.FileSpan, size int64) (io.ReadCloser, error) {
+	file, err := os.Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() != size {
+		// left over by a crash or modified, download it again
+		file.Close()
+		os.Remove(cachePath)
+		return nil, fs.ErrNotExist
+	}
+
+	rd, err := ioutils.ApplyFileSpan(file, span)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &cachedReadCloser{Reader: rd, Closer: file}, nil
+}
+
+type cachedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cachingReadCloser writes everything it reads to file, which is moved to
+// path once all size bytes have been read. Otherwise it is removed on Close.
+type cachingReadCloser struct {
+	io.ReadCloser
+	file    *os.File
+	path    string
+	size    int64
+	written int64
+}
+
+func (r *cachingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if r.file != nil && n > 0 {
+		if _, werr := r.file.Write(p[:n]); werr != nil {
+			r.discard()
+		} else {
+			r.written += int64(n)
+		}
+	}
+	if r.file != nil && errors.Is(err, io.EOF) {
+		r.commit()
+	}
+	return n, err
+}
+
+func (r *cachingReadCloser) Close() error {
+	if r.file != nil {
+		r.discard()
+	}
+	return r.ReadCloser.Close()
+}
+
+func (r *cachingReadCloser) commit() {
+	if r.written != r.size {
+		r.discard()
+		return
+	}
+	name := r.file.Name()
+	err := r.file.Close()
+	r.file = nil
+	if err == nil {
+		err = os.Rename(name, r.path)
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+}
+
+func (r *cachingReadCloser) discard() {
+	r.file.Close()
+	os.Remove(r.file.Name())
+	r.file = nil
+}
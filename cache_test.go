@@ -0,0 +1,80 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"os"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("GetObject CacheDir", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var dir string
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		var err error
+		dir, err = os.MkdirTemp("", "triparclient-cache")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+
+		fake.PutFile("/object", "0123456789")
+	})
+
+	read := func(span *ioutils.FileSpan) string {
+		rd, info, err := client.GetObject(ctx, "/object", span, GetCacheDir(dir))
+		Expect(err).NotTo(HaveOccurred())
+		defer rd.Close()
+		Expect(info.ContentType).To(Equal("application/octet-stream"))
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	gets := func() (n int) {
+		for _, req := range fake.Requests() {
+			if req == "GET /object" {
+				n++
+			}
+		}
+		return n
+	}
+
+	It("should serve repeated reads from the cache", func() {
+		Expect(read(nil)).To(Equal("0123456789"))
+		Expect(read(nil)).To(Equal("0123456789"))
+		Expect(read(&ioutils.FileSpan{Start: 2, End: 4})).To(Equal("234"))
+		Expect(gets()).To(Equal(1))
+	})
+
+	It("should download modified objects again", func() {
+		Expect(read(nil)).To(Equal("0123456789"))
+		fake.PutFile("/object", "abc")
+		Expect(read(nil)).To(Equal("abc"))
+		Expect(gets()).To(Equal(2))
+	})
+
+	It("should not cache ranged or incomplete reads", func() {
+		Expect(read(&ioutils.FileSpan{Start: 2, End: 4})).To(Equal("234"))
+
+		rd, _, err := client.GetObject(ctx, "/object", nil, GetCacheDir(dir))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rd.Read(make([]byte, 3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		Expect(read(nil)).To(Equal("0123456789"))
+		Expect(gets()).To(Equal(3))
+		Expect(read(nil)).To(Equal("0123456789"))
+		Expect(gets()).To(Equal(3))
+	})
+})
@@ -0,0 +1,40 @@
+package triparclient
+
+import (
+	"errors"
+	"sync"
+)
+
+// capabilities remembers commands the appliance rejected with
+// ErrNotSupported, so operations depending on them can fail or be skipped
+// without issuing requests. It is shared by the client and its clones, as
+// they talk to the same appliance.
+type capabilities struct {
+	mx          sync.Mutex
+	unsupported map[string]bool
+}
+
+func newCapabilities() *capabilities {
+	return &capabilities{
+		unsupported: map[string]bool{},
+	}
+}
+
+func (c *capabilities) supported(cmd string) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return !c.unsupported[cmd]
+}
+
+// observe records cmd as unsupported if err is ErrNotSupported.
+func (c *capabilities) observe(cmd string, err error) {
+	if !errors.Is(err, ErrNotSupported) {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.unsupported[cmd] = true
+}
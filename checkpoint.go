@@ -0,0 +1,196 @@
+package triparclient
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// DefaultCheckpointInterval is how often completed items are recorded if
+// the operation's options don't set an interval.
+const DefaultCheckpointInterval = 10 * time.Second
+
+// Checkpoint stores the items a long-running operation has completed, so
+// that the operation can be resumed after a crash or cancellation. Items are
+// paths, their meaning depends on the operation.
+type Checkpoint interface {
+	// Completed returns the items recorded by earlier runs.
+	Completed() ([]string, error)
+
+	// Record stores items completed since the last call. It is called
+	// periodically and when the operation returns, also if it fails.
+	Record(items []string) error
+
+	// Done is called when the operation has succeeded, e.g. to delete the
+	// stored items.
+	Done() error
+}
+
+// FileCheckpoint is a Checkpoint which appends items to a local file, one per
+// line. The file is deleted once the operation succeeds.
+type FileCheckpoint struct {
+	Path string
+}
+
+var _ Checkpoint = (*FileCheckpoint)(nil)
+
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{
+		Path: path,
+	}
+}
+
+func (c *FileCheckpoint) Completed() ([]string, error) {
+	file, err := os.Open(c.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	items := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// a crash can leave a partial last line, which is only a missed item
+		if line := scanner.Text(); line != "" {
+			items = append(items, line)
+		}
+	}
+
+	return items, scanner.Err()
+}
+
+func (c *FileCheckpoint) Record(items []string) error {
+	file, err := os.OpenFile(c.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(strings.Join(items, "\n") + "\n")
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (c *FileCheckpoint) Done() error {
+	if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// checkpointer batches completed items for a Checkpoint. A nil Checkpoint
+// makes it a no-op.
+type checkpointer struct {
+	cp       Checkpoint
+	interval time.Duration
+
+	completed map[string]bool
+	pending   []string
+	flushed   time.Time
+}
+
+// newCheckpointer returns a checkpointer for cp. A client in dry-run mode
+// skips the items which an earlier run completed, but records nothing, so
+// that a later run does not skip the items which were only planned.
+func (tp *TriparClient) newCheckpointer(cp Checkpoint, interval time.Duration) (*checkpointer, error) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	c := &checkpointer{
+		cp:        cp,
+		interval:  interval,
+		completed: map[string]bool{},
+		flushed:   time.Now(),
+	}
+
+	if cp == nil {
+		return c, nil
+	}
+
+	items, err := cp.Completed()
+	if err != nil {
+		return nil, xerrors.Errorf("checkpoint load error: %w", err)
+	}
+	for _, item := range items {
+		c.completed[item] = true
+	}
+
+	if tp.dryRun != nil {
+		c.cp = nil
+	}
+
+	return c, nil
+}
+
+// resumed reports whether an earlier run completed any items.
+func (c *checkpointer) resumed() bool {
+	return len(c.completed) > 0
+}
+
+func (c *checkpointer) done(item string) bool {
+	return c.completed[item]
+}
+
+func (c *checkpointer) record(item string) error {
+	if c.cp == nil {
+		return nil
+	}
+
+	c.completed[item] = true
+	c.pending = append(c.pending, item)
+
+	if time.Since(c.flushed) < c.interval {
+		return nil
+	}
+	return c.flush()
+}
+
+func (c *checkpointer) flush() error {
+	c.flushed = time.Now()
+
+	if c.cp == nil || len(c.pending) == 0 {
+		return nil
+	}
+
+	if err := c.cp.Record(c.pending); err != nil {
+		return xerrors.Errorf("checkpoint record error: %w", err)
+	}
+	c.pending = nil
+
+	return nil
+}
+
+// finish records the pending items and, if the operation succeeded, marks
+// the checkpoint done. It returns err or the checkpoint's error.
+func (c *checkpointer) finish(err error) error {
+	if c.cp == nil {
+		return err
+	}
+
+	if ferr := c.flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.cp.Done(); err != nil {
+		return xerrors.Errorf("checkpoint done error: %w", err)
+	}
+
+	return nil
+}
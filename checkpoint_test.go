@@ -0,0 +1,130 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failPath atomic.Value
+	var checkpoint *FileCheckpoint
+
+	countRequests := func(request string) (n int) {
+		for _, req := range fake.Requests() {
+			if req == request {
+				n++
+			}
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		failPath.Store("")
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			fail := failPath.Load().(string)
+			path := r.URL.Opaque + r.URL.Path
+			dst := r.URL.Query().Get("destination")
+			if r.Method != http.MethodGet && fail != "" && (strings.HasSuffix(path, fail) || strings.HasSuffix(dst, fail)) {
+				return testResponse(http.StatusInternalServerError, "text/plain", "error"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		dir, err := os.MkdirTemp("", "triparclient-checkpoint")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		checkpoint = NewFileCheckpoint(filepath.Join(dir, "checkpoint"))
+	})
+
+	It("should record and load items", func() {
+		items, err := checkpoint.Completed()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(BeEmpty())
+
+		Expect(checkpoint.Record([]string{"/a", "/b"})).To(Succeed())
+		Expect(checkpoint.Record([]string{"/c"})).To(Succeed())
+		items, err = checkpoint.Completed()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]string{"/a", "/b", "/c"}))
+
+		Expect(checkpoint.Done()).To(Succeed())
+		Expect(checkpoint.Path).NotTo(BeAnExistingFile())
+	})
+
+	It("should resume CopyTree", func() {
+		fake.Mkdir("/src")
+		fake.PutFile("/src/a", "a")
+		fake.PutFile("/src/b", "b")
+		fake.PutFile("/src/c", "c")
+
+		failPath.Store("/dst/b")
+		opts := &CopyOptions{Checkpoint: checkpoint}
+		Expect(client.CopyTree(ctx, "/src", "/dst", opts)).NotTo(Succeed())
+		items, err := checkpoint.Completed()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]string{"/src/a"}))
+
+		failPath.Store("")
+		Expect(client.CopyTree(ctx, "/src", "/dst", opts)).To(Succeed())
+		Expect(countRequests("PUT /src/a cp")).To(Equal(1))
+		Expect(countRequests("PUT /src/b cp")).To(Equal(1))
+		data, _ := fake.File("/dst/c")
+		Expect(string(data)).To(Equal("c"))
+		Expect(checkpoint.Path).NotTo(BeAnExistingFile())
+	})
+
+	It("should resume Mirror", func() {
+		local, err := os.MkdirTemp("", "triparclient-checkpoint-local")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, local)
+		for _, name := range []string{"a", "b"} {
+			Expect(os.WriteFile(filepath.Join(local, name), []byte(name), 0o644)).To(Succeed())
+		}
+
+		failPath.Store("/remote/b")
+		opts := &SyncOptions{Checkpoint: checkpoint}
+		_, err = client.Mirror(ctx, local, "/remote", opts)
+		Expect(err).To(HaveOccurred())
+
+		failPath.Store("")
+		result, err := client.Mirror(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Uploaded: 1, Unchanged: 1, Bytes: 1}))
+		Expect(countRequests("PUT /remote/a")).To(Equal(1))
+		Expect(checkpoint.Path).NotTo(BeAnExistingFile())
+	})
+
+	It("should resume DeleteTree", func() {
+		fake.Mkdir("/dir")
+		fake.Mkdir("/dir/sub")
+		fake.PutFile("/dir/sub/a", "a")
+		fake.PutFile("/dir/b", "b")
+
+		failPath.Store("/dir")
+		opts := &DeleteTreeOptions{Checkpoint: checkpoint}
+		Expect(client.DeleteTreeWithOptions(ctx, "/dir", opts)).NotTo(Succeed())
+		Expect(fake.Exists("/dir/sub")).To(BeFalse())
+		Expect(fake.Exists("/dir/b")).To(BeFalse())
+
+		// deleted by someone else in the meantime
+		failPath.Store("")
+		Expect(client.DeleteDirectory(ctx, "/dir")).To(Succeed())
+
+		Expect(client.DeleteTreeWithOptions(ctx, "/dir", opts)).To(Succeed())
+		Expect(checkpoint.Path).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,249 @@
+package triparclient
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrChecksumMismatch             = errors.New("checksum mismatch")
+	ErrUnsupportedChecksumAlgorithm = errors.New("unsupported checksum algorithm")
+)
+
+type ChecksumAlgorithm string
+
+const (
+	ChecksumMD5    ChecksumAlgorithm = "md5"
+	ChecksumSHA1   ChecksumAlgorithm = "sha1"
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumCRC32C ChecksumAlgorithm = "crc32c"
+)
+
+func (a ChecksumAlgorithm) newHash() (hash.Hash, bool) {
+	switch a {
+	case ChecksumMD5:
+		return md5.New(), true
+	case ChecksumSHA1:
+		return sha1.New(), true
+	case ChecksumSHA256:
+		return sha256.New(), true
+	case ChecksumCRC32C:
+		return crc32.New(castagnoli), true
+	default:
+		return nil, false
+	}
+}
+
+type Checksum struct {
+	Algorithm ChecksumAlgorithm
+
+	// Value is the hex encoded checksum.
+	Value string
+
+	// ServerSide is set if the checksum was computed by the appliance rather
+	// than by downloading the object.
+	ServerSide bool
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChunkChecksumError is returned by PutObject and GetObject with VerifyChunks
+// if the CRC32C of a chunk differs from the CRC32C of the same range read
+// back from the appliance. It matches ErrChecksumMismatch.
+type ChunkChecksumError struct {
+	Path     string
+	Offset   int64
+	Size     int64
+	Expected uint32
+	Actual   uint32
+}
+
+func (e *ChunkChecksumError) Error() string {
+	return fmt.Sprintf(
+		"checksum mismatch in %s at bytes %d-%d: expected crc32c %08x, got %08x",
+		e.Path, e.Offset, e.Offset+e.Size-1, e.Expected, e.Actual,
+	)
+}
+
+func (e *ChunkChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// verifyChunk reads size bytes at offset and compares their CRC32C to
+// expected.
+func (tp *TriparClient) verifyChunk(ctx context.Context, path string, offset int64, size int64, expected uint32) error {
+	// downloads are verified while their transfer holds a slot
+	rsp, err := tp.getObjectResponse(unscheduled(ctx), path, &ioutils.FileSpan{Start: offset, End: offset + size - 1})
+	if err != nil {
+		return xerrors.Errorf("verify chunk error: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	crc := crc32.New(castagnoli)
+	n, err := io.Copy(crc, rsp.Body)
+	if err != nil {
+		return xerrors.Errorf("verify chunk read error: %w", err)
+	}
+
+	if n != size || crc.Sum32() != expected {
+		return &ChunkChecksumError{
+			Path:     path,
+			Offset:   offset,
+			Size:     size,
+			Expected: expected,
+			Actual:   crc.Sum32(),
+		}
+	}
+
+	return nil
+}
+
+// chunkVerifyReader verifies every chunkSize bytes read from ReadCloser by
+// reading the same range again. A mismatch is returned as the error of the
+// Read which completes the chunk.
+type chunkVerifyReader struct {
+	io.ReadCloser
+	tp        *TriparClient
+	ctx       context.Context
+	path      string
+	chunkSize int64
+	offset    int64
+	read      int64
+	hash      hash.Hash32
+}
+
+func (tp *TriparClient) newChunkVerifyReader(
+	ctx context.Context,
+	rd io.ReadCloser,
+	path string,
+	offset int64,
+	chunkSize int64,
+) *chunkVerifyReader {
+	return &chunkVerifyReader{
+		ReadCloser: rd,
+		tp:         tp,
+		ctx:        ctx,
+		path:       path,
+		chunkSize:  chunkSize,
+		offset:     offset,
+		hash:       crc32.New(castagnoli),
+	}
+}
+
+func (r *chunkVerifyReader) Read(p []byte) (n int, err error) {
+	if left := r.chunkSize - r.read; int64(len(p)) > left {
+		p = p[:left]
+	}
+
+	n, err = r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+
+	if r.read == r.chunkSize || (err == io.EOF && r.read > 0) {
+		// the read's GET is still open and holds the per-host request slot,
+		// which the verify GET can't wait for
+		if verifyErr := r.tp.verifyChunk(withHeldHostSlot(r.ctx), r.path, r.offset, r.read, r.hash.Sum32()); verifyErr != nil {
+			return n, verifyErr
+		}
+		r.offset += r.read
+		r.read = 0
+		r.hash.Reset()
+	}
+
+	return n, err
+}
+
+// ObjectChecksum returns the checksum of the object at path. It is computed
+// by the appliance with the cksum command, so the object is not downloaded.
+// If the appliance does not support the command, the object is downloaded
+// and hashed while streaming instead, and later calls skip the command.
+func (tp *TriparClient) ObjectChecksum(ctx context.Context, path string, algo ChecksumAlgorithm) (checksum Checksum, err error) {
+	defer tp.observe(ctx, "ObjectChecksum", path, time.Now(), &err)
+
+	h, ok := algo.newHash()
+	if !ok {
+		return Checksum{}, xerrors.Errorf("object checksum %q: %w", algo, ErrUnsupportedChecksumAlgorithm)
+	}
+
+	if tp.caps.supported("cksum") {
+		checksum, err = tp.serverChecksum(ctx, path, algo)
+		if err == nil {
+			return checksum, nil
+		}
+		if !errors.Is(err, ErrNotSupported) {
+			return Checksum{}, xerrors.Errorf("object checksum error: %w", err)
+		}
+	}
+
+	rd, _, err := tp.GetObject(ctx, path, nil)
+	if err != nil {
+		return Checksum{}, xerrors.Errorf("object checksum get error: %w", err)
+	}
+	defer rd.Close()
+
+	buffer, err := tp.getBuffer(ctx)
+	if err != nil {
+		return Checksum{}, err
+	}
+	defer tp.putBuffer(buffer)
+
+	if _, err := io.CopyBuffer(h, rd, buffer); err != nil {
+		return Checksum{}, xerrors.Errorf("object checksum read error: %w", err)
+	}
+
+	return Checksum{
+		Algorithm: algo,
+		Value:     hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+type checksumResponse struct {
+	Algorithm string `json:"algorithm"`
+	Checksum  string `json:"checksum" tripar:"required"`
+}
+
+func (tp *TriparClient) serverChecksum(ctx context.Context, path string, algo ChecksumAlgorithm) (Checksum, error) {
+	params := tp.cmd("cksum")
+	params.Set("algorithm", string(algo))
+
+	var result checksumResponse
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "GET",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("cksum", err)
+		return Checksum{}, xerrors.Errorf("cksum request error: %w", err)
+	}
+
+	if err := tp.unmarshalResponse(rsp, &result); err != nil {
+		tp.caps.observe("cksum", err)
+		return Checksum{}, xerrors.Errorf("cksum response error: %w", err)
+	}
+	if result.Checksum == "" || (result.Algorithm != "" && result.Algorithm != string(algo)) {
+		return Checksum{}, xerrors.Errorf("cksum unexpected %s checksum %q", result.Algorithm, result.Checksum)
+	}
+
+	return Checksum{
+		Algorithm:  algo,
+		Value:      result.Checksum,
+		ServerSide: true,
+	}, nil
+}
@@ -0,0 +1,195 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("VerifyChunks", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var corruptWrite int32
+	var corruptRead int32
+	var gets int32
+
+	data := strings.Repeat("0123456789", 300)
+
+	corrupt := func(body io.ReadCloser) io.ReadCloser {
+		content, err := io.ReadAll(body)
+		Expect(err).NotTo(HaveOccurred())
+		content[len(content)/2] ^= 0xff
+		return io.NopCloser(bytes.NewReader(content))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		atomic.StoreInt32(&corruptWrite, 0)
+		atomic.StoreInt32(&corruptRead, 0)
+		atomic.StoreInt32(&gets, 0)
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			isData := r.URL.Query().Get("cmd") == ""
+			if isData && (r.Method == "PUT" || r.Method == "POST") && atomic.AddInt32(&corruptWrite, -1) == 0 {
+				r.Body = corrupt(r.Body)
+			}
+			rsp, err := fake.RoundTrip(r)
+			if isData && r.Method == "GET" && err == nil {
+				atomic.AddInt32(&gets, 1)
+				if atomic.AddInt32(&corruptRead, -1) == 0 {
+					rsp.Body = corrupt(rsp.Body)
+				}
+			}
+			return rsp, err
+		}))
+		client.UploadChunkSize = 1024
+	})
+
+	It("should verify uploaded chunks", func() {
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data), PutVerifyChunks())).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(atomic.LoadInt32(&gets)).To(Equal(int32(3)))
+	})
+
+	It("should verify chunks uploaded from pieces", func() {
+		atomic.StoreInt32(&corruptWrite, 3)
+
+		err := client.PutObject(ctx, "/root/object", io.MultiReader(strings.NewReader(data)), PutVerifyChunks())
+		Expect(err).To(MatchError(ErrChecksumMismatch))
+		var checksumErr *ChunkChecksumError
+		Expect(errors.As(err, &checksumErr)).To(BeTrue())
+		Expect(checksumErr.Offset).To(Equal(int64(2048)))
+	})
+
+	It("should detect corrupted uploaded chunks", func() {
+		atomic.StoreInt32(&corruptWrite, 2)
+
+		err := client.PutObject(ctx, "/root/object", strings.NewReader(data), PutVerifyChunks())
+		Expect(err).To(MatchError(ErrChecksumMismatch))
+		var checksumErr *ChunkChecksumError
+		Expect(errors.As(err, &checksumErr)).To(BeTrue())
+		Expect(checksumErr.Offset).To(Equal(int64(1024)))
+		Expect(checksumErr.Size).To(Equal(int64(1024)))
+
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should verify downloaded chunks", func() {
+		fake.PutFile("/root/object", data)
+
+		rd, _, err := client.GetObject(ctx, "/root/object", nil, GetVerifyChunks())
+		Expect(err).NotTo(HaveOccurred())
+		read, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(string(read)).To(Equal(data))
+
+		// one complete read and three verification reads
+		Expect(atomic.LoadInt32(&gets)).To(Equal(int32(4)))
+	})
+
+	It("should verify downloaded chunks with one request in flight per host", func() {
+		fake.PutFile("/root/object", data)
+		client.SetHostLimiter(NewHostLimiter(HostLimitOptions{MaxInFlightPerHost: 1}))
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		for _, options := range [][]GetOption{{GetVerifyChunks()}, {GetVerifyChunks(), GetChunkSize(1024)}} {
+			rd, _, err := client.GetObject(ctx, "/root/object", nil, options...)
+			Expect(err).NotTo(HaveOccurred())
+			read, err := io.ReadAll(rd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rd.Close()).To(Succeed())
+			Expect(string(read)).To(Equal(data))
+		}
+	})
+
+	It("should detect corrupted downloaded chunks", func() {
+		fake.PutFile("/root/object", data)
+		atomic.StoreInt32(&corruptRead, 1)
+
+		rd, _, err := client.GetObject(ctx, "/root/object", nil, GetVerifyChunks())
+		Expect(err).NotTo(HaveOccurred())
+		defer rd.Close()
+
+		read, err := io.ReadAll(rd)
+		Expect(err).To(MatchError(ErrChecksumMismatch))
+		var checksumErr *ChunkChecksumError
+		Expect(errors.As(err, &checksumErr)).To(BeTrue())
+		Expect(checksumErr.Offset).To(Equal(int64(1024)))
+		Expect(read).To(HaveLen(2048))
+	})
+})
+
+var _ = Describe("ObjectChecksum", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	data := strings.Repeat("0123456789", 300)
+	sha := sha256.Sum256([]byte(data))
+	expected := hex.EncodeToString(sha[:])
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", data)
+	})
+
+	It("should use the appliance's checksum", func() {
+		checksum, err := client.ObjectChecksum(ctx, "/root/object", ChecksumSHA256)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(Equal(Checksum{Algorithm: ChecksumSHA256, Value: expected, ServerSide: true}))
+		Expect(fake.Requests()).To(Equal([]string{"GET /root/object cksum"}))
+	})
+
+	It("should hash the object if the command is not supported", func() {
+		fake.Unsupport("cksum")
+
+		checksum, err := client.ObjectChecksum(ctx, "/root/object", ChecksumSHA256)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(Equal(Checksum{Algorithm: ChecksumSHA256, Value: expected}))
+
+		md5sum := md5.Sum([]byte(data))
+		checksum, err = client.ObjectChecksum(ctx, "/root/object", ChecksumMD5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum.Value).To(Equal(hex.EncodeToString(md5sum[:])))
+
+		// the command is only tried once
+		Expect(fake.Requests()).To(HaveLen(5))
+		Expect(fake.Requests()[0]).To(Equal("GET /root/object cksum"))
+		Expect(fake.Requests()[3]).To(Equal("GET /root/object stat"))
+	})
+
+	It("should fail for missing objects", func() {
+		_, err := client.ObjectChecksum(ctx, "/root/missing", ChecksumSHA256)
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should fail for unknown algorithms", func() {
+		_, err := client.ObjectChecksum(ctx, "/root/object", ChecksumAlgorithm("whirlpool"))
+		Expect(err).To(MatchError(ErrUnsupportedChecksumAlgorithm))
+		Expect(fake.Requests()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,90 @@
+package triparclient
+
+import (
+	"errors"
+	"fmt"
+	pathpkg "path"
+	"strings"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+var ErrPathEscape = errors.New("path escapes root")
+
+// PathEscapeError is returned by clients created with Chroot for paths which
+// resolve outside of the root, e.g. "/a/../../b". It matches ErrPathEscape and
+// ErrInvalidPath.
+type PathEscapeError struct {
+	Root string
+	Path string
+}
+
+func (e *PathEscapeError) Error() string {
+	return fmt.Sprintf("path %s escapes root %s", e.Path, e.Root)
+}
+
+func (e *PathEscapeError) Is(target error) bool {
+	return target == ErrPathEscape || target == ErrInvalidPath
+}
+
+// Chroot returns a client whose paths are relative to prefix. Requests for
+// paths which resolve outside of prefix fail with a *PathEscapeError without
+// being sent. Chroot of a chrooted client is relative to its root.
+func (tp *TriparClient) Chroot(prefix string) (*TriparClient, error) {
+	root := pathpkg.Clean(tp.path(prefix))
+	if tp.root != "" {
+		if !tp.within(root) {
+			return nil, &PathEscapeError{Root: tp.root, Path: prefix}
+		}
+	}
+	if root == "/" {
+		root = ""
+	}
+
+	c := tp.clone()
+	c.root = root
+	return c, nil
+}
+
+func (tp *TriparClient) within(path string) bool {
+	return path == tp.root || strings.HasPrefix(path, tp.root+"/")
+}
+
+// confinePath cleans path, which already includes the root, and checks that
+// it does not escape the root.
+func (tp *TriparClient) confinePath(path string) (string, error) {
+	cleaned := pathpkg.Clean(path)
+	if !tp.within(cleaned) {
+		return "", &PathEscapeError{Root: tp.root, Path: strings.TrimPrefix(path, tp.root)}
+	}
+	return cleaned, nil
+}
+
+// confine checks the paths of a request of a chrooted client.
+func (tp *TriparClient) confine(req *httpclient.RequestData) (err error) {
+	if tp.root == "" {
+		return nil
+	}
+
+	if req.Path, err = tp.confinePath(req.Path); err != nil {
+		return err
+	}
+
+	if dst := req.Params.Get("destination"); dst != "" {
+		if dst, err = tp.confinePath(dst); err != nil {
+			return err
+		}
+		req.Params.Set("destination", dst)
+	}
+
+	return nil
+}
+
+// unroot converts a path returned by the appliance to a path relative to the
+// root.
+func (tp *TriparClient) unroot(path string) string {
+	if tp.root == "" || !tp.within(path) {
+		return path
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(path, tp.root), "/")
+}
@@ -0,0 +1,84 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Chroot", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var jail *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/jail/dir")
+		fake.PutFile("/root/secret", "secret")
+
+		var err error
+		jail, err = client.Chroot("/root/jail")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should confine operations to the root", func() {
+		Expect(jail.PutObject(ctx, "/dir/object", bytes.NewBufferString("12345"))).To(Succeed())
+		data, ok := fake.File("/root/jail/dir/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("12345"))
+
+		info, err := jail.Stat(ctx, "dir/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Path).To(Equal("/dir/object"))
+
+		entries, err := jail.List(ctx, "/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"dir"}))
+
+		Expect(jail.MoveObject(ctx, "/dir/object", "/moved")).To(Succeed())
+		Expect(fake.Exists("/root/jail/moved")).To(BeTrue())
+
+		created, err := jail.CreateDirectoriesWithResult(ctx, "/a/b")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(Equal([]string{"/a", "/a/b"}))
+		Expect(fake.Exists("/root/jail/a/b")).To(BeTrue())
+	})
+
+	It("should reject escapes", func() {
+		requests := len(fake.Requests())
+
+		_, err := jail.Stat(ctx, "/dir/../../secret")
+		Expect(err).To(MatchError(ErrPathEscape))
+		var escapeErr *PathEscapeError
+		Expect(errors.As(err, &escapeErr)).To(BeTrue())
+		Expect(escapeErr.Root).To(Equal("/root/jail"))
+
+		Expect(jail.CopyObject(ctx, "/dir", "/../stolen")).To(MatchError(ErrPathEscape))
+		Expect(jail.DeleteObject(ctx, "..")).To(MatchError(ErrPathEscape))
+		_, err = jail.CreateDirectoriesWithResult(ctx, "/../x")
+		Expect(err).To(MatchError(ErrPathEscape))
+
+		Expect(fake.Requests()).To(HaveLen(requests))
+
+		_, err = jail.Stat(ctx, "/dir/..")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should nest", func() {
+		dir, err := jail.Chroot("dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dir.PutObject(ctx, "/object", bytes.NewBufferString("1"))).To(Succeed())
+		Expect(fake.Exists("/root/jail/dir/object")).To(BeTrue())
+
+		_, err = jail.Chroot("/../secret")
+		Expect(err).To(MatchError(ErrPathEscape))
+	})
+})
@@ -0,0 +1,223 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitBreakerFailureThreshold = 5
+	DefaultCircuitBreakerCoolDown         = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned without sending a request while the circuit
+// breaker is open. It matches ErrCircuitOpen.
+type CircuitOpenError struct {
+	// Until is when the next probe request is let through.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open until %s", e.Until.Format(time.RFC3339Nano))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails all requests until the cool-down has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a single probe request through at a time.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests which
+	// open the circuit, DefaultCircuitBreakerFailureThreshold by default.
+	FailureThreshold int
+
+	// CoolDown is how long the circuit stays open before probe requests are
+	// let through, DefaultCircuitBreakerCoolDown by default.
+	CoolDown time.Duration
+
+	// SuccessThreshold is the number of consecutive successful probe
+	// requests which close the circuit again, 1 by default. A failed probe
+	// opens the circuit for another CoolDown.
+	SuccessThreshold int
+
+	// IsFailure decides which request errors count as failures. By default
+	// errors without a response and 5xx and 429 responses do. Requests whose
+	// context is done are never counted.
+	IsFailure func(err error) bool
+
+	// OnStateChange is called when the circuit changes its state.
+	OnStateChange func(from CircuitState, to CircuitState)
+}
+
+// CircuitBreaker makes the clients sharing it fail fast with a
+// *CircuitOpenError once the appliance fails consecutive requests, instead of
+// sending more requests to it, see TriparClient.CircuitBreaker. After a
+// cool-down, probe requests are let through one at a time, and once enough of
+// them succeed all requests are let through again.
+type CircuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mx        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	probing   bool
+	openUntil time.Time
+}
+
+func NewCircuitBreaker(opts CircuitBreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultCircuitBreakerFailureThreshold
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = DefaultCircuitBreakerCoolDown
+	}
+	if opts.SuccessThreshold <= 0 {
+		opts.SuccessThreshold = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = isCircuitFailure
+	}
+
+	return &CircuitBreaker{
+		opts: opts,
+	}
+}
+
+func isCircuitFailure(err error) bool {
+	_, ok := classifyError(&RetryPolicy{}, err)
+	return ok
+}
+
+// State returns the current state. An open circuit whose cool-down has passed
+// is reported as CircuitHalfOpen.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.state == CircuitOpen && !time.Now().Before(b.openUntil) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow returns an error if a request can't be sent now. probe is set if the
+// request is a probe of a half-open circuit.
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mx.Lock()
+
+	from := b.state
+	if b.state == CircuitOpen {
+		if time.Now().Before(b.openUntil) {
+			until := b.openUntil
+			b.mx.Unlock()
+			return false, &CircuitOpenError{Until: until}
+		}
+		b.state = CircuitHalfOpen
+		b.successes = 0
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probing {
+			b.mx.Unlock()
+			return false, &CircuitOpenError{Until: time.Now()}
+		}
+		b.probing = true
+		probe = true
+	}
+
+	to := b.state
+	b.mx.Unlock()
+	b.changed(from, to)
+
+	return probe, nil
+}
+
+// record counts the result of a request admitted by allow.
+func (b *CircuitBreaker) record(ctx context.Context, probe bool, err error) {
+	b.mx.Lock()
+
+	from := b.state
+	if probe {
+		b.probing = false
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// the caller gave up, which says nothing about the appliance
+
+	case err != nil && b.opts.IsFailure(err):
+		b.failures++
+		if (b.state == CircuitHalfOpen && probe) || (b.state == CircuitClosed && b.failures >= b.opts.FailureThreshold) {
+			b.state = CircuitOpen
+			b.openUntil = time.Now().Add(b.opts.CoolDown)
+			b.failures = 0
+		}
+
+	default:
+		b.failures = 0
+		if b.state == CircuitHalfOpen && probe {
+			b.successes++
+			if b.successes >= b.opts.SuccessThreshold {
+				b.state = CircuitClosed
+			}
+		}
+	}
+
+	to := b.state
+	b.mx.Unlock()
+	b.changed(from, to)
+}
+
+func (b *CircuitBreaker) changed(from CircuitState, to CircuitState) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
+
+// circuitAllow checks the client's CircuitBreaker before a request. The
+// returned function must be called with the request's result.
+func (tp *TriparClient) circuitAllow(ctx context.Context) (done func(err error), err error) {
+	b := tp.CircuitBreaker
+	if b == nil {
+		return func(error) {}, nil
+	}
+
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(err error) {
+		b.record(ctx, probe, err)
+	}, nil
+}
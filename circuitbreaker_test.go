@@ -0,0 +1,124 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failing int32
+	var requests int32
+	var changes []string
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		atomic.StoreInt32(&failing, 0)
+		atomic.StoreInt32(&requests, 0)
+		changes = nil
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.LoadInt32(&failing) == 1 {
+				return testResponse(http.StatusInternalServerError, "text/plain", "failed"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.CircuitBreaker = NewCircuitBreaker(CircuitBreakerOptions{
+			FailureThreshold: 3,
+			CoolDown:         50 * time.Millisecond,
+			OnStateChange: func(from CircuitState, to CircuitState) {
+				changes = append(changes, from.String()+" -> "+to.String())
+			},
+		})
+	})
+
+	stat := func() error {
+		_, err := client.Stat(ctx, "/root")
+		return err
+	}
+
+	It("should open after consecutive failures and fail fast", func() {
+		atomic.StoreInt32(&failing, 1)
+		for i := 0; i < 3; i++ {
+			Expect(stat()).NotTo(MatchError(ErrCircuitOpen))
+		}
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitOpen))
+
+		err := stat()
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		var openErr *CircuitOpenError
+		Expect(errors.As(err, &openErr)).To(BeTrue())
+		Expect(openErr.Until).To(BeTemporally(">", time.Now()))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should not open if failures are not consecutive", func() {
+		for i := 0; i < 3; i++ {
+			atomic.StoreInt32(&failing, 1)
+			Expect(stat()).To(HaveOccurred())
+			Expect(stat()).To(HaveOccurred())
+			atomic.StoreInt32(&failing, 0)
+			Expect(stat()).To(Succeed())
+		}
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should not count errors from the appliance as failures", func() {
+		for i := 0; i < 5; i++ {
+			_, err := client.Stat(ctx, "/root/missing")
+			Expect(err).To(MatchError(ErrNotFound))
+		}
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should close after a successful probe", func() {
+		atomic.StoreInt32(&failing, 1)
+		for i := 0; i < 3; i++ {
+			Expect(stat()).To(HaveOccurred())
+		}
+		Expect(stat()).To(MatchError(ErrCircuitOpen))
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitHalfOpen))
+
+		atomic.StoreInt32(&failing, 0)
+		Expect(stat()).To(Succeed())
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitClosed))
+		Expect(changes).To(Equal([]string{"closed -> open", "open -> half-open", "half-open -> closed"}))
+	})
+
+	It("should open again after a failed probe", func() {
+		atomic.StoreInt32(&failing, 1)
+		for i := 0; i < 3; i++ {
+			Expect(stat()).To(HaveOccurred())
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(stat()).NotTo(MatchError(ErrCircuitOpen))
+		Expect(client.CircuitBreaker.State()).To(Equal(CircuitOpen))
+		Expect(stat()).To(MatchError(ErrCircuitOpen))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(4)))
+	})
+
+	It("should not retry while open", func() {
+		client.RetryPolicy = &RetryPolicy{MaxAttempts: 10}
+		atomic.StoreInt32(&failing, 1)
+
+		Expect(stat()).To(MatchError(ErrCircuitOpen))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+})
@@ -0,0 +1,223 @@
+package triparclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// DefaultGetChunkSize is the chunk size of clients created with
+// NewTriparClientWithOptions without WithGetChunkSize.
+const DefaultGetChunkSize = 4 * 1024 * 1024
+
+// ClientOptions configure a client created with NewTriparClientWithOptions.
+// Options are applied in order, so later options override earlier ones.
+type ClientOptions struct {
+	// User and Pass are the credentials sent with every request.
+	User string
+	Pass string
+
+	// Share is appended to the endpoint.
+	Share string
+
+	// BufferPool provides the buffers of uploads, a pool of
+	// DefaultBufferPoolCapacity buffers of DefaultBufferSize bytes by
+	// default.
+	BufferPool BufferPoolIface
+
+	// GetChunkSize is the size of ranged GET requests, DefaultGetChunkSize
+	// by default.
+	GetChunkSize int64
+
+	// HTTPClient replaces the client's http.Client. Connections of its
+	// transport are not counted in Stats and not limited by a HostLimiter.
+	HTTPClient *http.Client
+
+	// Transport replaces the transport of the client's http.Client, with
+	// the same caveats as HTTPClient. TLSConfig and client certificates
+	// require an *http.Transport.
+	Transport http.RoundTripper
+
+	// WrapTransport wraps the client's transport once it is configured,
+	// e.g. for instrumentation, keeping the default transport's connection
+	// handling.
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+
+	// Timeout sets TriparClient.DefaultTimeout.
+	Timeout time.Duration
+
+	// TLSConfig is applied to the transport with SetTLSConfig. If it is nil,
+	// certificates are verified against the system roots.
+	TLSConfig *tls.Config
+
+	// InsecureSkipVerify disables the verification of the appliance's
+	// certificate, e.g. for self-signed certificates. It is applied on top
+	// of TLSConfig.
+	InsecureSkipVerify bool
+
+	// ClientCertificate is applied with SetClientCertificate. If
+	// ClientCertFile and ClientKeyFile are set instead, the certificate is
+	// loaded from these PEM files.
+	ClientCertificate *tls.Certificate
+	ClientCertFile    string
+	ClientKeyFile     string
+}
+
+type ClientOption func(opts *ClientOptions)
+
+func WithBasicAuth(user string, pass string) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.User = user
+		opts.Pass = pass
+	}
+}
+
+func WithShare(share string) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.Share = share
+	}
+}
+
+func WithBufferPool(bp BufferPoolIface) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.BufferPool = bp
+	}
+}
+
+func WithGetChunkSize(size int64) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.GetChunkSize = size
+	}
+}
+
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.HTTPClient = client
+	}
+}
+
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.Transport = rt
+	}
+}
+
+func WithTransportWrapper(wrap func(rt http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.WrapTransport = wrap
+	}
+}
+
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.Timeout = timeout
+	}
+}
+
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.TLSConfig = config
+	}
+}
+
+func WithInsecureSkipVerify() ClientOption {
+	return func(opts *ClientOptions) {
+		opts.InsecureSkipVerify = true
+	}
+}
+
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.ClientCertificate = &cert
+		opts.ClientCertFile = ""
+		opts.ClientKeyFile = ""
+	}
+}
+
+func WithClientCertificateFiles(certFile string, keyFile string) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.ClientCertificate = nil
+		opts.ClientCertFile = certFile
+		opts.ClientKeyFile = keyFile
+	}
+}
+
+func newClientOptions(options []ClientOption) *ClientOptions {
+	opts := &ClientOptions{
+		GetChunkSize: DefaultGetChunkSize,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// NewTriparClientWithOptions creates a client for endpoint configured with
+// options, e.g.
+//
+//	NewTriparClientWithOptions(endpoint, WithBasicAuth(user, pass), WithShare(share))
+//
+// Options not given use the defaults described in ClientOptions.
+func NewTriparClientWithOptions(endpoint string, options ...ClientOption) (*TriparClient, error) {
+	opts := newClientOptions(options)
+
+	getChunkSize := opts.GetChunkSize
+	if getChunkSize <= 0 {
+		getChunkSize = DefaultGetChunkSize
+	}
+
+	tp, err := NewTriparClient(endpoint, opts.User, opts.Pass, opts.Share, opts.BufferPool, getChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.HTTPClient != nil {
+		tp.HTTPClient.Client = opts.HTTPClient
+		tp.ownTransport = nil
+	}
+	if opts.Transport != nil {
+		tp.setHTTPTransport(opts.Transport, nil)
+	}
+	tp.DefaultTimeout = opts.Timeout
+
+	tlsConfig := opts.TLSConfig
+	if opts.InsecureSkipVerify {
+		if tlsConfig != nil {
+			tlsConfig = tlsConfig.Clone()
+		} else {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if tlsConfig != nil {
+		if err := tp.SetTLSConfig(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	cert := opts.ClientCertificate
+	if cert == nil && (opts.ClientCertFile != "" || opts.ClientKeyFile != "") {
+		loaded, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, xerrors.Errorf("load client certificate error: %w", err)
+		}
+		cert = &loaded
+	}
+	if cert != nil {
+		if err := tp.SetClientCertificate(*cert); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.WrapTransport != nil {
+		rt := tp.HTTPClient.Client.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		tp.setHTTPTransport(opts.WrapTransport(rt), nil)
+	}
+
+	return tp, nil
+}
@@ -0,0 +1,105 @@
+package triparclient_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("NewTriparClientWithOptions", func() {
+	It("should apply the options", func() {
+		fake := newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+
+		var requests []*http.Request
+		client, err := NewTriparClientWithOptions(
+			"http://tripar.example.com",
+			WithBasicAuth("user", "pass"),
+			WithShare("share"),
+			WithBufferPool(NewBufferPool(4, 1024)),
+			WithGetChunkSize(2),
+			WithTimeout(time.Minute),
+			WithHTTPClient(&http.Client{
+				Transport: funcTransport(func(r *http.Request) (*http.Response, error) {
+					requests = append(requests, r)
+					return fake.RoundTrip(r)
+				}),
+			}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.DefaultTimeout).To(Equal(time.Minute))
+
+		_, err = client.Stat(context.Background(), "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Host).To(Equal("tripar.example.com"))
+		Expect(requests[0].URL.Opaque + requests[0].URL.Path).To(ContainSubstring("/share/root/object"))
+		user, pass, ok := requests[0].BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(user).To(Equal("user"))
+		Expect(pass).To(Equal("pass"))
+	})
+
+	It("should use defaults without options", func() {
+		client, err := NewTriparClientWithOptions("http://tripar.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.DefaultTimeout).To(BeZero())
+	})
+
+	It("should fail for invalid endpoints", func() {
+		_, err := NewTriparClientWithOptions("http://tripar.example.com:port")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should use the transport", func() {
+		fake := newFakeTripar()
+		fake.Mkdir("/root")
+
+		client, err := NewTriparClientWithOptions("http://tripar.example.com", WithTransport(fake))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Stat(context.Background(), "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Requests()).To(Equal([]string{"GET /root stat"}))
+	})
+
+	It("should wrap the configured transport", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+
+		var wrapped http.RoundTripper
+		var requests int32
+		client, err := NewTriparClientWithOptions(
+			server.URL,
+			WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+				wrapped = rt
+				return funcTransport(func(r *http.Request) (*http.Response, error) {
+					atomic.AddInt32(&requests, 1)
+					return rt.RoundTrip(r)
+				})
+			}),
+			WithTLSConfig(&tls.Config{RootCAs: roots}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wrapped.(*http.Transport).TLSClientConfig.RootCAs).To(Equal(roots))
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+})
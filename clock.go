@@ -0,0 +1,110 @@
+package triparclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// clockSkew estimates the offset of the appliance's clock from the Date
+// headers of responses. A Date header only has second precision, so every
+// response bounds the skew to an interval. Intervals of later responses are
+// intersected with it, which narrows the estimate over time. If they don't
+// intersect, the clock was adjusted and the estimate starts over.
+type clockSkew struct {
+	mx       sync.Mutex
+	measured bool
+	min      time.Duration
+	max      time.Duration
+}
+
+func newClockSkew() *clockSkew {
+	return &clockSkew{}
+}
+
+// observe records a response which was requested at start and received at
+// end.
+func (c *clockSkew) observe(start time.Time, end time.Time, date string) {
+	if date == "" {
+		return
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+
+	// the server time was in [serverTime, serverTime+1s) at some point
+	// between start and end
+	min := serverTime.Sub(end)
+	max := serverTime.Add(time.Second).Sub(start)
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.measured || min > c.max || max < c.min {
+		c.measured = true
+		c.min = min
+		c.max = max
+		return
+	}
+	if min > c.min {
+		c.min = min
+	}
+	if max < c.max {
+		c.max = max
+	}
+}
+
+func (c *clockSkew) get() (time.Duration, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.measured {
+		return 0, false
+	}
+	return (c.min + c.max) / 2, true
+}
+
+// ClockSkew returns how far the appliance's clock is ahead of the local
+// clock, estimated from the Date headers of the responses received so far.
+// It is accurate to about half a second plus half the round-trip time, and
+// improves as more responses are received. ok is false if no response had a
+// Date header yet.
+//
+// Object mtimes are set by the appliance's clock, so the skew has to be
+// subtracted before comparing them to local times.
+func (tp *TriparClient) ClockSkew() (skew time.Duration, ok bool) {
+	return tp.clock.get()
+}
+
+// MeasureClockSkew makes a request to the share root and returns the updated
+// ClockSkew estimate.
+func (tp *TriparClient) MeasureClockSkew(ctx context.Context) (skew time.Duration, err error) {
+	defer tp.observe(ctx, "MeasureClockSkew", "", time.Now(), &err)
+
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "GET",
+		Path:           tp.path("/"),
+		Params:         tp.cmd("stat"),
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("measure clock skew request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		return 0, xerrors.Errorf("measure clock skew response error: %w", err)
+	}
+
+	skew, ok := tp.ClockSkew()
+	if !ok {
+		return 0, xerrors.Errorf("measure clock skew: no Date header")
+	}
+
+	return skew, nil
+}
@@ -0,0 +1,66 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("ClockSkew", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var offset time.Duration
+	var date bool
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		offset = time.Hour
+		date = true
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			rsp := testStatResponse(5)
+			if date {
+				rsp.Header.Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+			}
+			return rsp, nil
+		}))
+	})
+
+	It("should estimate the skew from Date headers", func() {
+		_, ok := client.ClockSkew()
+		Expect(ok).To(BeFalse())
+
+		_, err := client.Stat(ctx, "/object")
+		Expect(err).NotTo(HaveOccurred())
+		skew, ok := client.ClockSkew()
+		Expect(ok).To(BeTrue())
+		Expect(skew).To(BeNumerically("~", time.Hour, time.Second))
+	})
+
+	It("should measure the skew", func() {
+		offset = -30 * time.Minute
+		skew, err := client.MeasureClockSkew(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skew).To(BeNumerically("~", -30*time.Minute, time.Second))
+	})
+
+	It("should start over when the clock is adjusted", func() {
+		_, err := client.MeasureClockSkew(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		offset = 0
+		skew, err := client.MeasureClockSkew(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skew).To(BeNumerically("~", 0, time.Second))
+	})
+
+	It("should fail without Date headers", func() {
+		date = false
+		_, err := client.MeasureClockSkew(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+})
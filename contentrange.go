@@ -0,0 +1,71 @@
+package triparclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+var ErrRangeMismatch = errors.New("content range mismatch")
+
+// ContentRangeError is returned when reading a chunk of an object if the
+// response does not contain the requested range. It matches
+// ErrRangeMismatch.
+type ContentRangeError struct {
+	Start         int64
+	End           int64
+	ContentRange  string
+	ContentLength int64
+}
+
+func (e *ContentRangeError) Error() string {
+	return fmt.Sprintf(
+		"content range mismatch: requested bytes %d-%d, got content range %q with length %d",
+		e.Start, e.End, e.ContentRange, e.ContentLength,
+	)
+}
+
+func (e *ContentRangeError) Is(target error) bool {
+	return target == ErrRangeMismatch
+}
+
+// parseContentRange parses a Content-Range header of the form
+// "bytes start-end/size", where size may be "*".
+func parseContentRange(header string) (start int64, end int64, ok bool) {
+	var size string
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%s", &start, &end, &size); err != nil {
+		return 0, 0, false
+	}
+	if size != "*" {
+		if _, err := strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if start < 0 || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// checkContentRange checks that rsp, whose body has length bytes, contains
+// the range from start to end. The response may end early, e.g. if the
+// object was truncated, but it must not start at a different offset.
+func checkContentRange(rsp *http.Response, start int64, end int64, length int64) error {
+	header := rsp.Header.Get("Content-Range")
+
+	if rsp.StatusCode != http.StatusPartialContent {
+		// the range was ignored, which is only correct for a whole object
+		if start == 0 && length == end+1 {
+			return nil
+		}
+		return &ContentRangeError{Start: start, End: end, ContentRange: header, ContentLength: length}
+	}
+
+	gotStart, gotEnd, ok := parseContentRange(header)
+	if !ok || gotStart != start || gotEnd > end || gotEnd-gotStart+1 != length {
+		return &ContentRangeError{Start: start, End: end, ContentRange: header, ContentLength: length}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Content-Range validation", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var modify func(r *http.Request, rsp *http.Response)
+
+	data := strings.Repeat("0123456789", 300)
+	span := &ioutils.FileSpan{Start: 0, End: int64(len(data)) - 1}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", data)
+
+		modify = nil
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			rsp, err := fake.RoundTrip(r)
+			if err == nil && modify != nil && r.Method == "GET" && r.Header.Get("Range") != "" {
+				modify(r, rsp)
+			}
+			return rsp, err
+		}))
+	})
+
+	read := func() ([]byte, error) {
+		rd, _, err := client.GetObject(ctx, "/root/object", span)
+		Expect(err).NotTo(HaveOccurred())
+		defer rd.Close()
+		return io.ReadAll(rd)
+	}
+
+	It("should read chunks with matching ranges", func() {
+		got, err := read()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(got)).To(Equal(data))
+	})
+
+	It("should fail on shifted ranges", func() {
+		modify = func(r *http.Request, rsp *http.Response) {
+			if strings.HasPrefix(r.Header.Get("Range"), "bytes=1024-") {
+				rsp.Header.Set("Content-Range", fmt.Sprintf("bytes 1025-2048/%d", len(data)))
+			}
+		}
+
+		got, err := read()
+		Expect(err).To(MatchError(ErrRangeMismatch))
+		var rangeErr *ContentRangeError
+		Expect(errors.As(err, &rangeErr)).To(BeTrue())
+		Expect(rangeErr.Start).To(Equal(int64(1024)))
+		Expect(rangeErr.End).To(Equal(int64(2047)))
+		Expect(rangeErr.ContentRange).To(Equal("bytes 1025-2048/3000"))
+		Expect(got).To(HaveLen(1024))
+	})
+
+	It("should fail on missing ranges", func() {
+		modify = func(r *http.Request, rsp *http.Response) {
+			rsp.Header.Del("Content-Range")
+		}
+
+		_, err := read()
+		Expect(err).To(MatchError(ErrRangeMismatch))
+	})
+
+	It("should fail if the range is ignored", func() {
+		modify = func(r *http.Request, rsp *http.Response) {
+			if !strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+				rsp.StatusCode = http.StatusOK
+				rsp.Header.Del("Content-Range")
+			}
+		}
+
+		_, err := read()
+		Expect(err).To(MatchError(ErrRangeMismatch))
+	})
+})
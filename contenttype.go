@@ -0,0 +1,43 @@
+package triparclient
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const octetStream = "application/octet-stream"
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// sniffContentType detects the content type of rd if contentType is
+// octet-stream. The returned reader still returns the whole content.
+func sniffContentType(rd io.ReadCloser, contentType string) (io.ReadCloser, string, error) {
+	if contentType != "" && !strings.HasPrefix(contentType, octetStream) {
+		return rd, contentType, nil
+	}
+
+	brd := bufio.NewReaderSize(rd, sniffLen)
+	head, err := brd.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		rd.Close()
+		return nil, "", err
+	}
+
+	if len(head) > 0 {
+		contentType = http.DetectContentType(head)
+	}
+
+	return &bufferedReadCloser{
+		Reader: brd,
+		Closer: rd,
+	}, contentType, nil
+}
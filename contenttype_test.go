@@ -0,0 +1,56 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+
+	"github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("ContentType", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/page", "<!DOCTYPE html><html><body>hello</body></html>")
+	})
+
+	get := func(span *ioutils.FileSpan, options ...GetOption) (string, string) {
+		rd, info, err := client.GetObject(ctx, "/root/page", span, options...)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		return info.ContentType, string(data)
+	}
+
+	It("should return the response content type", func() {
+		contentType, _ := get(nil)
+		Expect(contentType).To(Equal("application/octet-stream"))
+	})
+
+	It("should sniff the content type", func() {
+		contentType, data := get(nil, GetSniffContentType())
+		Expect(contentType).To(Equal("text/html; charset=utf-8"))
+		Expect(data).To(Equal("<!DOCTYPE html><html><body>hello</body></html>"))
+
+		contentType, data = get(&ioutils.FileSpan{Start: 0, End: 45}, GetChunkSize(8), GetSniffContentType())
+		Expect(contentType).To(Equal("text/html; charset=utf-8"))
+		Expect(data).To(Equal("<!DOCTYPE html><html><body>hello</body></html>"))
+	})
+
+	It("should keep octet-stream for binary content", func() {
+		fake.PutFile("/root/page", "\x00\x01\x02\x03")
+		contentType, _ := get(nil, GetSniffContentType())
+		Expect(contentType).To(Equal("application/octet-stream"))
+	})
+})
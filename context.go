@@ -0,0 +1,103 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var ErrRunAsHeaderNotConfigured = errors.New("run-as header not configured")
+
+type contextKey int
+
+const (
+	runAsUserContextKey contextKey = iota
+	headersContextKey
+	tagsContextKey
+	transferCounterContextKey
+	managedTransferContextKey
+	priorityContextKey
+	jobProgressContextKey
+	retryPolicyContextKey
+	heldHostSlotContextKey
+)
+
+// Priority is the class of a transfer scheduled by a TransferManager.
+type Priority int
+
+const (
+	// PriorityInteractive is the default priority, for latency-sensitive
+	// transfers like user downloads.
+	PriorityInteractive Priority = iota
+
+	// PriorityBackground is for bulk transfers like migrations, whose chunk
+	// requests only get a slot when no interactive transfer is waiting.
+	PriorityBackground
+
+	priorityCount
+)
+
+// WithRunAsUser returns a context which makes requests act on behalf of the
+// given user. The appliance must be configured to trust the client's
+// credentials for impersonation, and the client's RunAsHeader must be set,
+// otherwise requests fail with ErrRunAsHeaderNotConfigured.
+func WithRunAsUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, runAsUserContextKey, user)
+}
+
+func RunAsUser(ctx context.Context) string {
+	user, _ := ctx.Value(runAsUserContextKey).(string)
+	return user
+}
+
+// WithTransferPriority returns a context whose GetObject and PutObject
+// transfers are scheduled with the given priority by the TransferManager.
+func WithTransferPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey, priority)
+}
+
+func TransferPriority(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityContextKey).(Priority)
+	if priority < 0 || priority >= priorityCount {
+		return PriorityInteractive
+	}
+	return priority
+}
+
+// WithHeader returns a context which adds the header to every request made
+// with it. Headers which the client sets itself, like Authorization, Range,
+// Content-Type, Expect and the RunAsHeader, can not be overridden this way
+// and are ignored.
+func WithHeader(ctx context.Context, key string, value string) context.Context {
+	headers := Headers(ctx)
+	headers.Set(key, value)
+	return context.WithValue(ctx, headersContextKey, headers)
+}
+
+// Headers returns a copy of the headers added with WithHeader.
+func Headers(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(headersContextKey).(http.Header)
+	if headers == nil {
+		return make(http.Header)
+	}
+	return headers.Clone()
+}
+
+// WithTag returns a context with a key/value tag, e.g. a tenant id, which is
+// not sent to the appliance but is available to ObserveContext for
+// attribution of operations.
+func WithTag(ctx context.Context, key string, value string) context.Context {
+	tags := Tags(ctx)
+	tags[key] = value
+	return context.WithValue(ctx, tagsContextKey, tags)
+}
+
+// Tags returns a copy of the tags added with WithTag.
+func Tags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsContextKey).(map[string]string)
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,101 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("WithHeader", func() {
+	It("should add headers to requests", func() {
+		headers := make(chan http.Header, 2)
+
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			headers <- r.Header
+			return testStatResponse(5), nil
+		}))
+
+		ctx := WithHeader(context.Background(), "X-Tenant", "tenant")
+		ctx = WithHeader(ctx, "Authorization", "Basic Zm9vOmJhcg==")
+
+		_, err := client.Stat(ctx, "/object")
+		Expect(err).NotTo(HaveOccurred())
+		header := <-headers
+		Expect(header.Get("X-Tenant")).To(Equal("tenant"))
+		Expect(header.Get("Authorization")).To(Equal("Basic dXNlcjpwYXNz"))
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect((<-headers).Get("X-Tenant")).To(BeEmpty())
+	})
+
+	It("should not override headers set by the client", func() {
+		var header http.Header
+
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "0123456789")
+		client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Query().Get("cmd") == "" {
+				header = r.Header
+			}
+			return fake.RoundTrip(r)
+		})
+		client.RunAsHeader = "X-Run-As-User"
+
+		ctx := WithRunAsUser(context.Background(), "alice")
+		ctx = WithHeader(ctx, "Range", "bytes=0-9")
+		ctx = WithHeader(ctx, "X-Run-As-User", "mallory")
+		ctx = WithHeader(ctx, "Content-Type", "text/plain")
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 2, End: 4})
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(string(data)).To(Equal("234"))
+		Expect(header.Get("Range")).To(Equal("bytes=2-4"))
+		Expect(header.Get("X-Run-As-User")).To(Equal("alice"))
+		Expect(header.Get("Content-Type")).To(BeEmpty())
+	})
+
+	It("should not modify parent contexts", func() {
+		parent := WithHeader(context.Background(), "X-A", "a")
+		child := WithHeader(parent, "X-B", "b")
+
+		Expect(Headers(parent)).To(Equal(http.Header{"X-A": {"a"}}))
+		Expect(Headers(child)).To(Equal(http.Header{"X-A": {"a"}, "X-B": {"b"}}))
+	})
+})
+
+var _ = Describe("WithTag", func() {
+	It("should expose tags to ObserveContext", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		tags := []map[string]string{}
+		client.ObserveContext = func(ctx context.Context, op string, d time.Duration, err error) {
+			tags = append(tags, Tags(ctx))
+		}
+
+		parent := WithTag(context.Background(), "tenant", "a")
+		child := WithTag(parent, "job", "b")
+
+		_, err := client.Stat(parent, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Stat(child, "/root")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(tags).To(Equal([]map[string]string{
+			{"tenant": "a"},
+			{"tenant": "a", "job": "b"},
+		}))
+	})
+})
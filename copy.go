@@ -0,0 +1,165 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/xerrors"
+)
+
+// CopyRange copies length bytes at srcOffset of srcPath into dstPath at
+// dstOffset. The destination is created if it does not exist and dstOffset is
+// 0, and extended if the range ends after its end. Copies of whole objects
+// use the appliance's server-side copy, other ranges are copied through the
+// client one buffered chunk at a time.
+func (tp *TriparClient) CopyRange(
+	ctx context.Context,
+	srcPath string,
+	srcOffset int64,
+	dstPath string,
+	dstOffset int64,
+	length int64,
+) (err error) {
+	defer tp.observe(ctx, "CopyRange", dstPath, time.Now(), &err)
+
+	if srcOffset < 0 || dstOffset < 0 || length < 0 {
+		return xerrors.Errorf("copy range invalid range: %w", ErrBadRange)
+	}
+
+	src, err := tp.Stat(ctx, srcPath, StatSkipIdentity())
+	if err != nil {
+		return xerrors.Errorf("copy range source stat error: %w", err)
+	}
+	if srcOffset+length > src.Status.Size {
+		return xerrors.Errorf("copy range source range %d-%d of %d bytes: %w", srcOffset, srcOffset+length, src.Status.Size, ErrBadRange)
+	}
+
+	dstExists := true
+	dst, err := tp.Stat(ctx, dstPath, StatSkipIdentity())
+	if errors.Is(err, ErrNotFound) {
+		dstExists = false
+		if dstOffset > 0 {
+			return xerrors.Errorf("copy range destination error: %w", err)
+		}
+	} else if err != nil {
+		return xerrors.Errorf("copy range destination stat error: %w", err)
+	}
+
+	if tp.planned(ctx, PlannedOperation{
+		Op:                "CopyRange",
+		Path:              srcPath,
+		Destination:       dstPath,
+		Size:              length,
+		Offset:            srcOffset,
+		DestinationOffset: dstOffset,
+	}) {
+		return nil
+	}
+
+	// the server-side copy replaces the destination, which only equals
+	// writing the whole source at 0 if the destination isn't longer
+	if srcOffset == 0 && dstOffset == 0 && length == src.Status.Size && (!dstExists || dst.Status.Size <= length) {
+		return tp.CopyObject(ctx, srcPath, dstPath)
+	}
+
+	if length == 0 {
+		if !dstExists {
+			return tp.putChunk(ctx, dstPath, 0, bytes.NewReader(nil), 0)
+		}
+		return nil
+	}
+
+	buffer, err := tp.getBuffer(ctx)
+	if err != nil {
+		return xerrors.Errorf("copy range buffer error: %w", err)
+	}
+	defer tp.putBuffer(buffer)
+
+	for copied := int64(0); copied < length; {
+		n := length - copied
+		if n > tp.getChunkSize {
+			n = tp.getChunkSize
+		}
+		if n > int64(len(buffer)) {
+			n = int64(len(buffer))
+		}
+
+		rsp, err := tp.getObjectResponse(ctx, srcPath, &ioutils.FileSpan{
+			Start: srcOffset + copied,
+			End:   srcOffset + copied + n - 1,
+		})
+		if err != nil {
+			return xerrors.Errorf("copy range get error: %w", err)
+		}
+
+		// the chunk is buffered so that the GET's per-host request slot is
+		// released before the write takes one
+		_, err = io.ReadFull(rsp.Body, buffer[:n])
+		rsp.Body.Close()
+		if err != nil {
+			return xerrors.Errorf("copy range read error: %w", err)
+		}
+
+		offset := dstOffset + copied
+		err = tp.writeRange(ctx, dstPath, offset, bytes.NewReader(buffer[:n]), n, !dstExists && offset == 0)
+		if err != nil {
+			return xerrors.Errorf("copy range write error: %w", err)
+		}
+
+		dstExists = true
+		copied += n
+	}
+
+	return nil
+}
+
+// ConcatObjects replaces destPath with the concatenation of sources. The first
+// source is copied with the appliance's server-side copy and the rest are
+// appended with CopyRange. destPath may be the first source, in which case the
+// other sources are appended to it, but it may not be any of the others.
+func (tp *TriparClient) ConcatObjects(ctx context.Context, destPath string, sources []string) (err error) {
+	defer tp.observe(ctx, "ConcatObjects", destPath, time.Now(), &err)
+
+	for i, src := range sources {
+		if i > 0 && src == destPath {
+			return xerrors.Errorf("concat objects destination is source %d: %s", i, src)
+		}
+	}
+
+	sizes := make([]int64, len(sources))
+	for i, src := range sources {
+		info, err := tp.Stat(ctx, src, StatSkipIdentity())
+		if err != nil {
+			return xerrors.Errorf("concat objects source stat error: %w", err)
+		}
+		sizes[i] = info.Status.Size
+	}
+
+	if tp.planned(ctx, PlannedOperation{Op: "ConcatObjects", Path: destPath, Sources: sources}) {
+		return nil
+	}
+
+	if len(sources) == 0 {
+		return tp.putChunk(ctx, destPath, 0, bytes.NewReader(nil), 0)
+	}
+
+	if sources[0] != destPath {
+		if err := tp.CopyObject(ctx, sources[0], destPath); err != nil {
+			return xerrors.Errorf("concat objects copy error: %w", err)
+		}
+	}
+
+	offset := sizes[0]
+	for i, src := range sources[1:] {
+		if err := tp.CopyRange(ctx, src, 0, destPath, offset, sizes[i+1]); err != nil {
+			return xerrors.Errorf("concat objects append error: %w", err)
+		}
+		offset += sizes[i+1]
+	}
+
+	return nil
+}
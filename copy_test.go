@@ -0,0 +1,139 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("CopyRange", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/src", "0123456789")
+	})
+
+	expectFile := func(p string, expected string) {
+		data, ok := fake.File(p)
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(expected))
+	}
+
+	It("should copy whole objects server-side", func() {
+		Expect(client.CopyRange(ctx, "/root/src", 0, "/root/dst", 0, 10)).To(Succeed())
+		expectFile("/root/dst", "0123456789")
+		Expect(fake.Requests()).To(ContainElement("PUT /root/src cp"))
+	})
+
+	It("should copy a range into a new object", func() {
+		Expect(client.CopyRange(ctx, "/root/src", 2, "/root/dst", 0, 5)).To(Succeed())
+		expectFile("/root/dst", "23456")
+		Expect(fake.Requests()).NotTo(ContainElement("PUT /root/src cp"))
+	})
+
+	It("should copy a range into an existing object", func() {
+		fake.PutFile("/root/dst", "abcdefghij")
+		Expect(client.CopyRange(ctx, "/root/src", 0, "/root/dst", 8, 4)).To(Succeed())
+		expectFile("/root/dst", "abcdefgh0123")
+	})
+
+	It("should not truncate longer destinations", func() {
+		fake.PutFile("/root/dst", "abcdefghijkl")
+		Expect(client.CopyRange(ctx, "/root/src", 0, "/root/dst", 0, 10)).To(Succeed())
+		expectFile("/root/dst", "0123456789kl")
+	})
+
+	It("should copy in chunks", func() {
+		client, err := NewTriparClient("http://tripar.example.com", "user", "pass", "share", NewBufferPool(4, 1024), 4)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+
+		Expect(client.CopyRange(ctx, "/root/src", 1, "/root/dst", 0, 9)).To(Succeed())
+		expectFile("/root/dst", "123456789")
+	})
+
+	It("should copy with one request in flight per host", func() {
+		client, err := NewTriparClient("http://tripar.example.com", "user", "pass", "share", NewBufferPool(4, 1024), 4)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+		client.SetHostLimiter(NewHostLimiter(HostLimitOptions{MaxInFlightPerHost: 1}))
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		Expect(client.CopyRange(ctx, "/root/src", 1, "/root/dst", 0, 9)).To(Succeed())
+		expectFile("/root/dst", "123456789")
+	})
+
+	It("should create empty objects", func() {
+		Expect(client.CopyRange(ctx, "/root/src", 3, "/root/dst", 0, 0)).To(Succeed())
+		expectFile("/root/dst", "")
+	})
+
+	It("should fail for invalid ranges", func() {
+		Expect(client.CopyRange(ctx, "/root/src", 5, "/root/dst", 0, 6)).To(MatchError(ErrBadRange))
+		Expect(client.CopyRange(ctx, "/root/src", -1, "/root/dst", 0, 1)).To(MatchError(ErrBadRange))
+		Expect(client.CopyRange(ctx, "/root/src", 0, "/root/dst", 1, 1)).To(MatchError(ErrNotFound))
+		Expect(client.CopyRange(ctx, "/root/missing", 0, "/root/dst", 0, 1)).To(MatchError(ErrNotFound))
+	})
+})
+
+var _ = Describe("ConcatObjects", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/a", "012")
+		fake.PutFile("/root/b", "")
+		fake.PutFile("/root/c", "3456789")
+	})
+
+	expectFile := func(p string, expected string) {
+		data, ok := fake.File(p)
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(expected))
+	}
+
+	It("should concatenate objects", func() {
+		fake.PutFile("/root/dst", "abcdefghijklmnop")
+		Expect(client.ConcatObjects(ctx, "/root/dst", []string{"/root/a", "/root/b", "/root/c"})).To(Succeed())
+		expectFile("/root/dst", "0123456789")
+		Expect(fake.Requests()).To(ContainElement("PUT /root/a cp"))
+	})
+
+	It("should append to the first source", func() {
+		Expect(client.ConcatObjects(ctx, "/root/a", []string{"/root/a", "/root/c"})).To(Succeed())
+		expectFile("/root/a", "0123456789")
+	})
+
+	It("should create an empty object without sources", func() {
+		Expect(client.ConcatObjects(ctx, "/root/dst", nil)).To(Succeed())
+		expectFile("/root/dst", "")
+	})
+
+	It("should fail for missing sources", func() {
+		Expect(client.ConcatObjects(ctx, "/root/dst", []string{"/root/a", "/root/missing"})).To(MatchError(ErrNotFound))
+		Expect(fake.Exists("/root/dst")).To(BeFalse())
+	})
+
+	It("should fail if the destination is a later source", func() {
+		Expect(client.ConcatObjects(ctx, "/root/c", []string{"/root/a", "/root/c"})).To(HaveOccurred())
+		expectFile("/root/c", "3456789")
+	})
+})
@@ -0,0 +1,170 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type CopyOptions struct {
+	// Overwrite allows copying into an existing destination. Existing
+	// directories are merged and existing objects are replaced.
+	Overwrite bool
+
+	// PreservePerms applies the source modes to copied objects. Directory
+	// modes are applied regardless if the appliance supports chmod.
+	PreservePerms bool
+
+	// PreserveOwner applies the source owner and group to copied objects and
+	// directories.
+	PreserveOwner bool
+
+	// PreserveTimes makes CopyTree fail if the source directory times can't
+	// be applied because the appliance does not support utime. Otherwise
+	// they are skipped in that case.
+	PreserveTimes bool
+
+	// Checkpoint records the copied objects and directories, by source path,
+	// so that an interrupted CopyTree resumes where it stopped when it is
+	// called again with the same checkpoint. A resumed copy does not fail
+	// because dst exists.
+	Checkpoint Checkpoint
+
+	// CheckpointInterval is how often completed items are recorded,
+	// DefaultCheckpointInterval if 0.
+	CheckpointInterval time.Duration
+}
+
+// CopyTree copies src to dst. Objects are copied with the appliance's
+// server-side copy and directories are copied recursively. Once a
+// directory's contents are copied, the source directory's mode and times are
+// applied to the destination directory, as copying the contents changes its
+// mtime. CopyTree fails with ErrAlreadyExists if dst exists unless
+// opts.Overwrite is set.
+//
+// If opts.PreservePerms, opts.PreserveOwner or opts.PreserveTimes is set and
+// the appliance does not support chmod, chown or utime, CopyTree fails with
+// ErrNotSupported. A directory root is chowned before its contents are
+// copied, so a missing capability is detected before any data is copied, and
+// later copies with the same client fail without issuing requests.
+func (tp *TriparClient) CopyTree(ctx context.Context, src string, dst string, opts *CopyOptions) (err error) {
+	defer tp.observe(ctx, "CopyTree", src, time.Now(), &err)
+
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	if opts.PreservePerms && !tp.caps.supported("chmod") {
+		return xerrors.Errorf("copy tree preserve perms: %w", ErrNotSupported)
+	}
+	if opts.PreserveOwner && !tp.caps.supported("chown") {
+		return xerrors.Errorf("copy tree preserve owner: %w", ErrNotSupported)
+	}
+	if opts.PreserveTimes && !tp.caps.supported("utime") {
+		return xerrors.Errorf("copy tree preserve times: %w", ErrNotSupported)
+	}
+
+	cp, err := tp.newCheckpointer(opts.Checkpoint, opts.CheckpointInterval)
+	if err != nil {
+		return xerrors.Errorf("copy tree error: %w", err)
+	}
+
+	if !opts.Overwrite && !cp.resumed() {
+		_, err := tp.Stat(ctx, dst, StatSkipIdentity())
+		if err == nil {
+			return xerrors.Errorf("copy tree destination %s: %w", dst, ErrAlreadyExists)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return xerrors.Errorf("copy tree destination stat error: %w", err)
+		}
+	}
+
+	info, err := tp.Stat(ctx, src, StatSkipIdentity())
+	if err != nil {
+		return xerrors.Errorf("copy tree source stat error: %w", err)
+	}
+
+	if cp.done(src) {
+		return cp.finish(nil)
+	}
+
+	return cp.finish(tp.copyTree(ctx, src, dst, info, opts, cp))
+}
+
+func (tp *TriparClient) copyTree(ctx context.Context, src string, dst string, info Stat, opts *CopyOptions, cp *checkpointer) error {
+	if !info.IsDir() {
+		if err := tp.copyTreeObject(ctx, src, dst, info, opts); err != nil {
+			return err
+		}
+		jobItemDone(ctx, info.Status.Size)
+		return cp.record(src)
+	}
+
+	if err := tp.CreateDirectory(ctx, dst); err != nil && !errors.Is(err, ErrAlreadyExists) {
+		return err
+	}
+
+	if opts.PreserveOwner {
+		if err := tp.Chown(ctx, dst, info.Status.Uid, info.Status.Gid); err != nil {
+			return err
+		}
+	}
+
+	entries, err := tp.List(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries.Entries {
+		srcPath := joinPath(src, entry.Name)
+		if cp.done(srcPath) {
+			continue
+		}
+
+		entryInfo, err := tp.Stat(ctx, srcPath, StatSkipIdentity())
+		if err != nil {
+			return err
+		}
+
+		if err := tp.copyTree(ctx, srcPath, joinPath(dst, entry.Name), entryInfo, opts, cp); err != nil {
+			return err
+		}
+	}
+
+	// the mode is applied after the contents are copied, as it may not allow
+	// writing into the directory
+	if opts.PreservePerms || tp.caps.supported("chmod") {
+		err := tp.Chmod(ctx, dst, info.Status.Mode)
+		if err != nil && (opts.PreservePerms || !errors.Is(err, ErrNotSupported)) {
+			return err
+		}
+	}
+
+	if opts.PreserveTimes || tp.caps.supported("utime") {
+		err := tp.Utime(ctx, dst, info.Status.AccessTime(), info.Status.ModTime())
+		if err != nil && (opts.PreserveTimes || !errors.Is(err, ErrNotSupported)) {
+			return err
+		}
+	}
+	jobItemDone(ctx, 0)
+
+	return cp.record(src)
+}
+
+func (tp *TriparClient) copyTreeObject(ctx context.Context, src string, dst string, info Stat, opts *CopyOptions) error {
+	if err := tp.CopyObject(ctx, src, dst); err != nil {
+		return err
+	}
+	// chown before chmod, as changing the owner can clear setuid bits
+	if opts.PreserveOwner {
+		if err := tp.Chown(ctx, dst, info.Status.Uid, info.Status.Gid); err != nil {
+			return err
+		}
+	}
+	if opts.PreservePerms {
+		return tp.Chmod(ctx, dst, info.Status.Mode)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package triparclient_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("CopyTree", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/src/a/b")
+		fake.PutFile("/root/src/1", "1")
+		fake.PutFile("/root/src/a/2", "22")
+		fake.PutFile("/root/src/a/b/3", "333")
+	})
+
+	expectFile := func(p string, expected string) {
+		data, ok := fake.File(p)
+		ExpectWithOffset(1, ok).To(BeTrue())
+		ExpectWithOffset(1, string(data)).To(Equal(expected))
+	}
+
+	It("should copy trees", func() {
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", nil)).To(Succeed())
+
+		expectFile("/root/dst/1", "1")
+		expectFile("/root/dst/a/2", "22")
+		expectFile("/root/dst/a/b/3", "333")
+		expectFile("/root/src/a/b/3", "333")
+	})
+
+	It("should preserve directory modes and times", func() {
+		Expect(client.Chmod(ctx, "/root/src/a", 0700)).To(Succeed())
+		mtime := time.Unix(1500000000, 0)
+		Expect(client.Utime(ctx, "/root/src/a", mtime, mtime)).To(Succeed())
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", nil)).To(Succeed())
+
+		info, err := client.Stat(ctx, "/root/dst/a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+		Expect(info.Status.Mode & 07777).To(Equal(int32(0700)))
+		Expect(info.Status.Mtime).To(Equal(float64(1500000000)))
+
+		src, err := client.Stat(ctx, "/root/src")
+		Expect(err).NotTo(HaveOccurred())
+		dst, err := client.Stat(ctx, "/root/dst")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst.Status.Mtime).To(Equal(src.Status.Mtime))
+	})
+
+	It("should copy single objects", func() {
+		Expect(client.CopyTree(ctx, "/root/src/a/2", "/root/2", nil)).To(Succeed())
+		expectFile("/root/2", "22")
+	})
+
+	It("should not overwrite existing destinations by default", func() {
+		fake.Mkdir("/root/dst")
+		fake.PutFile("/root/dst/1", "old")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", nil)).To(MatchError(ErrAlreadyExists))
+		expectFile("/root/dst/1", "old")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", &CopyOptions{Overwrite: true})).To(Succeed())
+		expectFile("/root/dst/1", "1")
+		expectFile("/root/dst/a/b/3", "333")
+	})
+
+	It("should fail for missing sources", func() {
+		Expect(client.CopyTree(ctx, "/root/missing", "/root/dst", nil)).To(MatchError(ErrNotFound))
+	})
+
+	It("should preserve object modes and owners", func() {
+		Expect(client.Chmod(ctx, "/root/src/a/2", 0600)).To(Succeed())
+		Expect(client.Chown(ctx, "/root/src/a/2", 1000, 100)).To(Succeed())
+		Expect(client.Chown(ctx, "/root/src/a", 1001, 101)).To(Succeed())
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", &CopyOptions{
+			PreservePerms: true,
+			PreserveOwner: true,
+		})).To(Succeed())
+
+		info, err := client.Stat(ctx, "/root/dst/a/2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Mode).To(Equal(int32(0100600)))
+		Expect(info.Status.Uid).To(Equal(int32(1000)))
+		Expect(info.Status.Gid).To(Equal(int32(100)))
+
+		info, err = client.Stat(ctx, "/root/dst/a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Uid).To(Equal(int32(1001)))
+		Expect(info.Status.Gid).To(Equal(int32(101)))
+	})
+
+	It("should fail before copying data if chown is not supported", func() {
+		fake.Unsupport("chown")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", &CopyOptions{PreserveOwner: true})).To(MatchError(ErrNotSupported))
+		Expect(fake.Exists("/root/dst/1")).To(BeFalse())
+
+		requests := len(fake.Requests())
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst2", &CopyOptions{PreserveOwner: true})).To(MatchError(ErrNotSupported))
+		Expect(fake.Requests()).To(HaveLen(requests))
+	})
+
+	It("should skip directory modes if chmod is not supported", func() {
+		fake.Unsupport("chmod")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", nil)).To(Succeed())
+		expectFile("/root/dst/a/b/3", "333")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst2", &CopyOptions{PreservePerms: true})).To(MatchError(ErrNotSupported))
+	})
+
+	It("should skip directory times if utime is not supported", func() {
+		fake.Unsupport("utime")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst", nil)).To(Succeed())
+		expectFile("/root/dst/a/b/3", "333")
+
+		Expect(client.CopyTree(ctx, "/root/src", "/root/dst2", &CopyOptions{PreserveTimes: true})).To(MatchError(ErrNotSupported))
+		Expect(fake.Exists("/root/dst2")).To(BeFalse())
+	})
+})
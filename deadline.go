@@ -0,0 +1,87 @@
+package triparclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// chunks are not shrunk below minDeadlineChunkSize, as the request
+	// latency would dominate smaller ones
+	minDeadlineChunkSize = 64 * 1024
+
+	// deadlineChunkMargin is the fraction of the estimated transferable bytes
+	// used for a chunk, leaving room for throughput fluctuations
+	deadlineChunkMargin = 0.8
+
+	throughputSmoothing = 0.3
+)
+
+// throughputEstimator is an exponentially weighted moving average of the
+// throughput of chunk requests.
+type throughputEstimator struct {
+	mx             sync.Mutex
+	bytesPerSecond float64
+}
+
+func (e *throughputEstimator) observe(n int64, d time.Duration) {
+	if n <= 0 || d <= 0 {
+		return
+	}
+
+	bps := float64(n) / d.Seconds()
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if e.bytesPerSecond == 0 {
+		e.bytesPerSecond = bps
+	} else {
+		e.bytesPerSecond = throughputSmoothing*bps + (1-throughputSmoothing)*e.bytesPerSecond
+	}
+}
+
+func (e *throughputEstimator) estimate() float64 {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	return e.bytesPerSecond
+}
+
+// deadlineChunkSize shrinks size so that a chunk request can plausibly
+// complete before ctx's deadline, based on the throughput of previous chunks.
+// It fails with context.DeadlineExceeded if not even a minimal chunk can.
+func (tp *TriparClient) deadlineChunkSize(ctx context.Context, size int64) (int64, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return size, nil
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+
+	bps := tp.throughput.estimate()
+	if bps <= 0 {
+		return size, nil
+	}
+
+	fits := int64(bps * remaining.Seconds() * deadlineChunkMargin)
+	if fits >= size {
+		return size, nil
+	}
+
+	min := int64(minDeadlineChunkSize)
+	if size < min {
+		min = size
+	}
+	if fits < min {
+		return 0, xerrors.Errorf("chunk of %d bytes can't complete in %s: %w", min, remaining, context.DeadlineExceeded)
+	}
+
+	return fits, nil
+}
@@ -0,0 +1,108 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Deadline-aware chunk sizing", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var mx sync.Mutex
+	var ranges []string
+	var delay time.Duration
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/small", strings.Repeat("x", 8*1024))
+		fake.PutFile("/root/large", strings.Repeat("x", 256*1024))
+
+		ranges = nil
+		delay = 100 * time.Millisecond
+
+		client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+			if rng := r.Header.Get("Range"); rng != "" && r.Method == "GET" {
+				mx.Lock()
+				ranges = append(ranges, rng)
+				d := delay
+				mx.Unlock()
+				time.Sleep(d)
+			}
+			return fake.RoundTrip(r)
+		})
+
+		// 2 chunks of 4KB taking 100ms each, about 40KB/s
+		rd, _, err := client.GetObject(ctx, "/root/small", &ioutils.FileSpan{Start: 0, End: 8*1024 - 1}, GetChunkSize(4*1024))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		mx.Lock()
+		ranges = nil
+		mx.Unlock()
+	})
+
+	It("should shrink chunks to fit the deadline", func() {
+		dctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+
+		rd, _, err := client.GetObject(dctx, "/root/large", &ioutils.FileSpan{Start: 0, End: 256*1024 - 1}, GetChunkSize(128*1024))
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(data).To(HaveLen(256 * 1024))
+
+		mx.Lock()
+		defer mx.Unlock()
+		var start, end int64
+		_, err = fmt.Sscanf(ranges[0], "bytes=%d-%d", &start, &end)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(end - start + 1).To(BeNumerically("<", 128*1024))
+		Expect(end - start + 1).To(BeNumerically(">=", 64*1024))
+	})
+
+	It("should fail fast if a chunk can't complete before the deadline", func() {
+		dctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		defer cancel()
+
+		rd, _, err := client.GetObject(dctx, "/root/large", &ioutils.FileSpan{Start: 0, End: 256*1024 - 1}, GetChunkSize(128*1024))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rd)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(dctx.Err()).To(BeNil())
+		Expect(rd.Close()).To(Succeed())
+
+		mx.Lock()
+		defer mx.Unlock()
+		Expect(ranges).To(BeEmpty())
+	})
+
+	It("should not limit chunks without a deadline", func() {
+		rd, _, err := client.GetObject(ctx, "/root/large", &ioutils.FileSpan{Start: 0, End: 256*1024 - 1}, GetChunkSize(128*1024))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		mx.Lock()
+		defer mx.Unlock()
+		Expect(ranges).To(Equal([]string{"bytes=0-131071", "bytes=131072-262143"}))
+	})
+})
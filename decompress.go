@@ -0,0 +1,53 @@
+package triparclient
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/xerrors"
+)
+
+// isGzipped reports whether the object at path is stored gzip-compressed,
+// either by the .gz naming convention or by its Content-Encoding.
+func isGzipped(path string, stat *Stat) bool {
+	return strings.HasSuffix(path, ".gz") || strings.EqualFold(stat.ContentEncoding, "gzip")
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	rd io.ReadCloser
+}
+
+func (r *gzipReadCloser) Close() error {
+	err := r.Reader.Close()
+	if closeErr := r.rd.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// decompress returns the decompressed content of rd if the object is gzipped.
+// rd is closed if it fails.
+func decompress(rd io.ReadCloser, path string, span *ioutils.FileSpan, stat *Stat) (io.ReadCloser, error) {
+	if !isGzipped(path, stat) {
+		return rd, nil
+	}
+
+	if span != nil {
+		rd.Close()
+		return nil, xerrors.Errorf("decompress %s: ranges of compressed objects are not supported: %w", path, ErrNotSupported)
+	}
+
+	zrd, err := gzip.NewReader(rd)
+	if err != nil {
+		rd.Close()
+		return nil, xerrors.Errorf("decompress %s error: %w", path, err)
+	}
+
+	return &gzipReadCloser{
+		Reader: zrd,
+		rd:     rd,
+	}, nil
+}
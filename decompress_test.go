@@ -0,0 +1,94 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Decompress", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var compressed []byte
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			rsp, err := fake.RoundTrip(r)
+			if err == nil && r.Method == "GET" && r.URL.Query().Get("cmd") == "" && strings.HasSuffix(r.URL.Opaque+r.URL.Path, "/encoded") {
+				rsp.Header.Set("Content-Encoding", "gzip")
+			}
+			return rsp, err
+		}))
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte("log line 1\nlog line 2\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+		compressed = buf.Bytes()
+
+		fake.PutFile("/root/app.log.gz", string(compressed))
+		fake.PutFile("/root/encoded", string(compressed))
+	})
+
+	read := func(rd io.ReadCloser) string {
+		defer rd.Close()
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should decompress .gz objects", func() {
+		rd, info, err := client.GetObject(ctx, "/root/app.log.gz", nil, GetDecompress())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(rd)).To(Equal("log line 1\nlog line 2\n"))
+		Expect(info.Status.Size).To(Equal(int64(len(compressed))))
+	})
+
+	It("should decompress objects with a gzip Content-Encoding", func() {
+		rd, info, err := client.GetObject(ctx, "/root/encoded", nil, GetDecompress())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(rd)).To(Equal("log line 1\nlog line 2\n"))
+		Expect(info.ContentEncoding).To(Equal("gzip"))
+	})
+
+	It("should return compressed content without the option", func() {
+		rd, _, err := client.GetObject(ctx, "/root/app.log.gz", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(rd)).To(Equal(string(compressed)))
+	})
+
+	It("should not change uncompressed objects", func() {
+		fake.PutFile("/root/plain.log", "plain")
+
+		rd, _, err := client.GetObject(ctx, "/root/plain.log", nil, GetDecompress())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(rd)).To(Equal("plain"))
+	})
+
+	It("should reject ranges of compressed objects", func() {
+		_, _, err := client.GetObject(ctx, "/root/app.log.gz", &ioutils.FileSpan{Start: 0, End: 5}, GetDecompress())
+		Expect(err).To(MatchError(ErrNotSupported))
+	})
+
+	It("should fail for corrupted objects", func() {
+		fake.PutFile("/root/broken.gz", "this is not gzip")
+
+		_, _, err := client.GetObject(ctx, "/root/broken.gz", nil, GetDecompress())
+		Expect(err).To(MatchError(gzip.ErrHeader))
+	})
+})
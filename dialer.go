@@ -0,0 +1,233 @@
+package triparclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFallbackDelay is the delay before a connection to the other address
+// family is attempted if the host has both IPv4 and IPv6 addresses.
+const DefaultFallbackDelay = 300 * time.Millisecond
+
+// dialer is the client's own dialer, so that connections can be counted
+// without affecting other users of httpclient's shared transport.
+type dialer struct {
+	net.Dialer
+	stats         *clientStats
+	dnsCache      atomic.Pointer[dnsCache]
+	fallbackDelay atomic.Int64
+	hostLimiter   atomic.Pointer[HostLimiter]
+}
+
+func newDialer(stats *clientStats) *dialer {
+	d := &dialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		stats: stats,
+	}
+	d.fallbackDelay.Store(int64(DefaultFallbackDelay))
+	return d
+}
+
+func (d *dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	conn, err := d.dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt64(&d.stats.openConns, 1)
+
+	return &countingConn{
+		Conn:  conn,
+		stats: d.stats,
+	}, nil
+}
+
+func (d *dialer) netDialer() *net.Dialer {
+	nd := d.Dialer
+	nd.FallbackDelay = time.Duration(d.fallbackDelay.Load())
+	return &nd
+}
+
+func (d *dialer) dial(ctx context.Context, network string, address string) (net.Conn, error) {
+	cache := d.dnsCache.Load()
+	limiter := d.hostLimiter.Load()
+	if limiter != nil && limiter.opts.MaxConnsPerHost <= 0 {
+		limiter = nil
+	}
+	if cache == nil && limiter == nil {
+		// net.Dialer races address families itself
+		return d.netDialer().DialContext(ctx, network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	if cache != nil {
+		addrs, err = cache.lookup(ctx, host)
+	} else if net.ParseIP(host) != nil {
+		addrs = []string{host}
+	} else {
+		addrs, err = net.DefaultResolver.LookupHost(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	if limiter != nil {
+		// addresses are dialed one at a time, so that each dial takes a
+		// single connection slot
+		return d.dialLimited(ctx, limiter, network, port, addrs)
+	}
+
+	return d.dialDualStack(ctx, network, port, addrs)
+}
+
+// partitionAddrs splits addrs into the addresses of the first address's
+// family and the rest, the same as net.Dialer does.
+func partitionAddrs(addrs []string) (primaries []string, fallbacks []string) {
+	isIPv4 := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		return ip != nil && ip.To4() != nil
+	}
+
+	primaryIPv4 := isIPv4(addrs[0])
+	for _, addr := range addrs {
+		if isIPv4(addr) == primaryIPv4 {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+
+	return primaries, fallbacks
+}
+
+// dialSerial tries addrs in order and returns the first connection.
+func (d *dialer) dialSerial(ctx context.Context, network string, port string, addrs []string) (net.Conn, error) {
+	nd := d.netDialer()
+
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := nd.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// dialDualStack dials the primary address family and starts dialing the other
+// family if the primary one fails or doesn't connect within the fallback
+// delay, so an unreachable family doesn't stall new connections.
+func (d *dialer) dialDualStack(ctx context.Context, network string, port string, addrs []string) (net.Conn, error) {
+	primaries, fallbacks := partitionAddrs(addrs)
+
+	delay := time.Duration(d.fallbackDelay.Load())
+	if len(fallbacks) == 0 || delay < 0 {
+		return d.dialSerial(ctx, network, port, addrs)
+	}
+	if delay == 0 {
+		delay = DefaultFallbackDelay
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, 2)
+	pending := 0
+	start := func(addrs []string) {
+		pending++
+		go func() {
+			conn, err := d.dialSerial(ctx, network, port, addrs)
+			results <- result{conn: conn, err: err}
+		}()
+	}
+
+	start(primaries)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	fallbackStarted := false
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				start(fallbacks)
+			}
+
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				// close the connection of the other family if it also succeeds
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if res := <-results; res.conn != nil {
+							res.conn.Close()
+						}
+					}
+				}(pending)
+				return res.conn, nil
+			}
+
+			if firstErr == nil {
+				firstErr = res.err
+			}
+
+			if !fallbackStarted {
+				fallbackStarted = true
+				start(fallbacks)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	stats *clientStats
+	once  sync.Once
+}
+
+func (c *countingConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.stats.openConns, -1)
+	})
+	return c.Conn.Close()
+}
+
+// SetFallbackDelay sets how long the client's dialer waits for a connection
+// to the preferred address family before also dialing the other one. A
+// negative delay disables the fallback. It has no effect if the client's
+// transport was replaced.
+func (tp *TriparClient) SetFallbackDelay(delay time.Duration) {
+	tp.dialer.fallbackDelay.Store(int64(delay))
+}
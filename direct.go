@@ -0,0 +1,160 @@
+package triparclient
+
+import (
+	"context"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// directSource is a reader whose remaining length is known and which can be
+// read at any offset, so it can be uploaded without copying it into pieces.
+type directSource struct {
+	reader io.ReaderAt
+	start  int64
+	size   int64
+}
+
+// newDirectSource returns a directSource for readers which can seek, e.g.
+// *os.File, *bytes.Reader and *strings.Reader. Files which are not regular
+// files, like pipes, are not supported.
+func newDirectSource(reader io.Reader) (*directSource, bool) {
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok {
+		return nil, false
+	}
+
+	if file, ok := reader.(*os.File); ok {
+		info, err := file.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, false
+		}
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false
+	}
+
+	var size int64
+	if sized, ok := reader.(interface{ Len() int }); ok {
+		size = int64(sized.Len())
+	} else {
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, false
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, false
+		}
+		size = end - start
+	}
+
+	readerAt, ok := reader.(io.ReaderAt)
+	if !ok {
+		readerAt = &seekReaderAt{reader: seeker}
+	}
+
+	return &directSource{
+		reader: readerAt,
+		start:  start,
+		size:   size,
+	}, true
+}
+
+// section returns size bytes at offset, relative to the start of the source.
+func (s *directSource) section(offset int64, size int64) *io.SectionReader {
+	return io.NewSectionReader(s.reader, s.start+offset, size)
+}
+
+// seekReaderAt implements io.ReaderAt by seeking. It must not be used
+// concurrently.
+type seekReaderAt struct {
+	reader io.ReadSeeker
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if _, err := r.reader.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.reader, p)
+}
+
+// putDirect uploads src with requests whose bodies are read directly from
+// src. They are seekable, so the requests can be retried.
+func (tp *TriparClient) putDirect(
+	ctx context.Context,
+	path string,
+	reader io.Reader,
+	src *directSource,
+	opts *PutOptions,
+) (err error) {
+	defer func() {
+		if err != nil {
+			_ = tp.deleteObject(ctx, path)
+		}
+	}()
+
+	written := int64(0)
+
+	if src.size == 0 {
+		if err := tp.putChunk(ctx, path, 0, src.section(0, 0), 0); err != nil {
+			return err
+		}
+	}
+
+	for written < src.size {
+		size := src.size - written
+		if tp.UploadChunkSize > 0 && size > tp.UploadChunkSize {
+			size = tp.UploadChunkSize
+		}
+		size = tp.limitRequestSize(size)
+		size, err := tp.deadlineChunkSize(ctx, size)
+		if err != nil {
+			return err
+		}
+
+		offset := written
+		body := func(skip int64) io.Reader {
+			return src.section(offset+skip, size-skip)
+		}
+
+		chunkStart := time.Now()
+
+		if written == 0 {
+			err = tp.putChunk(ctx, path, 0, body(0), size)
+		} else {
+			err = tp.appendRange(ctx, path, written, size, body)
+		}
+		if err != nil {
+			if isRequestTooLarge(err) && tp.lowerRequestSize(size) {
+				continue
+			}
+			return err
+		}
+
+		tp.chunkDone(ctx, "PutObject", path, written, size, chunkStart)
+
+		if opts.VerifyChunks {
+			crc := crc32.New(castagnoli)
+			if _, err := io.Copy(crc, body(0)); err != nil {
+				return err
+			}
+			if err := tp.verifyChunk(ctx, path, written, size, crc.Sum32()); err != nil {
+				return err
+			}
+		}
+
+		written += size
+	}
+
+	// the reader is left at the end, as if it was read
+	if seeker, ok := reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(src.start+src.size, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	return tp.putFinish(ctx, path, written, opts)
+}
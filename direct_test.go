@@ -0,0 +1,132 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type failingBufferPool struct{}
+
+func (failingBufferPool) Get() []byte {
+	Fail("buffer pool used")
+	return nil
+}
+
+func (failingBufferPool) Put(buffer []byte) {}
+
+var _ = Describe("direct uploads", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failures int32
+	var puts []int64
+
+	data := strings.Repeat("0123456789", 300)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		atomic.StoreInt32(&failures, 0)
+		puts = nil
+
+		var err error
+		client, err = NewTriparClient("http://tripar.example.com", "user", "pass", "share", failingBufferPool{}, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{
+			Transport: funcTransport(func(r *http.Request) (*http.Response, error) {
+				if (r.Method == "PUT" || r.Method == "POST") && r.URL.Query().Get("cmd") == "" {
+					puts = append(puts, r.ContentLength)
+					if atomic.AddInt32(&failures, -1) >= 0 {
+						_, _ = io.Copy(io.Discard, r.Body)
+						return testResponse(http.StatusServiceUnavailable, "text/plain", "unavailable"), nil
+					}
+				}
+				return fake.RoundTrip(r)
+			}),
+		}
+	})
+
+	It("should stream files with a single request", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(path, []byte(data), 0o644)).To(Succeed())
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		Expect(client.PutObject(ctx, "/root/object", file)).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(puts).To(Equal([]int64{int64(len(data))}))
+
+		// the file is read to the end
+		offset, err := file.Seek(0, io.SeekCurrent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(offset).To(Equal(int64(len(data))))
+	})
+
+	It("should upload from the current offset", func() {
+		reader := bytes.NewReader([]byte(data))
+		_, err := reader.Seek(1000, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.PutObject(ctx, "/root/object", reader)).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data[1000:]))
+		Expect(reader.Len()).To(Equal(0))
+	})
+
+	It("should split readers into UploadChunkSize requests", func() {
+		client.UploadChunkSize = 1024
+
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(puts).To(Equal([]int64{1024, 1024, 952}))
+	})
+
+	It("should upload empty readers", func() {
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(""))).To(Succeed())
+
+		written, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(written).To(BeEmpty())
+	})
+
+	It("should seek back when retrying", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts:        2,
+			RetryNonIdempotent: true,
+		}
+		atomic.StoreInt32(&failures, 1)
+
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(puts).To(HaveLen(2))
+	})
+
+	It("should reject readers larger than MaxObjectSize before sending", func() {
+		client.MaxObjectSize = 1000
+
+		err := client.PutObject(ctx, "/root/object", strings.NewReader(data))
+		Expect(err).To(MatchError(ErrObjectTooLarge))
+		Expect(puts).To(BeEmpty())
+	})
+})
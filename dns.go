@@ -0,0 +1,93 @@
+package triparclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type DNSCacheOptions struct {
+	// TTL is how long lookup results are cached. DNS record TTLs are not
+	// available from the system resolver, so it applies to all records.
+	TTL time.Duration
+
+	// StaleOnError makes the dialer use expired results if a lookup fails.
+	StaleOnError bool
+
+	// LookupHost resolves host names, net.DefaultResolver.LookupHost if nil.
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+type dnsCache struct {
+	opts  DNSCacheOptions
+	stats *clientStats
+
+	mx      sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+func newDNSCache(opts DNSCacheOptions, stats *clientStats) *dnsCache {
+	if opts.LookupHost == nil {
+		opts.LookupHost = net.DefaultResolver.LookupHost
+	}
+
+	return &dnsCache{
+		opts:    opts,
+		stats:   stats,
+		entries: map[string]dnsCacheEntry{},
+	}
+}
+
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	c.mx.Lock()
+	entry, ok := c.entries[host]
+	c.mx.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	atomic.AddInt64(&c.stats.dnsLookups, 1)
+
+	addrs, err := c.opts.LookupHost(ctx, host)
+	if err != nil {
+		if ok && c.opts.StaleOnError {
+			return entry.addrs, nil
+		}
+		return nil, err
+	}
+
+	c.mx.Lock()
+	c.entries[host] = dnsCacheEntry{
+		addrs:   addrs,
+		expires: time.Now().Add(c.opts.TTL),
+	}
+	c.mx.Unlock()
+
+	return addrs, nil
+}
+
+// EnableDNSCache makes the client's dialer cache host lookups. It has no
+// effect if the client's transport was replaced.
+func (tp *TriparClient) EnableDNSCache(opts DNSCacheOptions) error {
+	if opts.TTL <= 0 {
+		return xerrors.Errorf("invalid dns cache ttl: %s", opts.TTL)
+	}
+
+	tp.dialer.dnsCache.Store(newDNSCache(opts, tp.stats))
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("EnableDNSCache", func() {
+	var server *httptest.Server
+	var client *TriparClient
+	var mx sync.Mutex
+	var lookups []string
+	var lookupErr error
+
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		mx.Lock()
+		defer mx.Unlock()
+		lookups = append(lookups, host)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+
+	BeforeEach(func() {
+		lookups = nil
+		lookupErr = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			// close the connection so that every request dials
+			w.Header().Set("Connection", "close")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		client, err = NewTriparClient("http://tripar.invalid:"+u.Port(), "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should cache lookups", func() {
+		Expect(client.EnableDNSCache(DNSCacheOptions{
+			TTL:        time.Minute,
+			LookupHost: lookupHost,
+		})).To(Succeed())
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Stat(context.Background(), "/object")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(lookups).To(Equal([]string{"tripar.invalid"}))
+		Expect(client.Stats().DNSLookups).To(Equal(int64(1)))
+	})
+
+	It("should use stale results if lookups fail", func() {
+		Expect(client.EnableDNSCache(DNSCacheOptions{
+			TTL:          time.Nanosecond,
+			StaleOnError: true,
+			LookupHost:   lookupHost,
+		})).To(Succeed())
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+
+		mx.Lock()
+		lookupErr = errors.New("dns unavailable")
+		mx.Unlock()
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lookups).To(HaveLen(2))
+	})
+
+	It("should fail if lookups fail without stale results", func() {
+		Expect(client.EnableDNSCache(DNSCacheOptions{
+			TTL:        time.Nanosecond,
+			LookupHost: lookupHost,
+		})).To(Succeed())
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+
+		mx.Lock()
+		lookupErr = errors.New("dns unavailable")
+		mx.Unlock()
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ContainSubstring("dns unavailable")))
+	})
+
+	It("should require a ttl", func() {
+		Expect(client.EnableDNSCache(DNSCacheOptions{})).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("SetFallbackDelay", func() {
+	It("should fall back to the other address family", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		client, err := NewTriparClient("http://tripar.invalid:"+u.Port(), "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.SetFallbackDelay(50 * time.Millisecond)
+		Expect(client.EnableDNSCache(DNSCacheOptions{
+			TTL: time.Minute,
+			LookupHost: func(ctx context.Context, host string) ([]string, error) {
+				// the documentation IPv6 prefix is never reachable, it either
+				// fails immediately or hangs
+				return []string{"2001:db8::1", "127.0.0.1"}, nil
+			},
+		})).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err = client.Stat(ctx, "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+	})
+})
@@ -0,0 +1,40 @@
+package triparclient
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// GetObjectTo streams the object at path once and writes its bytes to all
+// writers, e.g. a hasher and a file, so that verifying a copy does not need a
+// second download. Writes happen in order and the first failing writer aborts
+// the download. It returns the number of bytes written to each writer.
+func (tp *TriparClient) GetObjectTo(
+	ctx context.Context,
+	path string,
+	writers ...io.Writer,
+) (n int64, err error) {
+	defer tp.observe(ctx, "GetObjectTo", path, time.Now(), &err)
+
+	reader, _, err := tp.GetObject(ctx, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	buffer, err := tp.getBuffer(ctx)
+	if err != nil {
+		return 0, xerrors.Errorf("get object to buffer error: %w", err)
+	}
+	defer tp.putBuffer(buffer)
+
+	n, err = io.CopyBuffer(io.MultiWriter(writers...), reader, buffer)
+	if err != nil {
+		return n, xerrors.Errorf("get object to copy error: %w", err)
+	}
+
+	return n, nil
+}
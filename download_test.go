@@ -0,0 +1,65 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+var _ = Describe("GetObjectTo", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+	})
+
+	It("should write the object to all writers with one download", func() {
+		data := bytes.Repeat([]byte("0123456789"), 500)
+		fake.PutFile("/object", string(data))
+
+		var file bytes.Buffer
+		hash := md5.New()
+		n, err := client.GetObjectTo(ctx, "/object", &file, hash)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(len(data))))
+		Expect(file.Bytes()).To(Equal(data))
+		Expect(fmt.Sprintf("%x", hash.Sum(nil))).To(Equal(fmt.Sprintf("%x", md5.Sum(data))))
+
+		gets := 0
+		for _, req := range fake.Requests() {
+			if req == "GET /object" {
+				gets++
+			}
+		}
+		Expect(gets).To(Equal(1))
+	})
+
+	It("should fail if a writer fails", func() {
+		fake.PutFile("/object", "12345")
+
+		var file bytes.Buffer
+		_, err := client.GetObjectTo(ctx, "/object", &file, failingWriter{})
+		Expect(err).To(MatchError(ContainSubstring("disk full")))
+	})
+
+	It("should fail for missing objects", func() {
+		_, err := client.GetObjectTo(ctx, "/missing", &bytes.Buffer{})
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+})
@@ -0,0 +1,54 @@
+package triparclient
+
+import (
+	"context"
+)
+
+type PlannedOperation struct {
+	// Op is the method which would have been executed, e.g. "DeleteObject".
+	Op string
+
+	Path string
+
+	// Destination is set for MoveObject, CopyObject and CopyRange. For
+	// ConcatObjects Path is the destination.
+	Destination string
+
+	// Size is set for Truncate, Preallocate and PutObject's SizeHint, and is
+	// the length of the range for CopyRange, WriteAt and PunchHole.
+	Size int64
+
+	// Offset is set for CopyRange, WriteAt and PunchHole, DestinationOffset
+	// for CopyRange.
+	Offset            int64
+	DestinationOffset int64
+
+	// Sources are set for ConcatObjects.
+	Sources []string
+}
+
+// WithDryRun returns a client which does not execute mutating operations but
+// passes them to report instead, so bulk cleanups and copies can be
+// previewed. Covered are DeleteObject, DeleteDirectory, MoveObject,
+// CopyObject, CopyRange, ConcatObjects, Truncate, CreateDirectory,
+// CreateDirectories, PutObject, WriteAt, PunchHole, Preallocate, Chmod,
+// Chown, Utime, Touch and SetObjectMetadata, and operations built on them
+// like Purge, DeleteTree, Rename, CopyTree, SyncDir, Mirror and moving
+// entries to the trash. They succeed without sending requests, while reads
+// like Stat and List are executed as usual. Checkpoints and SyncOptions'
+// StateFile are read but not written. report may be called concurrently,
+// e.g. by Purge.
+func (tp *TriparClient) WithDryRun(report func(ctx context.Context, op PlannedOperation)) *TriparClient {
+	c := tp.clone()
+	c.dryRun = report
+	return c
+}
+
+// planned reports op and returns true if the client is in dry-run mode.
+func (tp *TriparClient) planned(ctx context.Context, op PlannedOperation) bool {
+	if tp.dryRun == nil {
+		return false
+	}
+	tp.dryRun(ctx, op)
+	return true
+}
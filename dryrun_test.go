@@ -0,0 +1,162 @@
+package triparclient_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("WithDryRun", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var dry *TriparClient
+	var fake *fakeTripar
+	var mx sync.Mutex
+	var planned []PlannedOperation
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/dir/sub")
+		fake.PutFile("/root/object", "12345")
+		fake.PutFile("/root/dir/sub/object", "123")
+
+		planned = nil
+		dry = client.WithDryRun(func(ctx context.Context, op PlannedOperation) {
+			mx.Lock()
+			defer mx.Unlock()
+
+			planned = append(planned, op)
+		})
+	})
+
+	It("should report destructive operations instead of executing them", func() {
+		Expect(dry.DeleteObject(ctx, "/root/object")).To(Succeed())
+		Expect(dry.MoveObject(ctx, "/root/object", "/root/moved")).To(Succeed())
+		Expect(dry.CopyObject(ctx, "/root/object", "/root/copy")).To(Succeed())
+		Expect(dry.Truncate(ctx, "/root/object", 0)).To(Succeed())
+		Expect(dry.DeleteTree(ctx, "/root/dir")).To(Succeed())
+
+		Expect(planned).To(Equal([]PlannedOperation{
+			{Op: "DeleteObject", Path: "/root/object"},
+			{Op: "MoveObject", Path: "/root/object", Destination: "/root/moved"},
+			{Op: "CopyObject", Path: "/root/object", Destination: "/root/copy"},
+			{Op: "Truncate", Path: "/root/object", Size: 0},
+			{Op: "DeleteObject", Path: "/root/dir/sub/object"},
+			{Op: "DeleteDirectory", Path: "/root/dir/sub"},
+			{Op: "DeleteDirectory", Path: "/root/dir"},
+		}))
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("12345"))
+		Expect(fake.Exists("/root/dir/sub/object")).To(BeTrue())
+		Expect(fake.Exists("/root/moved")).To(BeFalse())
+		Expect(fake.Exists("/root/copy")).To(BeFalse())
+	})
+
+	It("should not write ranges of CopyRange and ConcatObjects", func() {
+		fake.PutFile("/root/other", "678")
+
+		Expect(dry.CopyRange(ctx, "/root/object", 1, "/root/other", 3, 2)).To(Succeed())
+		Expect(dry.CopyRange(ctx, "/root/object", 0, "/root/copy", 0, 5)).To(Succeed())
+		Expect(dry.ConcatObjects(ctx, "/root/concat", []string{"/root/object", "/root/other"})).To(Succeed())
+
+		Expect(planned).To(Equal([]PlannedOperation{
+			{Op: "CopyRange", Path: "/root/object", Destination: "/root/other", Size: 2, Offset: 1, DestinationOffset: 3},
+			{Op: "CopyRange", Path: "/root/object", Destination: "/root/copy", Size: 5},
+			{Op: "ConcatObjects", Path: "/root/concat", Sources: []string{"/root/object", "/root/other"}},
+		}))
+
+		for _, req := range fake.Requests() {
+			Expect(req).NotTo(HavePrefix("PUT "))
+			Expect(req).NotTo(HavePrefix("POST "))
+		}
+		data, ok := fake.File("/root/other")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("678"))
+		Expect(fake.Exists("/root/copy")).To(BeFalse())
+		Expect(fake.Exists("/root/concat")).To(BeFalse())
+	})
+
+	It("should not create directories or set attributes in CopyTree", func() {
+		fake.PutFile("/root/copy/sub/object", "old")
+
+		opts := &CopyOptions{
+			Overwrite:     true,
+			PreservePerms: true,
+			PreserveTimes: true,
+		}
+		Expect(dry.CopyTree(ctx, "/root/dir", "/root/copy", opts)).To(Succeed())
+
+		ops := []string{}
+		for _, op := range planned {
+			ops = append(ops, op.Op+" "+op.Path)
+		}
+		Expect(ops).To(ContainElements(
+			"CreateDirectory /root/copy",
+			"CreateDirectory /root/copy/sub",
+			"CopyObject /root/dir/sub/object",
+			"Chmod /root/copy/sub/object",
+			"Utime /root/copy",
+		))
+
+		for _, req := range fake.Requests() {
+			Expect(req).NotTo(HavePrefix("PUT "))
+			Expect(req).NotTo(HavePrefix("POST "))
+		}
+		data, ok := fake.File("/root/copy/sub/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("old"))
+	})
+
+	It("should not upload files or save the state in Mirror", func() {
+		local, err := os.MkdirTemp("", "triparclient-dryrun")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, local)
+		Expect(os.MkdirAll(filepath.Join(local, "dir"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(local, "dir", "a"), []byte("aaa"), 0o644)).To(Succeed())
+		stateDir, err := os.MkdirTemp("", "triparclient-dryrun-state")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, stateDir)
+		stateFile := filepath.Join(stateDir, "state.json")
+
+		opts := &SyncOptions{StateFile: stateFile}
+		result, err := dry.Mirror(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Uploaded).To(Equal(1))
+
+		Expect(planned).To(Equal([]PlannedOperation{
+			{Op: "CreateDirectories", Path: "/remote"},
+			{Op: "CreateDirectory", Path: "/remote/dir"},
+			{Op: "PutObject", Path: "/remote/dir/a", Size: 3},
+			{Op: "Utime", Path: "/remote/dir/a"},
+		}))
+
+		for _, req := range fake.Requests() {
+			Expect(req).NotTo(HavePrefix("PUT "))
+			Expect(req).NotTo(HavePrefix("POST "))
+			Expect(req).NotTo(HavePrefix("DELETE "))
+		}
+		Expect(fake.Exists("/remote")).To(BeFalse())
+		Expect(stateFile).NotTo(BeAnExistingFile())
+	})
+
+	It("should still fail for missing entries", func() {
+		Expect(dry.DeleteTree(ctx, "/root/missing")).To(MatchError(ErrNotFound))
+		Expect(planned).To(BeEmpty())
+	})
+
+	It("should not affect the parent client", func() {
+		Expect(client.DeleteObject(ctx, "/root/object")).To(Succeed())
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+		Expect(planned).To(BeEmpty())
+	})
+})
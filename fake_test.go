@@ -0,0 +1,16 @@
+package triparclient_test
+
+import (
+	"github.com/koofr/go-triparclient/internal/triparfake"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type fakeTripar = triparfake.Fake
+
+var newFakeTripar = triparfake.New
+
+func newFakeTriparClient() (*TriparClient, *fakeTripar) {
+	fake := newFakeTripar()
+	return newTestClient(fake), fake
+}
@@ -0,0 +1,354 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/xerrors"
+)
+
+// DefaultParallelChunks is the number of chunks ObjectReader.WriteTo
+// downloads concurrently.
+const DefaultParallelChunks = 4
+
+var ErrHandleClosed = errors.New("object handle closed")
+
+// ObjectReader is a handle for reading an object, see OpenReader. It
+// implements io.WriterTo, so io.Copy to a local file downloads the object
+// with concurrent ranged requests.
+type ObjectReader struct {
+	tp      *TriparClient
+	ctx     context.Context
+	path    string
+	options []GetOption
+	info    Stat
+
+	rd     io.ReadCloser
+	read   int64
+	closed bool
+}
+
+// OpenReader stats the object at path and returns a handle for reading it.
+// The object is only requested once reading starts.
+func (tp *TriparClient) OpenReader(ctx context.Context, path string, options ...GetOption) (*ObjectReader, error) {
+	info, err := tp.Stat(ctx, path)
+	if err != nil {
+		return nil, xerrors.Errorf("open reader stat error: %w", err)
+	}
+	if info.IsDir() {
+		return nil, xerrors.Errorf("open reader %s: %w", path, ErrNotAFile)
+	}
+
+	return &ObjectReader{
+		tp:      tp,
+		ctx:     ctx,
+		path:    path,
+		options: options,
+		info:    info,
+	}, nil
+}
+
+// Stat returns the object's Stat from when it was opened.
+func (r *ObjectReader) Stat() Stat {
+	return r.info
+}
+
+func (r *ObjectReader) open() error {
+	if r.closed {
+		return ErrHandleClosed
+	}
+	if r.rd != nil {
+		return nil
+	}
+	rd, _, err := r.tp.GetObject(r.ctx, r.path, nil, r.options...)
+	if err != nil {
+		return err
+	}
+	r.rd = rd
+	return nil
+}
+
+func (r *ObjectReader) Read(p []byte) (n int, err error) {
+	if err := r.open(); err != nil {
+		return 0, err
+	}
+	n, err = r.rd.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// WriteTo writes the rest of the object to w. If nothing was read yet and w
+// is an io.WriterAt and io.Seeker, e.g. an *os.File, chunks are downloaded
+// concurrently and written at their offsets, otherwise the object is copied
+// with a buffer from the BufferPool.
+func (r *ObjectReader) WriteTo(w io.Writer) (n int64, err error) {
+	if r.closed {
+		return 0, ErrHandleClosed
+	}
+
+	if ws, ok := w.(interface {
+		io.WriterAt
+		io.Seeker
+	}); ok && r.rd == nil && r.read == 0 && r.info.Status.Size > r.tp.getChunkSize {
+		return r.writeChunksTo(ws)
+	}
+
+	if err := r.open(); err != nil {
+		return 0, err
+	}
+
+	buffer, err := r.tp.getBuffer(r.ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer r.tp.putBuffer(buffer)
+
+	// the reader is wrapped so that the copy does not call WriteTo again
+	n, err = io.CopyBuffer(w, struct{ io.Reader }{r.rd}, buffer)
+	r.read += n
+	return n, err
+}
+
+func (r *ObjectReader) writeChunksTo(w interface {
+	io.WriterAt
+	io.Seeker
+}) (n int64, err error) {
+	base, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	size := r.info.Status.Size
+	chunkSize := r.tp.getChunkSize
+	if opts := newGetOptions(r.options); opts.ChunkSize > 0 {
+		chunkSize = opts.ChunkSize
+	}
+
+	ctx, cancel := context.WithCancel(r.tp.beginTransfer(r.ctx))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	sem := make(chan struct{}, DefaultParallelChunks)
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		start := start
+		wg.Add(1)
+		goLabeled(ctx, "GetObject", r.path, func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := r.writeChunkTo(ctx, w, base, start, end); err != nil {
+				fail(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, xerrors.Errorf("write chunks to error: %w", firstErr)
+	}
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.Seek(base+size, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r.read = size
+
+	return size, nil
+}
+
+func (r *ObjectReader) writeChunkTo(ctx context.Context, w io.WriterAt, base int64, start int64, end int64) error {
+	return r.tp.writeRangeTo(ctx, "GetObject", r.path, w, base+start, start, end)
+}
+
+// writeRangeTo downloads bytes start-end of the object at path and writes
+// them to w at offset.
+func (tp *TriparClient) writeRangeTo(ctx context.Context, op string, path string, w io.WriterAt, offset int64, start int64, end int64) error {
+	chunkStart := time.Now()
+
+	rsp, err := tp.getObjectResponse(ctx, path, &ioutils.FileSpan{Start: start, End: end})
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if err := checkContentRange(rsp, start, end, rsp.ContentLength); err != nil {
+		return err
+	}
+
+	buffer, err := tp.getBuffer(ctx)
+	if err != nil {
+		return err
+	}
+	defer tp.putBuffer(buffer)
+
+	n, err := io.CopyBuffer(io.NewOffsetWriter(w, offset), rsp.Body, buffer)
+	if err != nil {
+		return err
+	}
+	if n != end-start+1 {
+		return xerrors.Errorf("chunk %d-%d is short: %w", start, end, io.ErrUnexpectedEOF)
+	}
+
+	tp.chunkDone(ctx, op, path, start, n, chunkStart)
+
+	return nil
+}
+
+func (r *ObjectReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.rd != nil {
+		return r.rd.Close()
+	}
+	return nil
+}
+
+// ObjectWriter is a handle for writing an object, see OpenWriter. It
+// implements io.ReaderFrom, so io.Copy from a local file uploads the file
+// directly.
+type ObjectWriter struct {
+	tp      *TriparClient
+	ctx     context.Context
+	path    string
+	options []PutOption
+
+	pw      *io.PipeWriter
+	done    chan error
+	written bool
+	direct  bool
+	err     error
+	closed  bool
+}
+
+// OpenWriter returns a handle for writing the object at path. The object is
+// written with PutObject once writing starts and is complete when the handle
+// is closed.
+func (tp *TriparClient) OpenWriter(ctx context.Context, path string, options ...PutOption) *ObjectWriter {
+	return &ObjectWriter{
+		tp:      tp,
+		ctx:     ctx,
+		path:    path,
+		options: options,
+	}
+}
+
+func (w *ObjectWriter) start() {
+	if w.pw != nil {
+		return
+	}
+
+	pr, pw := io.Pipe()
+	w.pw = pw
+	w.done = make(chan error, 1)
+	goLabeled(w.ctx, "PutObject", w.path, func() {
+		err := w.tp.PutObject(w.ctx, w.path, pr, w.options...)
+		pr.CloseWithError(err)
+		w.done <- err
+	})
+}
+
+// writable returns an error if the handle can't be written to anymore.
+func (w *ObjectWriter) writable() error {
+	if w.closed {
+		return ErrHandleClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.direct {
+		return xerrors.Errorf("write to %s: object was written by ReadFrom: %w", w.path, ErrHandleClosed)
+	}
+	return nil
+}
+
+func (w *ObjectWriter) Write(p []byte) (n int, err error) {
+	if err := w.writable(); err != nil {
+		return 0, err
+	}
+	w.written = true
+	w.start()
+	return w.pw.Write(p)
+}
+
+// ReadFrom writes the rest of r to the object. If nothing was written yet and
+// r can seek, e.g. an *os.File, it is uploaded directly without copying it
+// into pieces and the object is complete, so later writes fail. Otherwise r
+// is copied into the upload like with Write.
+func (w *ObjectWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if err := w.writable(); err != nil {
+		return 0, err
+	}
+
+	if !w.written {
+		if src, ok := newDirectSource(r); ok {
+			w.written = true
+			w.direct = true
+			if err := w.tp.PutObject(w.ctx, w.path, r, w.options...); err != nil {
+				w.err = err
+				return 0, err
+			}
+			return src.size, nil
+		}
+	}
+
+	w.written = true
+	w.start()
+
+	// PutObject copies the pipe into buffers from the BufferPool, so none is
+	// taken here
+	return io.Copy(w.pw, r)
+}
+
+// Close completes the object and returns the error of the upload. An object
+// which was not written to is created empty.
+func (w *ObjectWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	if !w.written {
+		w.written = true
+		w.start()
+	}
+	if w.pw != nil {
+		w.pw.Close()
+		w.err = <-w.done
+	}
+
+	return w.err
+}
@@ -0,0 +1,149 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("object handles", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var dir string
+
+	data := strings.Repeat("0123456789", 300)
+
+	countRequests := func(request string) int {
+		n := 0
+		for _, r := range fake.Requests() {
+			if r == request {
+				n++
+			}
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", data)
+		dir = GinkgoT().TempDir()
+	})
+
+	Describe("ObjectReader", func() {
+		It("should download chunks concurrently into files", func() {
+			rd, err := client.OpenReader(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			defer rd.Close()
+			Expect(rd.Stat().Status.Size).To(Equal(int64(len(data))))
+
+			file, err := os.Create(filepath.Join(dir, "file"))
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+			_, err = file.WriteString("head")
+			Expect(err).NotTo(HaveOccurred())
+
+			n, err := io.Copy(file, rd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(len(data))))
+			Expect(countRequests("GET /root/object")).To(Equal(3))
+
+			_, err = file.WriteString("tail")
+			Expect(err).NotTo(HaveOccurred())
+			written, err := os.ReadFile(file.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(written)).To(Equal("head" + data + "tail"))
+		})
+
+		It("should stream into other writers", func() {
+			rd, err := client.OpenReader(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			defer rd.Close()
+
+			var buf bytes.Buffer
+			_, err = io.Copy(&buf, rd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(Equal(data))
+			Expect(countRequests("GET /root/object")).To(Equal(1))
+		})
+
+		It("should continue after reads", func() {
+			rd, err := client.OpenReader(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			defer rd.Close()
+
+			head := make([]byte, 10)
+			_, err = io.ReadFull(rd, head)
+			Expect(err).NotTo(HaveOccurred())
+
+			file, err := os.Create(filepath.Join(dir, "file"))
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+			_, err = io.Copy(file, rd)
+			Expect(err).NotTo(HaveOccurred())
+
+			written, err := os.ReadFile(file.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(head) + string(written)).To(Equal(data))
+		})
+
+		It("should fail for missing objects", func() {
+			_, err := client.OpenReader(ctx, "/root/missing")
+			Expect(err).To(MatchError(ErrNotFound))
+		})
+	})
+
+	Describe("ObjectWriter", func() {
+		It("should upload files directly", func() {
+			path := filepath.Join(dir, "file")
+			Expect(os.WriteFile(path, []byte(data), 0o644)).To(Succeed())
+			file, err := os.Open(path)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			w := client.OpenWriter(ctx, "/root/copy")
+			n, err := io.Copy(w, file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(len(data))))
+			Expect(countRequests("PUT /root/copy")).To(Equal(1))
+
+			_, err = w.Write([]byte("more"))
+			Expect(err).To(MatchError(ErrHandleClosed))
+			Expect(w.Close()).To(Succeed())
+
+			written, _ := fake.File("/root/copy")
+			Expect(string(written)).To(Equal(data))
+		})
+
+		It("should upload writes", func() {
+			w := client.OpenWriter(ctx, "/root/copy")
+			_, err := w.Write([]byte("head"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.Copy(w, io.MultiReader(strings.NewReader(data)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			written, _ := fake.File("/root/copy")
+			Expect(string(written)).To(Equal("head" + data))
+		})
+
+		It("should create empty objects", func() {
+			w := client.OpenWriter(ctx, "/root/empty")
+			Expect(w.Close()).To(Succeed())
+
+			written, ok := fake.File("/root/empty")
+			Expect(ok).To(BeTrue())
+			Expect(written).To(BeEmpty())
+		})
+	})
+})
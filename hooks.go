@@ -0,0 +1,196 @@
+package triparclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+// Hooks are called synchronously by the client, so they must be cheap and
+// must not block. Nil hooks are skipped. See TriparClient.Hooks.
+type Hooks struct {
+	// OnRequest is called after every attempt of a request.
+	OnRequest func(ctx context.Context, event RequestEvent)
+
+	// OnRetry is called before a failed request is retried, and when a
+	// retry is suppressed because the RetryPolicy's Budget is exhausted.
+	OnRetry func(ctx context.Context, event RetryEvent)
+
+	// OnChunkDone is called after a chunk of GetObject or PutObject was
+	// transferred.
+	OnChunkDone func(ctx context.Context, event ChunkEvent)
+
+	// OnOperationComplete is called after every operation, like
+	// ObserveContext.
+	OnOperationComplete func(ctx context.Context, event OperationEvent)
+
+	// Sampler limits the events passed to the hooks, nil passes all of them.
+	// ObserveLatency and ObserveContext are not sampled.
+	Sampler *Sampler
+}
+
+type RequestEvent struct {
+	Method string
+	Path   string
+	// Cmd is the appliance command, e.g. "mkdir", or empty for data
+	// requests.
+	Cmd string
+	// Attempt is 1 for the first attempt and is increased by retries.
+	Attempt int
+	// Status is the response status, or 0 if no response was received.
+	Status   int
+	BytesOut int64
+	Duration time.Duration
+	Err      error
+}
+
+type RetryEvent struct {
+	Method string
+	Path   string
+	Cmd    string
+	// Attempt is the attempt which failed with Err.
+	Attempt int
+	Err     error
+	// Backoff is the delay before the next attempt.
+	Backoff time.Duration
+	// Suppressed is set if the request is not retried because the
+	// RetryPolicy's Budget is exhausted.
+	Suppressed bool
+}
+
+type ChunkEvent struct {
+	// Op is "GetObject" or "PutObject".
+	Op       string
+	Path     string
+	Offset   int64
+	Bytes    int64
+	Duration time.Duration
+}
+
+type OperationEvent struct {
+	Op       string
+	Path     string
+	Duration time.Duration
+	Err      error
+}
+
+func (tp *TriparClient) hookRequest(req *httpclient.RequestData, attempt int, response *http.Response, start time.Time, err error) {
+	if tp.Hooks.OnRequest == nil || !tp.Hooks.Sampler.sample("request", "", err) {
+		return
+	}
+
+	status := 0
+	if response != nil {
+		status = response.StatusCode
+	} else if ise, ok := asInvalidStatusError(err); ok {
+		status = ise.Got
+	}
+
+	tp.Hooks.OnRequest(hookContext(req.Context), RequestEvent{
+		Method:   req.Method,
+		Path:     tp.unroot(req.Path),
+		Cmd:      req.Params.Get("cmd"),
+		Attempt:  attempt,
+		Status:   status,
+		BytesOut: req.ReqContentLength,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
+
+func (tp *TriparClient) hookRetry(ctx context.Context, event RetryEvent) {
+	if tp.Hooks.OnRetry != nil && tp.Hooks.Sampler.sample("retry", "", event.Err) {
+		tp.Hooks.OnRetry(hookContext(ctx), event)
+	}
+}
+
+// chunkDone records a transferred chunk for the deadline estimate and the
+// OnChunkDone hook.
+func (tp *TriparClient) chunkDone(ctx context.Context, op string, path string, offset int64, n int64, start time.Time) {
+	d := time.Since(start)
+	tp.throughput.observe(n, d)
+
+	if tp.Hooks.OnChunkDone != nil && tp.Hooks.Sampler.sample("chunk", op, nil) {
+		tp.Hooks.OnChunkDone(hookContext(ctx), ChunkEvent{
+			Op:       op,
+			Path:     path,
+			Offset:   offset,
+			Bytes:    n,
+			Duration: d,
+		})
+	}
+}
+
+func hookContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+type SamplerOptions struct {
+	// Rate is the fraction of events passed to the hooks, from 0 to 1.
+	Rate float64
+
+	// Rates overrides Rate for operations and chunks by operation name, e.g.
+	// "GetObject".
+	Rates map[string]float64
+
+	// AlwaysSampleErrors passes all events with an error to the hooks
+	// regardless of the rate.
+	AlwaysSampleErrors bool
+}
+
+// Sampler limits the events passed to Hooks, e.g. to trace only a fraction of
+// the chunks of large transfers, see Hooks.Sampler. Events are sampled
+// evenly, e.g. with a rate of 0.1 every tenth event of an operation is
+// passed.
+type Sampler struct {
+	opts SamplerOptions
+
+	mx     sync.Mutex
+	counts map[string]uint64
+}
+
+func NewSampler(opts SamplerOptions) *Sampler {
+	return &Sampler{
+		opts:   opts,
+		counts: map[string]uint64{},
+	}
+}
+
+// sample reports whether the next event of the kind, e.g. "chunk", of op is
+// passed to the hooks. op is empty for requests and retries.
+func (s *Sampler) sample(kind string, op string, err error) bool {
+	if s == nil {
+		return true
+	}
+	if err != nil && s.opts.AlwaysSampleErrors {
+		return true
+	}
+
+	rate := s.opts.Rate
+	if opRate, ok := s.opts.Rates[op]; ok && op != "" {
+		rate = opRate
+	}
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	key := kind + " " + op
+	n := s.counts[key]
+	s.counts[key] = n + 1
+
+	// sampled whenever the expected number of samples reaches the next
+	// integer
+	return uint64(float64(n+1)*rate) > uint64(float64(n)*rate)
+}
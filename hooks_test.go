@@ -0,0 +1,156 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Hooks", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failures int32
+
+	var mx sync.Mutex
+	var requests []RequestEvent
+	var retries []RetryEvent
+	var chunks []ChunkEvent
+	var operations []OperationEvent
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+		atomic.StoreInt32(&failures, 0)
+
+		requests, retries, chunks, operations = nil, nil, nil, nil
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				return testResponse(http.StatusServiceUnavailable, "text/plain", "unavailable"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.UploadChunkSize = 1024
+		client.Hooks = Hooks{
+			OnRequest: func(ctx context.Context, event RequestEvent) {
+				mx.Lock()
+				defer mx.Unlock()
+				event.Duration = 0
+				requests = append(requests, event)
+			},
+			OnRetry: func(ctx context.Context, event RetryEvent) {
+				mx.Lock()
+				defer mx.Unlock()
+				retries = append(retries, event)
+			},
+			OnChunkDone: func(ctx context.Context, event ChunkEvent) {
+				mx.Lock()
+				defer mx.Unlock()
+				event.Duration = 0
+				chunks = append(chunks, event)
+			},
+			OnOperationComplete: func(ctx context.Context, event OperationEvent) {
+				mx.Lock()
+				defer mx.Unlock()
+				event.Duration = 0
+				operations = append(operations, event)
+			},
+		}
+	})
+
+	It("should report requests and operations", func() {
+		Expect(client.CreateDirectory(ctx, "/root/dir")).To(Succeed())
+
+		Expect(requests).To(Equal([]RequestEvent{
+			{Method: "PUT", Path: "/root/dir", Cmd: "mkdir", Attempt: 1, Status: http.StatusOK},
+		}))
+		Expect(operations).To(Equal([]OperationEvent{
+			{Op: "CreateDirectory", Path: "/root/dir"},
+		}))
+	})
+
+	It("should report retries", func() {
+		client.RetryPolicy = &RetryPolicy{MaxAttempts: 2}
+		atomic.StoreInt32(&failures, 1)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Attempt).To(Equal(1))
+		Expect(requests[0].Status).To(Equal(http.StatusServiceUnavailable))
+		Expect(requests[0].Err).To(HaveOccurred())
+		Expect(requests[1].Attempt).To(Equal(2))
+		Expect(requests[1].Status).To(Equal(http.StatusOK))
+
+		Expect(retries).To(HaveLen(1))
+		Expect(retries[0].Method).To(Equal("GET"))
+		Expect(retries[0].Path).To(Equal("/root"))
+		Expect(retries[0].Attempt).To(Equal(1))
+	})
+
+	It("should report chunks", func() {
+		data := strings.Repeat("0123456789", 250)
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 0, End: 2499})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+
+		Expect(chunks).To(Equal([]ChunkEvent{
+			{Op: "PutObject", Path: "/root/object", Offset: 0, Bytes: 1024},
+			{Op: "PutObject", Path: "/root/object", Offset: 1024, Bytes: 1024},
+			{Op: "PutObject", Path: "/root/object", Offset: 2048, Bytes: 452},
+			{Op: "GetObject", Path: "/root/object", Offset: 0, Bytes: 1024},
+			{Op: "GetObject", Path: "/root/object", Offset: 1024, Bytes: 1024},
+			{Op: "GetObject", Path: "/root/object", Offset: 2048, Bytes: 452},
+		}))
+	})
+
+	Describe("Sampler", func() {
+		It("should sample chunks evenly", func() {
+			client.Hooks.Sampler = NewSampler(SamplerOptions{
+				Rate:  1,
+				Rates: map[string]float64{"PutObject": 0.5},
+			})
+
+			data := strings.Repeat("0123456789", 400)
+			Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+			Expect(chunks).To(Equal([]ChunkEvent{
+				{Op: "PutObject", Path: "/root/object", Offset: 1024, Bytes: 1024},
+				{Op: "PutObject", Path: "/root/object", Offset: 3072, Bytes: 928},
+			}))
+			// the rate of PutObject also applies to the operation
+			Expect(operations).To(BeEmpty())
+			Expect(requests).NotTo(BeEmpty())
+		})
+
+		It("should always sample errors if enabled", func() {
+			client.Hooks.Sampler = NewSampler(SamplerOptions{
+				AlwaysSampleErrors: true,
+			})
+
+			Expect(client.CreateDirectory(ctx, "/root/dir")).To(Succeed())
+			_, err := client.Stat(ctx, "/root/missing")
+			Expect(err).To(MatchError(ErrNotFound))
+
+			Expect(operations).To(HaveLen(1))
+			Expect(operations[0].Op).To(Equal("Stat"))
+			Expect(operations[0].Err).To(MatchError(ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,203 @@
+package triparclient
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+type HostLimitOptions struct {
+	// MaxConnsPerHost limits the open connections to each address the
+	// endpoint's host resolves to, so a slow node behind DNS load balancing
+	// can't hold all connections. New connections are dialed to the address
+	// with the fewest connections and wait if all addresses are at the limit.
+	// 0 means unlimited.
+	MaxConnsPerHost int
+
+	// MaxInFlightPerHost limits the concurrent requests to each endpoint
+	// host. A request holds its slot until its response body is closed, so
+	// a GetObject reader holds one until it is closed. 0 means unlimited.
+	MaxInFlightPerHost int
+}
+
+// HostLimiter limits connections and requests per host across all clients
+// sharing it, see SetHostLimiter.
+type HostLimiter struct {
+	opts     HostLimitOptions
+	conns    *hostSlots
+	inFlight *hostSlots
+}
+
+func NewHostLimiter(opts HostLimitOptions) *HostLimiter {
+	return &HostLimiter{
+		opts:     opts,
+		conns:    newHostSlots(opts.MaxConnsPerHost),
+		inFlight: newHostSlots(opts.MaxInFlightPerHost),
+	}
+}
+
+// Conns returns the number of open connections to addr, an IP address.
+func (l *HostLimiter) Conns(addr string) int {
+	return l.conns.count(addr)
+}
+
+// InFlight returns the number of requests in flight to host.
+func (l *HostLimiter) InFlight(host string) int {
+	return l.inFlight.count(host)
+}
+
+// SetHostLimiter makes the client's connections and requests count against
+// limiter, nil removes the limits. Connection limits have no effect if the
+// client's transport was replaced.
+func (tp *TriparClient) SetHostLimiter(limiter *HostLimiter) {
+	tp.hostLimiter = limiter
+	tp.dialer.hostLimiter.Store(limiter)
+}
+
+// hostSlots counts slots per host, up to max per host if max is positive.
+type hostSlots struct {
+	max int
+
+	mx       sync.Mutex
+	counts   map[string]int
+	released chan struct{}
+}
+
+func newHostSlots(max int) *hostSlots {
+	return &hostSlots{
+		max:      max,
+		counts:   map[string]int{},
+		released: make(chan struct{}),
+	}
+}
+
+func (s *hostSlots) count(host string) int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.counts[host]
+}
+
+// acquire takes a slot of the host among hosts with the fewest taken slots
+// and returns it, waiting until one is free.
+func (s *hostSlots) acquire(ctx context.Context, hosts []string) (string, error) {
+	for {
+		s.mx.Lock()
+		best := ""
+		for _, host := range hosts {
+			n := s.counts[host]
+			if s.max > 0 && n >= s.max {
+				continue
+			}
+			if best == "" || n < s.counts[best] {
+				best = host
+			}
+		}
+		if best != "" {
+			s.counts[best]++
+			s.mx.Unlock()
+			return best, nil
+		}
+		released := s.released
+		s.mx.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func (s *hostSlots) release(host string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.counts[host]--; s.counts[host] <= 0 {
+		delete(s.counts, host)
+	}
+	close(s.released)
+	s.released = make(chan struct{})
+}
+
+// withHeldHostSlot returns a context whose requests don't take a request slot,
+// for requests made while the operation already holds one and waits for them.
+func withHeldHostSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, heldHostSlotContextKey, true)
+}
+
+// acquireRequest waits for a request slot of the client's endpoint host. The
+// returned function releases it.
+func (tp *TriparClient) acquireRequest(ctx context.Context) (release func(), err error) {
+	limiter := tp.hostLimiter
+	if limiter == nil {
+		return func() {}, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if held, _ := ctx.Value(heldHostSlotContextKey).(bool); held {
+		return func() {}, nil
+	}
+
+	host := tp.HTTPClient.BaseURL.Host
+	if _, err := limiter.inFlight.acquire(ctx, []string{host}); err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			limiter.inFlight.release(host)
+		})
+	}, nil
+}
+
+// dialLimited dials the address of addrs with the fewest connections.
+func (d *dialer) dialLimited(ctx context.Context, limiter *HostLimiter, network string, port string, addrs []string) (net.Conn, error) {
+	var firstErr error
+	for len(addrs) > 0 {
+		addr, err := limiter.conns.acquire(ctx, addrs)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := d.dialSerial(ctx, network, port, []string{addr})
+		if err == nil {
+			return &limitedConn{
+				Conn:    conn,
+				release: func() { limiter.conns.release(addr) },
+			}, nil
+		}
+		limiter.conns.release(addr)
+
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		// the failed address is not retried
+		remaining := make([]string, 0, len(addrs)-1)
+		for _, other := range addrs {
+			if other != addr {
+				remaining = append(remaining, other)
+			}
+		}
+		addrs = remaining
+	}
+
+	return nil, firstErr
+}
+
+type limitedConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,131 @@
+package triparclient_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("HostLimiter", func() {
+	It("should limit in flight requests per host", func() {
+		fake := newFakeTripar()
+		fake.Mkdir("/root")
+
+		var inFlight int32
+		var maxInFlight int32
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return fake.RoundTrip(r)
+		}))
+
+		limiter := NewHostLimiter(HostLimitOptions{MaxInFlightPerHost: 2})
+		client.SetHostLimiter(limiter)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := client.Stat(context.Background(), "/root")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		Expect(atomic.LoadInt32(&maxInFlight)).To(Equal(int32(2)))
+		Expect(limiter.InFlight("tripar.example.com")).To(Equal(0))
+	})
+
+	It("should fail waiting for a slot when the context is done", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+		client.SetHostLimiter(NewHostLimiter(HostLimitOptions{MaxInFlightPerHost: 1}))
+
+		// an unread response holds the slot
+		rd, _, err := client.GetObject(context.Background(), "/root/object", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = client.Stat(ctx, "/root")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		Expect(rd.Close()).To(Succeed())
+		_, err = client.Stat(context.Background(), "/root")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should spread connections over the host's addresses", func() {
+		listener, err := net.Listen("tcp", ":0")
+		if err != nil {
+			Skip("can't listen on all addresses: " + err.Error())
+		}
+
+		release := make(chan struct{})
+		var requests int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		server.Listener.Close()
+		server.Listener = listener
+		server.Start()
+		defer server.Close()
+
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		client, err := NewTriparClient("http://tripar.invalid:"+u.Port(), "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.EnableDNSCache(DNSCacheOptions{
+			TTL: time.Minute,
+			LookupHost: func(ctx context.Context, host string) ([]string, error) {
+				return []string{"127.0.0.1", "127.0.0.2"}, nil
+			},
+		})).To(Succeed())
+
+		limiter := NewHostLimiter(HostLimitOptions{MaxConnsPerHost: 1})
+		client.SetHostLimiter(limiter)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := client.Stat(context.Background(), "/object")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+
+		Eventually(func() int32 { return atomic.LoadInt32(&requests) }).Should(Equal(int32(2)))
+		Expect(limiter.Conns("127.0.0.1")).To(Equal(1))
+		Expect(limiter.Conns("127.0.0.2")).To(Equal(1))
+		Consistently(func() int32 { return atomic.LoadInt32(&requests) }, 20*time.Millisecond).Should(Equal(int32(2)))
+
+		close(release)
+		wg.Wait()
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+})
@@ -0,0 +1,74 @@
+package triparclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// IdentityResolver maps numeric uids and gids to names. The Object Access API
+// does not expose the appliance's identity mapping, so resolvers are usually
+// backed by the same directory service the appliance uses.
+type IdentityResolver interface {
+	UserName(ctx context.Context, uid int32) (string, error)
+	GroupName(ctx context.Context, gid int32) (string, error)
+}
+
+// resolveIdentity fills in user and group names. Names are only used for
+// display so resolution failures leave them empty instead of failing Stat.
+func (tp *TriparClient) resolveIdentity(ctx context.Context, info *Stat) {
+	if tp.IdentityResolver == nil {
+		return
+	}
+
+	if name, err := tp.IdentityResolver.UserName(ctx, info.Status.Uid); err == nil {
+		info.UserName = name
+	}
+	if name, err := tp.IdentityResolver.GroupName(ctx, info.Status.Gid); err == nil {
+		info.GroupName = name
+	}
+}
+
+type Identity struct {
+	// User is the authenticated user.
+	User string
+
+	// EffectiveUser is the user operations are performed as, which differs
+	// from User if the context was created with WithRunAsUser.
+	EffectiveUser string
+}
+
+// WhoAmI performs a minimal authenticated request to validate the
+// credentials and reports the identity requests are performed with.
+func (tp *TriparClient) WhoAmI(ctx context.Context) (identity Identity, err error) {
+	defer tp.observe(ctx, "WhoAmI", "", time.Now(), &err)
+
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "GET",
+		Path:           tp.path("/"),
+		Params:         tp.cmd("stat"),
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		return Identity{}, xerrors.Errorf("whoami request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		return Identity{}, xerrors.Errorf("whoami response error: %w", err)
+	}
+
+	identity.User = tp.user
+	if _, user, _ := tp.auth.get(); user != "" {
+		identity.User = user
+	}
+	identity.EffectiveUser = identity.User
+	if user := RunAsUser(ctx); user != "" {
+		identity.EffectiveUser = user
+	}
+
+	return identity, nil
+}
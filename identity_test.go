@@ -0,0 +1,89 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type mapIdentityResolver struct {
+	users  map[int32]string
+	groups map[int32]string
+}
+
+func (r *mapIdentityResolver) UserName(ctx context.Context, uid int32) (string, error) {
+	if name, ok := r.users[uid]; ok {
+		return name, nil
+	}
+	return "", errors.New("unknown uid")
+}
+
+func (r *mapIdentityResolver) GroupName(ctx context.Context, gid int32) (string, error) {
+	if name, ok := r.groups[gid]; ok {
+		return name, nil
+	}
+	return "", errors.New("unknown gid")
+}
+
+var _ = Describe("IdentityResolver", func() {
+	It("should resolve user and group names", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(http.StatusOK, "application/json", `{
+				"path": "/object",
+				"status": {"mode": 33188, "size": 5, "uid": 1000, "gid": 100}
+			}`), nil
+		}))
+		client.IdentityResolver = &mapIdentityResolver{
+			users:  map[int32]string{1000: "alice"},
+			groups: map[int32]string{100: "users"},
+		}
+
+		info, err := client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.UserName).To(Equal("alice"))
+		Expect(info.GroupName).To(Equal("users"))
+	})
+
+	It("should leave names empty if they cannot be resolved", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testStatResponse(5), nil
+		}))
+		client.IdentityResolver = &mapIdentityResolver{}
+
+		info, err := client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.UserName).To(BeEmpty())
+		Expect(info.GroupName).To(BeEmpty())
+	})
+})
+
+var _ = Describe("WhoAmI", func() {
+	It("should report the identity", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testStatResponse(4096), nil
+		}))
+
+		identity, err := client.WhoAmI(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity).To(Equal(Identity{User: "user", EffectiveUser: "user"}))
+
+		client.RunAsHeader = "X-Run-As-User"
+		identity, err = client.WithCredentials("tenant", "secret").WhoAmI(WithRunAsUser(context.Background(), "alice"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity).To(Equal(Identity{User: "tenant", EffectiveUser: "alice"}))
+	})
+
+	It("should fail for invalid credentials", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(http.StatusUnauthorized, "text/plain", "Unauthorized"), nil
+		}))
+
+		_, err := client.WhoAmI(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,556 @@
+// Package triparfake is an in-memory implementation of the subset of the
+// Object Access API used by the client, for tests which don't need a real
+// appliance.
+package triparfake
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fake is an http.RoundTripper serving a share from memory.
+type Fake struct {
+	mx       sync.Mutex
+	share    string
+	files    map[string][]byte
+	dirs     map[string]bool
+	mtimes   map[string]json.Number
+	modes    map[string]int32
+	owners   map[string][2]int32
+	xattrs   map[string]map[string]string
+	clock    float64
+	requests []string
+
+	unsupported map[string]bool
+	quota       int64
+}
+
+func New() *Fake {
+	return &Fake{
+		share:  "/share",
+		files:  map[string][]byte{},
+		dirs:   map[string]bool{"/": true},
+		mtimes: map[string]json.Number{"/": "1"},
+		modes:  map[string]int32{},
+		owners: map[string][2]int32{},
+		xattrs: map[string]map[string]string{},
+		clock:  1,
+
+		unsupported: map[string]bool{},
+	}
+}
+
+func (f *Fake) Requests() []string {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return append([]string{}, f.requests...)
+}
+
+func (f *Fake) File(p string) ([]byte, bool) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	data, ok := f.files[p]
+	return data, ok
+}
+
+func (f *Fake) PutFile(p string, data string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.files[p] = []byte(data)
+	f.touch(p)
+}
+
+func (f *Fake) Mkdir(p string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	for dir := p; dir != "/"; dir = path.Dir(dir) {
+		f.dirs[dir] = true
+		f.touch(dir)
+	}
+}
+
+// Unsupport makes the fake fail cmd with "Operation not supported", like
+// firmware or file systems without the command.
+func (f *Fake) Unsupport(cmd string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.unsupported[cmd] = true
+}
+
+// SetQuota makes writes fail with "No space left on device" once the files
+// would hold more than quota bytes. 0 means unlimited.
+func (f *Fake) SetQuota(quota int64) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.quota = quota
+}
+
+// fits reports whether the file p can grow or shrink to size bytes within
+// the quota. It must be called with mx held.
+func (f *Fake) fits(p string, size int64) bool {
+	if f.quota <= 0 {
+		return true
+	}
+	used := size - int64(len(f.files[p]))
+	for _, data := range f.files {
+		used += int64(len(data))
+	}
+	return used <= f.quota
+}
+
+func (f *Fake) Exists(p string) bool {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	_, isFile := f.files[p]
+	return isFile || f.dirs[p]
+}
+
+func (f *Fake) touch(p string) {
+	f.clock++
+	f.mtimes[p] = json.Number(strconv.FormatFloat(f.clock, 'f', -1, 64))
+}
+
+func (f *Fake) error(code int, msg string) *http.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error_code":    code,
+		"long_message":  fmt.Sprintf("%s (error code %d)", msg, code),
+		"short_message": msg,
+	})
+	return response(http.StatusOK, "application/json", string(body))
+}
+
+func (f *Fake) json(v interface{}) *http.Response {
+	body, _ := json.Marshal(v)
+	return response(http.StatusOK, "application/json", string(body))
+}
+
+func (f *Fake) ok() *http.Response {
+	return response(http.StatusOK, "application/json", "")
+}
+
+func (f *Fake) children(dir string) []string {
+	names := []string{}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	add := func(p string) {
+		if p != dir && strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			names = append(names, p[len(prefix):])
+		}
+	}
+	for p := range f.files {
+		add(p)
+	}
+	for p := range f.dirs {
+		add(p)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *Fake) stat(p string) map[string]interface{} {
+	status := map[string]interface{}{
+		"mode":  33188,
+		"size":  len(f.files[p]),
+		"mtime": f.mtimes[p],
+		"nlink": 1,
+	}
+	if f.dirs[p] {
+		status["mode"] = 16877
+		status["size"] = 4096
+	}
+	if owner, ok := f.owners[p]; ok {
+		status["uid"] = owner[0]
+		status["gid"] = owner[1]
+	}
+	if perm, ok := f.modes[p]; ok {
+		status["mode"] = int32(status["mode"].(int))&^07777 | perm
+	}
+	return map[string]interface{}{
+		"path":   p,
+		"status": status,
+	}
+}
+
+func (f *Fake) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	rawPath := r.URL.Opaque
+	if rawPath == "" {
+		rawPath = r.URL.Path
+	}
+	p, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	p = strings.TrimPrefix(p, f.share)
+	if p == "" {
+		p = "/"
+	}
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+
+	params := r.URL.Query()
+	cmd := params.Get("cmd")
+
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+p+" "+cmd))
+
+	_, isFile := f.files[p]
+	isDir := f.dirs[p]
+	exists := isFile || isDir
+	parentExists := f.dirs[path.Dir(p)]
+
+	if f.unsupported[cmd] {
+		return f.error(95, "Operation not supported"), nil
+	}
+
+	switch {
+	case r.Method == "GET" && cmd == "stat":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		return f.json(f.stat(p)), nil
+
+	case r.Method == "GET" && cmd == "ls":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if !isDir {
+			return f.error(20, "Not a directory"), nil
+		}
+		entries := []map[string]interface{}{}
+		for _, name := range f.children(p) {
+			entries = append(entries, map[string]interface{}{"name": name})
+		}
+		return f.json(map[string]interface{}{"entries": entries}), nil
+
+	case r.Method == "GET" && cmd == "":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if isDir {
+			return f.error(21, "Is a directory"), nil
+		}
+		data := f.files[p]
+		status := http.StatusOK
+		header := http.Header{}
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start, end int64
+			if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+				return nil, err
+			}
+			if end >= int64(len(data)) {
+				end = int64(len(data)) - 1
+			}
+			if start > end {
+				return f.error(10004, "Bad range"), nil
+			}
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Length", strconv.Itoa(len(data)))
+		rsp := response(status, "application/octet-stream", string(data))
+		for k, v := range header {
+			rsp.Header[k] = v
+		}
+		rsp.ContentLength = int64(len(data))
+		return rsp, nil
+
+	case r.Method == "PUT" && cmd == "":
+		if isDir {
+			return f.error(21, "Is a directory"), nil
+		}
+		if !parentExists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !f.fits(p, int64(len(data))) {
+			return f.error(28, "No space left on device"), nil
+		}
+		f.files[p] = data
+		f.touch(p)
+		rsp := f.ok()
+		rsp.StatusCode = http.StatusCreated
+		return rsp, nil
+
+	case r.Method == "POST" && cmd == "":
+		if !isFile {
+			return f.error(2, "No such file or directory"), nil
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) != end-start+1 {
+			return f.error(10004, "Bad range"), nil
+		}
+		if end+1 > int64(len(f.files[p])) && !f.fits(p, end+1) {
+			return f.error(28, "No space left on device"), nil
+		}
+		file := f.files[p]
+		for int64(len(file)) < start {
+			file = append(file, 0)
+		}
+		if int64(len(file)) < end+1 {
+			file = append(file, make([]byte, end+1-int64(len(file)))...)
+		}
+		copy(file[start:], data)
+		f.files[p] = file
+		f.touch(p)
+		return f.ok(), nil
+
+	case r.Method == "DELETE" && cmd == "":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if isDir {
+			return f.error(21, "Is a directory"), nil
+		}
+		delete(f.files, p)
+		delete(f.mtimes, p)
+		delete(f.modes, p)
+		delete(f.owners, p)
+		delete(f.xattrs, p)
+		return f.ok(), nil
+
+	case r.Method == "DELETE" && cmd == "rmdir":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if !isDir {
+			return f.error(20, "Not a directory"), nil
+		}
+		if len(f.children(p)) > 0 {
+			return f.error(39, "Directory not empty"), nil
+		}
+		delete(f.dirs, p)
+		delete(f.mtimes, p)
+		delete(f.modes, p)
+		delete(f.owners, p)
+		delete(f.xattrs, p)
+		return f.ok(), nil
+
+	case r.Method == "PUT" && cmd == "mkdir":
+		if params.Get("parents") == "true" {
+			for dir := p; dir != "/"; dir = path.Dir(dir) {
+				if _, ok := f.files[dir]; ok {
+					return f.error(20, "Not a directory"), nil
+				}
+				if !f.dirs[dir] {
+					f.dirs[dir] = true
+					f.touch(dir)
+				}
+			}
+			return f.ok(), nil
+		}
+		if exists {
+			return f.error(17, "File exists"), nil
+		}
+		if !parentExists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		f.dirs[p] = true
+		f.touch(p)
+		return f.ok(), nil
+
+	case r.Method == "POST" && cmd == "mv", r.Method == "PUT" && cmd == "cp":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		dst := params.Get("destination")
+		if !f.dirs[path.Dir(dst)] {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if f.dirs[dst] {
+			return f.error(21, "Is a directory"), nil
+		}
+		if _, dstExists := f.files[dst]; dstExists && cmd == "cp" && params.Get("overwrite") != "true" {
+			return f.error(17, "File exists"), nil
+		}
+		if isDir {
+			if cmd == "cp" {
+				return f.error(21, "Is a directory"), nil
+			}
+			prefix := p + "/"
+			for fp, data := range f.files {
+				if strings.HasPrefix(fp, prefix) {
+					delete(f.files, fp)
+					f.files[dst+"/"+fp[len(prefix):]] = data
+				}
+			}
+			for dp := range f.dirs {
+				if strings.HasPrefix(dp, prefix) {
+					delete(f.dirs, dp)
+					f.dirs[dst+"/"+dp[len(prefix):]] = true
+				}
+			}
+			delete(f.dirs, p)
+			f.dirs[dst] = true
+			f.touch(dst)
+			return f.ok(), nil
+		}
+		f.files[dst] = append([]byte{}, f.files[p]...)
+		f.touch(dst)
+		if cmd == "mv" {
+			delete(f.files, p)
+		}
+		return f.ok(), nil
+
+	case r.Method == "POST" && cmd == "fallocate":
+		if !isFile {
+			return f.error(2, "No such file or directory"), nil
+		}
+		offset, _ := strconv.ParseInt(params.Get("offset"), 10, 64)
+		length, _ := strconv.ParseInt(params.Get("length"), 10, 64)
+		file := f.files[p]
+		switch params.Get("mode") {
+		case "punch_hole":
+			for i := offset; i < offset+length && i < int64(len(file)); i++ {
+				file[i] = 0
+			}
+		case "":
+			if int64(len(file)) < offset+length && !f.fits(p, offset+length) {
+				return f.error(28, "No space left on device"), nil
+			}
+			if int64(len(file)) < offset+length {
+				file = append(file, make([]byte, offset+length-int64(len(file)))...)
+			}
+		default:
+			return f.error(22, "Invalid argument"), nil
+		}
+		f.files[p] = file
+		f.touch(p)
+		return f.ok(), nil
+
+	case r.Method == "POST" && cmd == "chmod":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		mode, err := strconv.ParseInt(params.Get("mode"), 8, 32)
+		if err != nil {
+			return f.error(22, "Invalid argument"), nil
+		}
+		f.modes[p] = int32(mode)
+		return f.ok(), nil
+
+	case r.Method == "POST" && cmd == "chown":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		uid, err := strconv.ParseInt(params.Get("uid"), 10, 32)
+		if err != nil {
+			return f.error(22, "Invalid argument"), nil
+		}
+		gid, err := strconv.ParseInt(params.Get("gid"), 10, 32)
+		if err != nil {
+			return f.error(22, "Invalid argument"), nil
+		}
+		f.owners[p] = [2]int32{int32(uid), int32(gid)}
+		return f.ok(), nil
+
+	case r.Method == "GET" && cmd == "xattr":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		name := params.Get("name")
+		value, ok := f.xattrs[p][name]
+		if !ok {
+			return f.error(61, "No data available"), nil
+		}
+		return f.json(map[string]interface{}{"name": name, "value": value}), nil
+
+	case r.Method == "POST" && cmd == "xattr":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if f.xattrs[p] == nil {
+			f.xattrs[p] = map[string]string{}
+		}
+		f.xattrs[p][params.Get("name")] = string(value)
+		return f.ok(), nil
+
+	case r.Method == "POST" && cmd == "utime":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		mtime := params.Get("mtime")
+		if _, err := strconv.ParseFloat(mtime, 64); err != nil {
+			return f.error(22, "Invalid argument"), nil
+		}
+		// stored as sent, so times keep nanosecond precision
+		f.mtimes[p] = json.Number(mtime)
+		return f.ok(), nil
+
+	case r.Method == "GET" && cmd == "cksum":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		if isDir {
+			return f.error(21, "Is a directory"), nil
+		}
+		algorithm := params.Get("algorithm")
+		var sum []byte
+		switch algorithm {
+		case "md5":
+			s := md5.Sum(f.files[p])
+			sum = s[:]
+		case "sha256":
+			s := sha256.Sum256(f.files[p])
+			sum = s[:]
+		default:
+			return f.error(22, "Invalid argument"), nil
+		}
+		return f.json(map[string]interface{}{"algorithm": algorithm, "checksum": hex.EncodeToString(sum)}), nil
+
+	case r.Method == "POST" && cmd == "fsync":
+		if !exists {
+			return f.error(2, "No such file or directory"), nil
+		}
+		return f.ok(), nil
+	}
+
+	return f.error(95, "Operation not supported"), nil
+}
+
+func response(status int, contentType string, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -0,0 +1,158 @@
+package triparclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JobProgress describes the work done by a Job so far.
+type JobProgress struct {
+	// Items is the number of objects and directories copied or deleted.
+	Items int64
+
+	// Bytes is the size of the copied objects.
+	Bytes int64
+
+	// Elapsed is the time since the job was started, or its duration once it
+	// is done.
+	Elapsed time.Duration
+
+	// Done is set once the job has completed, failed or was canceled.
+	Done bool
+}
+
+// Job is a tree operation running in the background, see StartCopyTreeJob
+// and StartDeleteTreeJob.
+type Job struct {
+	progress *jobProgress
+	cancel   context.CancelFunc
+	done     chan struct{}
+	started  time.Time
+
+	mx       sync.Mutex
+	err      error
+	finished time.Time
+}
+
+// jobProgress counts the items of a job whose context is returned by
+// withJobProgress.
+type jobProgress struct {
+	items int64
+	bytes int64
+}
+
+func withJobProgress(ctx context.Context) (context.Context, *jobProgress) {
+	progress := &jobProgress{}
+	return context.WithValue(ctx, jobProgressContextKey, progress), progress
+}
+
+func jobProgressFrom(ctx context.Context) *jobProgress {
+	if ctx == nil {
+		return nil
+	}
+	progress, _ := ctx.Value(jobProgressContextKey).(*jobProgress)
+	return progress
+}
+
+// jobItemDone counts a copied or deleted item of size bytes if ctx belongs to
+// a job.
+func jobItemDone(ctx context.Context, size int64) {
+	if progress := jobProgressFrom(ctx); progress != nil {
+		atomic.AddInt64(&progress.items, 1)
+		atomic.AddInt64(&progress.bytes, size)
+	}
+}
+
+// startJob runs fn in a goroutine. The job's context keeps the values of ctx,
+// e.g. WithRunAsUser, but is only canceled by Job.Cancel, so the job outlives
+// a request-scoped ctx.
+func startJob(ctx context.Context, op string, path string, fn func(ctx context.Context) error) *Job {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	ctx, progress := withJobProgress(ctx)
+
+	job := &Job{
+		progress: progress,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		started:  time.Now(),
+	}
+
+	goLabeled(ctx, op, path, func() {
+		defer cancel()
+
+		err := fn(ctx)
+
+		job.mx.Lock()
+		job.err = err
+		job.finished = time.Now()
+		job.mx.Unlock()
+
+		close(job.done)
+	})
+
+	return job
+}
+
+// StartCopyTreeJob starts CopyTree in the background and returns
+// immediately.
+func (tp *TriparClient) StartCopyTreeJob(ctx context.Context, src string, dst string, opts *CopyOptions) *Job {
+	return startJob(ctx, "CopyTree", src, func(ctx context.Context) error {
+		return tp.CopyTree(ctx, src, dst, opts)
+	})
+}
+
+// StartDeleteTreeJob starts DeleteTreeWithOptions in the background and
+// returns immediately.
+func (tp *TriparClient) StartDeleteTreeJob(ctx context.Context, path string, opts *DeleteTreeOptions) *Job {
+	return startJob(ctx, "DeleteTree", path, func(ctx context.Context) error {
+		return tp.DeleteTreeWithOptions(ctx, path, opts)
+	})
+}
+
+func (j *Job) Progress() JobProgress {
+	progress := JobProgress{
+		Items: atomic.LoadInt64(&j.progress.items),
+		Bytes: atomic.LoadInt64(&j.progress.bytes),
+	}
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if j.finished.IsZero() {
+		progress.Elapsed = time.Since(j.started)
+	} else {
+		progress.Elapsed = j.finished.Sub(j.started)
+		progress.Done = true
+	}
+
+	return progress
+}
+
+// Done returns a channel which is closed once the job is done.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits until the job is done and returns its error, or returns ctx's
+// error if ctx is done first. The job keeps running in that case.
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.err
+}
+
+// Cancel stops the job. Its error is then context.Canceled, unless it was
+// already done. Work done before the job was canceled is not undone, use a
+// Checkpoint to resume it.
+func (j *Job) Cancel() {
+	j.cancel()
+}
@@ -0,0 +1,104 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Job", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var blocked chan struct{}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		blocked = nil
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if blocked != nil && r.Method == "DELETE" {
+				select {
+				case <-blocked:
+				case <-r.Context().Done():
+					return nil, r.Context().Err()
+				}
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		fake.Mkdir("/root/src/a")
+		fake.PutFile("/root/src/1", "1")
+		fake.PutFile("/root/src/a/2", "22")
+	})
+
+	It("should copy trees in the background", func() {
+		job := client.StartCopyTreeJob(ctx, "/root/src", "/root/dst", nil)
+		Expect(job.Wait(ctx)).To(Succeed())
+
+		data, ok := fake.File("/root/dst/a/2")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("22"))
+
+		progress := job.Progress()
+		Expect(progress.Done).To(BeTrue())
+		Expect(progress.Items).To(Equal(int64(4)))
+		Expect(progress.Bytes).To(Equal(int64(3)))
+	})
+
+	It("should delete trees in the background", func() {
+		job := client.StartDeleteTreeJob(ctx, "/root/src", nil)
+		Expect(job.Wait(ctx)).To(Succeed())
+
+		Expect(fake.Exists("/root/src")).To(BeFalse())
+		Expect(job.Progress().Items).To(Equal(int64(4)))
+	})
+
+	It("should outlive the context it was started with", func() {
+		blocked = make(chan struct{})
+		client.RunAsHeader = "X-Run-As-User"
+
+		startCtx, cancel := context.WithCancel(WithRunAsUser(ctx, "alice"))
+		job := client.StartDeleteTreeJob(startCtx, "/root/src", nil)
+		cancel()
+
+		Consistently(job.Done(), 20*time.Millisecond).ShouldNot(BeClosed())
+		Expect(job.Progress().Done).To(BeFalse())
+
+		close(blocked)
+		Expect(job.Wait(ctx)).To(Succeed())
+		Expect(fake.Exists("/root/src")).To(BeFalse())
+	})
+
+	It("should be canceled", func() {
+		blocked = make(chan struct{})
+		defer close(blocked)
+
+		job := client.StartDeleteTreeJob(ctx, "/root/src", nil)
+		job.Cancel()
+
+		err := job.Wait(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(job.Progress().Done).To(BeTrue())
+		Expect(fake.Exists("/root/src/a")).To(BeTrue())
+	})
+
+	It("should stop waiting when the context is done", func() {
+		blocked = make(chan struct{})
+		defer close(blocked)
+
+		job := client.StartDeleteTreeJob(ctx, "/root/src", nil)
+		defer job.Cancel()
+
+		waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		Expect(job.Wait(waitCtx)).To(MatchError(context.DeadlineExceeded))
+		Expect(job.Progress().Elapsed).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
@@ -0,0 +1,37 @@
+package triparclient
+
+import (
+	"context"
+	"encoding/hex"
+	"hash/fnv"
+	"runtime/pprof"
+)
+
+// Profile label keys of the goroutines spawned by operations, e.g. for the
+// chunks of GetObject, the reader of PutObject and tree operations, so that
+// CPU and goroutine profiles attribute them to operations. Paths are hashed
+// with ProfilePathHash to keep them out of profiles.
+const (
+	ProfileLabelOp       = "tripar.op"
+	ProfileLabelPathHash = "tripar.path_hash"
+)
+
+// ProfilePathHash returns the value of the ProfileLabelPathHash label for
+// path, a hex encoded FNV-1a hash.
+func ProfilePathHash(path string) string {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// goLabeled runs fn in a new goroutine with the profile labels of the
+// operation op on path, in addition to the labels of ctx.
+func goLabeled(ctx context.Context, op string, path string, fn func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	labels := pprof.Labels(ProfileLabelOp, op, ProfileLabelPathHash, ProfilePathHash(path))
+	go pprof.Do(ctx, labels, func(context.Context) {
+		fn()
+	})
+}
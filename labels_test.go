@@ -0,0 +1,90 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"runtime/pprof"
+	"strings"
+
+	ioutils "github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("profile labels", func() {
+	It("should label the goroutines of operations", func() {
+		ctx := context.Background()
+		fake := newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", strings.Repeat("0123456789", 300))
+
+		blocked := make(chan struct{})
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.Method == "GET" && r.URL.Query().Get("cmd") == "" {
+				<-blocked
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 0, End: 2999})
+		Expect(err).NotTo(HaveOccurred())
+
+		profile := func() string {
+			var buf bytes.Buffer
+			Expect(pprof.Lookup("goroutine").WriteTo(&buf, 1)).To(Succeed())
+			return buf.String()
+		}
+		Eventually(profile).Should(And(
+			ContainSubstring(`"tripar.op":"GetObject"`),
+			ContainSubstring(`"tripar.path_hash":"`+ProfilePathHash("/root/object")+`"`),
+		))
+
+		close(blocked)
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveLen(3000))
+		Expect(rd.Close()).To(Succeed())
+	})
+
+	It("should label the goroutines of ListRecursive", func() {
+		ctx := context.Background()
+		fake := newFakeTripar()
+		fake.Mkdir("/root/dir/sub")
+
+		blocked := make(chan struct{})
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Opaque+r.URL.Path, "/root/dir/sub") && r.URL.Query().Get("cmd") == "ls" {
+				<-blocked
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- client.ListRecursive(ctx, "/root", nil, func(entry WalkEntry) error {
+				return nil
+			})
+		}()
+
+		Eventually(func() string {
+			var buf bytes.Buffer
+			Expect(pprof.Lookup("goroutine").WriteTo(&buf, 1)).To(Succeed())
+			return buf.String()
+		}).Should(And(
+			ContainSubstring(`"tripar.op":"ListRecursive"`),
+			ContainSubstring(`"tripar.path_hash":"`+ProfilePathHash("/root/dir/sub")+`"`),
+		))
+
+		close(blocked)
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should hash paths", func() {
+		Expect(ProfilePathHash("/root/object")).To(HaveLen(16))
+		Expect(ProfilePathHash("/root/object")).NotTo(Equal(ProfilePathHash("/root/other")))
+	})
+})
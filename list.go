@@ -0,0 +1,254 @@
+package triparclient
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+type SortBy string
+
+const (
+	SortByNone  SortBy = ""
+	SortByName  SortBy = "name"
+	SortBySize  SortBy = "size"
+	SortByMtime SortBy = "mtime"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+type ListOptions struct {
+	SortBy    SortBy
+	SortOrder SortOrder
+}
+
+// ListWithOptions lists the directory at path like List.
+//
+// Deprecated: use List with ListOption values, e.g. ListSort.
+func (tp *TriparClient) ListWithOptions(ctx context.Context, path string, opts *ListOptions) (entries Entries, err error) {
+	return tp.listWithOptions(ctx, path, opts)
+}
+
+func (tp *TriparClient) listWithOptions(ctx context.Context, path string, opts *ListOptions) (entries Entries, err error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	entries, err = tp.list(ctx, path)
+	if err != nil {
+		return Entries{}, err
+	}
+
+	// ls does not support sorting, so entries are sorted after listing
+	if err := tp.sortEntries(ctx, path, entries.Entries, opts); err != nil {
+		return Entries{}, err
+	}
+
+	return entries, nil
+}
+
+const DefaultListStatConcurrency = 8
+
+type ListStatOptions struct {
+	// Concurrency is the number of concurrent Stat requests used if ls does
+	// not return metadata, DefaultListStatConcurrency if 0.
+	Concurrency int
+}
+
+// ListStat lists the directory at path like List, but every entry has its
+// Type, Size and Mtime set. Firmware whose ls includes metadata needs a
+// single request, otherwise every entry is stat-ed with a bounded number of
+// concurrent requests. Entries are returned in ls order either way.
+func (tp *TriparClient) ListStat(ctx context.Context, path string, opts *ListStatOptions) (entries Entries, err error) {
+	if opts == nil {
+		opts = &ListStatOptions{}
+	}
+
+	entries, err = tp.list(ctx, path)
+	if err != nil {
+		return Entries{}, err
+	}
+
+	if err := tp.fillEntryMetadata(ctx, path, entries.Entries, opts.Concurrency); err != nil {
+		return Entries{}, xerrors.Errorf("list stat error: %w", err)
+	}
+
+	return entries, nil
+}
+
+// fillEntryMetadata stats the entries without metadata, concurrency at a
+// time. It stops at the first error.
+func (tp *TriparClient) fillEntryMetadata(ctx context.Context, dir string, entries []Entry, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = DefaultListStatConcurrency
+	}
+
+	return tp.statEntries(ctx, "List", dir, entries, make(chan struct{}, concurrency))
+}
+
+// statEntries stats the entries without metadata concurrently, each Stat
+// holding a slot of sem, so that callers can share the limit with their
+// other requests. It stops at the first error.
+func (tp *TriparClient) statEntries(ctx context.Context, op string, dir string, entries []Entry, sem chan struct{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i := range entries {
+		if entries[i].HasMetadata() {
+			continue
+		}
+
+		acquired := true
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			acquired = false
+		}
+		if !acquired {
+			break
+		}
+
+		entry := &entries[i]
+		wg.Add(1)
+		goLabeled(ctx, op, dir, func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			info, err := tp.Stat(ctx, joinPath(dir, entry.Name), StatSkipIdentity())
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+
+			entry.setMetadata(info)
+		})
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (tp *TriparClient) sortEntries(ctx context.Context, dir string, entries []Entry, opts *ListOptions) error {
+	var less func(a Entry, b Entry) bool
+
+	switch opts.SortBy {
+	case SortByNone:
+		return nil
+	case SortByName:
+		less = func(a Entry, b Entry) bool {
+			return a.Name < b.Name
+		}
+	case SortBySize:
+		less = func(a Entry, b Entry) bool {
+			return a.Size < b.Size
+		}
+	case SortByMtime:
+		less = func(a Entry, b Entry) bool {
+			return a.Mtime < b.Mtime
+		}
+	default:
+		return xerrors.Errorf("invalid sort by: %s", opts.SortBy)
+	}
+
+	if opts.SortBy != SortByName {
+		if err := tp.fillEntryMetadata(ctx, dir, entries, 0); err != nil {
+			return xerrors.Errorf("sort entries stat error: %w", err)
+		}
+	}
+
+	switch opts.SortOrder {
+	case SortAscending, "":
+	case SortDescending:
+		asc := less
+		less = func(a Entry, b Entry) bool {
+			return asc(b, a)
+		}
+	default:
+		return xerrors.Errorf("invalid sort order: %s", opts.SortOrder)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if less(entries[i], entries[j]) {
+			return true
+		}
+		if less(entries[j], entries[i]) {
+			return false
+		}
+		// names are unique, so ties are broken deterministically
+		return entries[i].Name < entries[j].Name
+	})
+
+	return nil
+}
+
+type ListPageOptions struct {
+	// Limit is the maximum number of entries in the page, 0 means unlimited.
+	Limit int
+
+	// Marker is the name of the last entry of the previous page. Only entries
+	// sorted after it are returned, so entries added or removed between pages
+	// don't shift the remaining ones.
+	Marker string
+}
+
+type Page struct {
+	Entries []Entry
+
+	// NextMarker is empty if this is the last page.
+	NextMarker string
+}
+
+func (tp *TriparClient) ListPage(ctx context.Context, path string, opts *ListPageOptions) (page Page, err error) {
+	if opts == nil {
+		opts = &ListPageOptions{}
+	}
+	if opts.Limit < 0 {
+		return Page{}, xerrors.Errorf("invalid list page limit: %d", opts.Limit)
+	}
+
+	entries, err := tp.listWithOptions(ctx, path, &ListOptions{
+		SortBy: SortByName,
+	})
+	if err != nil {
+		return Page{}, err
+	}
+
+	all := entries.Entries
+
+	start := 0
+	if opts.Marker != "" {
+		start = sort.Search(len(all), func(i int) bool {
+			return all[i].Name > opts.Marker
+		})
+	}
+
+	end := len(all)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+	}
+
+	page.Entries = all[start:end]
+	if end < len(all) {
+		page.NextMarker = all[end-1].Name
+	}
+
+	return page, nil
+}
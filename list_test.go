@@ -0,0 +1,162 @@
+package triparclient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+func entryNames(entries Entries) []string {
+	names := []string{}
+	for _, entry := range entries.Entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+var _ = Describe("List sorting", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/b", "12345")
+		fake.PutFile("/root/c", "1")
+		fake.PutFile("/root/a", "123")
+	})
+
+	It("should sort by name", func() {
+		entries, err := client.List(ctx, "/root", ListSort(SortByName, SortDescending))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"c", "b", "a"}))
+	})
+
+	It("should sort by size", func() {
+		entries, err := client.List(ctx, "/root", ListSort(SortBySize, SortAscending))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"c", "a", "b"}))
+		Expect(entries.Entries[0].Size).To(Equal(int64(1)))
+	})
+
+	It("should sort by mtime", func() {
+		entries, err := client.List(ctx, "/root", ListSort(SortByMtime, SortDescending))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"a", "c", "b"}))
+	})
+
+	It("should fail for an invalid sort", func() {
+		_, err := client.List(ctx, "/root", ListSort("color", SortAscending))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ListStat", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var inFlight, maxInFlight int32
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		inFlight, maxInFlight = 0, 0
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Query().Get("cmd") == "stat" {
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		fake.Mkdir("/root")
+		for i := 0; i < 20; i++ {
+			fake.PutFile(fmt.Sprintf("/root/%02d", i), strings.Repeat("x", i))
+		}
+		fake.Mkdir("/root/dir")
+	})
+
+	It("should stat entries concurrently in ls order", func() {
+		entries, err := client.ListStat(ctx, "/root", &ListStatOptions{Concurrency: 4})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(HaveLen(21))
+		for i, entry := range entries.Entries[:20] {
+			Expect(entry.Name).To(Equal(fmt.Sprintf("%02d", i)))
+			Expect(entry.Type).To(Equal(EntryTypeFile))
+			Expect(entry.Size).To(Equal(int64(i)))
+		}
+		Expect(entries.Entries[20].Type).To(Equal(EntryTypeDirectory))
+
+		Expect(maxInFlight).To(BeNumerically(">", 1))
+		Expect(maxInFlight).To(BeNumerically("<=", 4))
+	})
+
+	It("should fail if a stat fails", func() {
+		fake.Unsupport("stat")
+		_, err := client.ListStat(ctx, "/root", nil)
+		Expect(err).To(MatchError(ErrNotSupported))
+	})
+})
+
+var _ = Describe("ListPage", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			fake.PutFile("/root/"+name, name)
+		}
+	})
+
+	It("should list all pages", func() {
+		page, err := client.ListPage(ctx, "/root", &ListPageOptions{Limit: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(Entries{Entries: page.Entries})).To(Equal([]string{"a", "b"}))
+		Expect(page.NextMarker).To(Equal("b"))
+
+		page, err = client.ListPage(ctx, "/root", &ListPageOptions{Limit: 2, Marker: page.NextMarker})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(Entries{Entries: page.Entries})).To(Equal([]string{"c", "d"}))
+		Expect(page.NextMarker).To(Equal("d"))
+
+		page, err = client.ListPage(ctx, "/root", &ListPageOptions{Limit: 2, Marker: page.NextMarker})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(Entries{Entries: page.Entries})).To(Equal([]string{"e"}))
+		Expect(page.NextMarker).To(BeEmpty())
+	})
+
+	It("should continue after marker if entries change between pages", func() {
+		page, err := client.ListPage(ctx, "/root", &ListPageOptions{Limit: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		fake.PutFile("/root/0", "0")
+		fake.PutFile("/root/bb", "bb")
+
+		page, err = client.ListPage(ctx, "/root", &ListPageOptions{Limit: 2, Marker: page.NextMarker})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(Entries{Entries: page.Entries})).To(Equal([]string{"bb", "c"}))
+	})
+})
@@ -0,0 +1,72 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrObjectTooLarge = errors.New("object too large")
+	ErrSizeMismatch   = errors.New("size mismatch")
+)
+
+// ObjectTooLargeError is returned by PutObject if the reader returns more
+// than the maximum object size. It matches ErrObjectTooLarge.
+type ObjectTooLargeError struct {
+	Limit int64
+}
+
+func (e *ObjectTooLargeError) Error() string {
+	return fmt.Sprintf("object too large: more than %d bytes", e.Limit)
+}
+
+func (e *ObjectTooLargeError) Is(target error) bool {
+	return target == ErrObjectTooLarge
+}
+
+// SizeMismatchError is returned by PutObject with VerifySize if the object's
+// size differs from the number of bytes sent. It matches ErrSizeMismatch.
+type SizeMismatchError struct {
+	Sent int64
+	Size int64
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("size mismatch: sent %d bytes, object has %d bytes", e.Sent, e.Size)
+}
+
+func (e *SizeMismatchError) Is(target error) bool {
+	return target == ErrSizeMismatch
+}
+
+// verifySize checks that the object at path has size bytes.
+func (tp *TriparClient) verifySize(ctx context.Context, path string, size int64) error {
+	info, err := tp.Stat(ctx, path, StatSkipIdentity())
+	if err != nil {
+		return xerrors.Errorf("verify size stat error: %w", err)
+	}
+	if info.Status.Size != size {
+		return &SizeMismatchError{Sent: size, Size: info.Status.Size}
+	}
+	return nil
+}
+
+// maxSizeReader fails once more than limit bytes are read.
+type maxSizeReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+func (r *maxSizeReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, &ObjectTooLargeError{Limit: r.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,127 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+var _ = Describe("MaxObjectSize", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		client.MaxObjectSize = 3000
+	})
+
+	It("should abort runaway uploads and delete the object", func() {
+		err := client.PutObject(ctx, "/root/object", endlessReader{})
+		Expect(err).To(MatchError(ErrObjectTooLarge))
+		var tooLarge *ObjectTooLargeError
+		Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		Expect(tooLarge.Limit).To(Equal(int64(3000)))
+
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+		Expect(fake.Requests()).To(ContainElement("DELETE /root/object"))
+	})
+
+	It("should abort chunked uploads", func() {
+		err := client.PutObject(ctx, "/root/object", io.LimitReader(endlessReader{}, 5000), PutChunked())
+		Expect(err).To(MatchError(ErrObjectTooLarge))
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should allow objects up to the limit", func() {
+		Expect(client.PutObject(ctx, "/root/object", io.LimitReader(endlessReader{}, 3000))).To(Succeed())
+		data, _ := fake.File("/root/object")
+		Expect(data).To(HaveLen(3000))
+	})
+
+	It("should be overridden by put options", func() {
+		Expect(client.PutObject(ctx, "/root/object", io.LimitReader(endlessReader{}, 4000), PutMaxObjectSize(4000))).To(Succeed())
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12"), PutMaxObjectSize(1))).To(MatchError(ErrObjectTooLarge))
+	})
+
+	It("should fail early for too large size hints", func() {
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12"), PutSizeHint(4000))).To(MatchError(ErrObjectTooLarge))
+		Expect(fake.Requests()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("VerifySize", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		// drops the last byte of every write, like a truncating proxy
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.Method == http.MethodPut || r.Method == http.MethodPost {
+				data, err := io.ReadAll(r.Body)
+				if err != nil {
+					return nil, err
+				}
+				if len(data) > 0 {
+					data = data[:len(data)-1]
+				}
+				r.Body = io.NopCloser(bytes.NewReader(data))
+				r.ContentLength = int64(len(data))
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		fake.Mkdir("/root")
+	})
+
+	It("should not verify by default", func() {
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"))).To(Succeed())
+		Expect(fake.Exists("/root/object")).To(BeTrue())
+	})
+
+	It("should detect truncated uploads and delete the object", func() {
+		err := client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutVerifySize())
+		Expect(err).To(MatchError(ErrSizeMismatch))
+		var mismatch *SizeMismatchError
+		Expect(errors.As(err, &mismatch)).To(BeTrue())
+		Expect(mismatch.Sent).To(Equal(int64(5)))
+		Expect(mismatch.Size).To(Equal(int64(4)))
+
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should detect truncated chunked uploads", func() {
+		err := client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutChunked(), PutVerifySize())
+		Expect(err).To(MatchError(ErrSizeMismatch))
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should pass when the size matches", func() {
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutVerifySize())).To(Succeed())
+		Expect(fake.Requests()).To(ContainElement("GET /root/object stat"))
+	})
+})
@@ -0,0 +1,176 @@
+package triparclient
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBudget limits the bytes of transfer buffers held at the same time by
+// all clients sharing it, so that a service running many transfers has a
+// predictable worst-case memory footprint. Uploads and downloads wait for
+// enough of the budget to become available before taking a buffer.
+type MemoryBudget struct {
+	mx      sync.Mutex
+	limit   int64
+	used    int64
+	changed chan struct{}
+}
+
+// NewMemoryBudget returns a budget of limit bytes.
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{
+		limit:   limit,
+		changed: make(chan struct{}),
+	}
+}
+
+// Acquire waits until n bytes of the budget are available and takes them,
+// or returns the context's error. Requests larger than the whole budget
+// wait until nothing else is in use.
+func (b *MemoryBudget) Acquire(ctx context.Context, n int64) error {
+	if n > b.limit {
+		n = b.limit
+	}
+
+	for {
+		b.mx.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mx.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mx.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release returns n bytes taken with Acquire.
+func (b *MemoryBudget) Release(n int64) {
+	if n > b.limit {
+		n = b.limit
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.used -= n
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// InUse returns the number of bytes currently taken.
+func (b *MemoryBudget) InUse() int64 {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return b.used
+}
+
+// Limit returns the size of the budget in bytes.
+func (b *MemoryBudget) Limit() int64 {
+	return b.limit
+}
+
+// poolBufferSize returns the size of the BufferPool's buffers, or 0 if the
+// pool doesn't report it.
+func (tp *TriparClient) poolBufferSize() int64 {
+	if sized, ok := tp.bufferPool.(interface{ BufferSize() int64 }); ok {
+		return sized.BufferSize()
+	}
+	return 0
+}
+
+// memoryBudget returns the client's MemoryBudget or the TransferManager's.
+func (tp *TriparClient) memoryBudget() *MemoryBudget {
+	if tp.MemoryBudget != nil {
+		return tp.MemoryBudget
+	}
+	if tp.TransferManager != nil {
+		return tp.TransferManager.memory
+	}
+	return nil
+}
+
+// reserveBuffer takes n bytes of the MemoryBudget, if there is one.
+func (tp *TriparClient) reserveBuffer(ctx context.Context, n int64) error {
+	budget := tp.memoryBudget()
+	if budget == nil || n <= 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return budget.Acquire(ctx, n)
+}
+
+// releaseBuffer returns n bytes taken with reserveBuffer.
+func (tp *TriparClient) releaseBuffer(n int64) {
+	budget := tp.memoryBudget()
+	if budget == nil || n <= 0 {
+		return
+	}
+	budget.Release(n)
+}
+
+// getBuffer takes a buffer from the BufferPool within the MemoryBudget. It
+// must be returned with putBuffer.
+func (tp *TriparClient) getBuffer(ctx context.Context) ([]byte, error) {
+	return tp.getBufferOrWait(ctx, nil)
+}
+
+// getBufferOrWait is like getBuffer, but if the BufferPool supports TryGet
+// and has no free buffer, wait is called before waiting for one, e.g. to
+// release buffers the caller holds.
+func (tp *TriparClient) getBufferOrWait(ctx context.Context, wait func()) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	size := tp.poolBufferSize()
+	if err := tp.reserveBuffer(ctx, size); err != nil {
+		return nil, err
+	}
+
+	buffer, err := tp.getPoolBuffer(ctx, wait)
+	if err != nil {
+		tp.releaseBuffer(size)
+		return nil, err
+	}
+
+	if size == 0 {
+		if err := tp.reserveBuffer(ctx, int64(len(buffer))); err != nil {
+			tp.bufferPool.Put(buffer)
+			return nil, err
+		}
+	}
+
+	return buffer, nil
+}
+
+// getPoolBuffer takes a buffer from the BufferPool, without waiting past the
+// context if the pool supports it.
+func (tp *TriparClient) getPoolBuffer(ctx context.Context, wait func()) ([]byte, error) {
+	if pool, ok := tp.bufferPool.(interface{ TryGet() ([]byte, bool) }); ok && wait != nil {
+		if buffer, ok := pool.TryGet(); ok {
+			return buffer, nil
+		}
+		wait()
+	}
+	if pool, ok := tp.bufferPool.(interface {
+		GetContext(ctx context.Context) ([]byte, error)
+	}); ok {
+		return pool.GetContext(ctx)
+	}
+	return tp.bufferPool.Get(), nil
+}
+
+func (tp *TriparClient) putBuffer(buffer []byte) {
+	tp.bufferPool.Put(buffer)
+	tp.releaseBuffer(int64(len(buffer)))
+}
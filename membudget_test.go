@@ -0,0 +1,94 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("MemoryBudget", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("should block until the budget is released", func() {
+		budget := NewMemoryBudget(100)
+		Expect(budget.Acquire(ctx, 60)).To(Succeed())
+
+		acquired := make(chan error)
+		go func() {
+			acquired <- budget.Acquire(ctx, 60)
+		}()
+		Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+
+		budget.Release(60)
+		Eventually(acquired).Should(Receive(BeNil()))
+		Expect(budget.InUse()).To(Equal(int64(60)))
+	})
+
+	It("should stop waiting when the context is done", func() {
+		budget := NewMemoryBudget(100)
+		Expect(budget.Acquire(ctx, 100)).To(Succeed())
+
+		tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		Expect(budget.Acquire(tctx, 1)).To(MatchError(context.DeadlineExceeded))
+		Expect(budget.InUse()).To(Equal(int64(100)))
+	})
+
+	It("should cap requests larger than the budget", func() {
+		budget := NewMemoryBudget(100)
+		Expect(budget.Acquire(ctx, 1000)).To(Succeed())
+		Expect(budget.InUse()).To(Equal(int64(100)))
+		budget.Release(1000)
+		Expect(budget.InUse()).To(Equal(int64(0)))
+	})
+
+	Describe("shared by clients", func() {
+		var budget *MemoryBudget
+		var client *TriparClient
+		var fake *fakeTripar
+
+		data := strings.Repeat("0123456789", 300)
+
+		BeforeEach(func() {
+			budget = NewMemoryBudget(2048)
+			client, fake = newFakeTriparClient()
+			client.MemoryBudget = budget
+			fake.Mkdir("/root")
+		})
+
+		It("should hold the budget only while transferring", func() {
+			// not seekable, so the reader is copied into buffers
+			Expect(client.PutObject(ctx, "/root/object", io.MultiReader(strings.NewReader(data)))).To(Succeed())
+			Expect(budget.InUse()).To(Equal(int64(0)))
+
+			var buf strings.Builder
+			_, err := client.GetObjectTo(ctx, "/root/object", &buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(Equal(data))
+			Expect(budget.InUse()).To(Equal(int64(0)))
+		})
+
+		It("should fail uploads waiting for the budget when the context is done", func() {
+			Expect(budget.Acquire(ctx, 2048)).To(Succeed())
+
+			tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			err := client.PutObject(tctx, "/root/object", io.MultiReader(strings.NewReader(data)))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(fake.Exists("/root/object")).To(BeFalse())
+
+			budget.Release(2048)
+			Expect(budget.InUse()).To(Equal(int64(0)))
+		})
+	})
+})
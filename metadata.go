@@ -0,0 +1,207 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// MetadataXattr is the extended attribute object metadata is stored in.
+const MetadataXattr = "user.triparclient.metadata"
+
+// errNoAttribute is ENODATA, returned for missing extended attributes.
+const errNoAttribute = 61
+
+type xattrResponse struct {
+	Name  string `json:"name"`
+	Value string `json:"value" tripar:"required"`
+}
+
+func isNoAttribute(err error) bool {
+	var perr *Error
+	return errors.As(err, &perr) && perr.Code == errNoAttribute
+}
+
+// metadataSidecarPath returns the hidden file metadata is stored in if the
+// appliance does not support extended attributes.
+func metadataSidecarPath(path string) string {
+	dir, name := pathpkg.Split(path)
+	return dir + "." + name + ".meta"
+}
+
+func (tp *TriparClient) getXattr(ctx context.Context, path string, name string) (value []byte, err error) {
+	params := tp.cmd("xattr")
+	params.Set("name", name)
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "GET",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("xattr", err)
+		return nil, xerrors.Errorf("get xattr request error: %w", err)
+	}
+
+	attr := xattrResponse{}
+	if err := tp.unmarshalResponse(rsp, &attr); err != nil {
+		tp.caps.observe("xattr", err)
+		return nil, xerrors.Errorf("get xattr response error: %w", err)
+	}
+
+	return []byte(attr.Value), nil
+}
+
+func (tp *TriparClient) setXattr(ctx context.Context, path string, name string, value []byte) (err error) {
+	params := tp.cmd("xattr")
+	params.Set("name", name)
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:          ctx,
+		Method:           "POST",
+		Path:             tp.path(path),
+		Params:           params,
+		ReqReader:        bytes.NewReader(value),
+		ReqContentLength: int64(len(value)),
+		ExpectedStatus:   []int{http.StatusOK},
+	})
+	if err != nil {
+		tp.caps.observe("xattr", err)
+		return xerrors.Errorf("set xattr request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		tp.caps.observe("xattr", err)
+		return xerrors.Errorf("set xattr response error: %w", err)
+	}
+
+	return nil
+}
+
+// GetObjectMetadata returns the metadata set with SetObjectMetadata, or an
+// empty map if none was set. It fails with ErrNotFound if the object does not
+// exist.
+func (tp *TriparClient) GetObjectMetadata(ctx context.Context, path string) (metadata map[string]string, err error) {
+	defer tp.observe(ctx, "GetObjectMetadata", path, time.Now(), &err)
+
+	var data []byte
+
+	if tp.caps.supported("xattr") {
+		data, err = tp.getXattr(ctx, path, MetadataXattr)
+		if isNoAttribute(err) {
+			return map[string]string{}, nil
+		}
+		if err != nil && !errors.Is(err, ErrNotSupported) {
+			return nil, xerrors.Errorf("get object metadata error: %w", err)
+		}
+	}
+
+	if !tp.caps.supported("xattr") {
+		data, err = tp.getMetadataSidecar(ctx, path)
+		if err != nil {
+			return nil, xerrors.Errorf("get object metadata error: %w", err)
+		}
+		if data == nil {
+			return map[string]string{}, nil
+		}
+	}
+
+	metadata = map[string]string{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, xerrors.Errorf("get object metadata unmarshal error: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func (tp *TriparClient) getMetadataSidecar(ctx context.Context, path string) ([]byte, error) {
+	// the sidecar may outlive its object, so the object is checked first
+	if _, err := tp.Stat(ctx, path, StatSkipIdentity()); err != nil {
+		return nil, err
+	}
+
+	rd, _, err := tp.GetObject(ctx, metadataSidecarPath(path), nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	return io.ReadAll(rd)
+}
+
+// SetObjectMetadata replaces the metadata of an object with metadata. It is
+// meant for small amounts of application metadata.
+//
+// Metadata is stored in the MetadataXattr extended attribute. If the
+// appliance does not support extended attributes, it is stored in a hidden
+// ".<name>.meta" file next to the object, which is replaced atomically by
+// moving a temporary file over it. Sidecar files are not moved or deleted
+// with their objects.
+func (tp *TriparClient) SetObjectMetadata(ctx context.Context, path string, metadata map[string]string) (err error) {
+	defer tp.observe(ctx, "SetObjectMetadata", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "SetObjectMetadata", Path: path}) {
+		return nil
+	}
+
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return xerrors.Errorf("set object metadata marshal error: %w", err)
+	}
+
+	if tp.caps.supported("xattr") {
+		err = tp.setXattr(ctx, path, MetadataXattr, data)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrNotSupported) {
+			return xerrors.Errorf("set object metadata error: %w", err)
+		}
+	}
+
+	if err := tp.setMetadataSidecar(ctx, path, data); err != nil {
+		return xerrors.Errorf("set object metadata error: %w", err)
+	}
+
+	return nil
+}
+
+func (tp *TriparClient) setMetadataSidecar(ctx context.Context, path string, data []byte) error {
+	if _, err := tp.Stat(ctx, path, StatSkipIdentity()); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	sidecar := metadataSidecarPath(path)
+	tmp := sidecar + ".tmp-" + hex.EncodeToString(suffix)
+
+	if err := tp.PutObject(ctx, tmp, bytes.NewReader(data), PutSizeHint(int64(len(data)))); err != nil {
+		return err
+	}
+
+	if err := tp.MoveObject(ctx, tmp, sidecar); err != nil {
+		tp.deleteObject(ctx, tmp)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package triparclient_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("ObjectMetadata", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+	})
+
+	testMetadata := func() {
+		metadata, err := client.GetObjectMetadata(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metadata).To(BeEmpty())
+
+		Expect(client.SetObjectMetadata(ctx, "/root/object", map[string]string{"a": "1", "b": "2"})).To(Succeed())
+		metadata, err = client.GetObjectMetadata(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metadata).To(Equal(map[string]string{"a": "1", "b": "2"}))
+
+		Expect(client.SetObjectMetadata(ctx, "/root/object", map[string]string{"c": "3"})).To(Succeed())
+		metadata, err = client.GetObjectMetadata(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metadata).To(Equal(map[string]string{"c": "3"}))
+
+		_, err = client.GetObjectMetadata(ctx, "/root/missing")
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(client.SetObjectMetadata(ctx, "/root/missing", map[string]string{"a": "1"})).To(MatchError(ErrNotFound))
+	}
+
+	It("should store metadata in extended attributes", func() {
+		testMetadata()
+
+		Expect(fake.Exists("/root/.object.meta")).To(BeFalse())
+	})
+
+	It("should store metadata in sidecar files without extended attributes", func() {
+		fake.Unsupport("xattr")
+
+		testMetadata()
+
+		data, ok := fake.File("/root/.object.meta")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(`{"c":"3"}`))
+		entries, err := client.List(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{".object.meta", "object"}))
+	})
+})
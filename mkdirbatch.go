@@ -0,0 +1,135 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const DefaultCreateDirectoriesConcurrency = 8
+
+type CreateDirectoriesOptions struct {
+	// Concurrency is the number of concurrent requests,
+	// DefaultCreateDirectoriesConcurrency if 0.
+	Concurrency int
+
+	// ContinueOnError makes CreateDirectoriesBatch create as many directories
+	// as possible and return all errors joined, instead of stopping at the
+	// first one.
+	ContinueOnError bool
+}
+
+// CreateDirectoriesBatch creates the directories at paths with their
+// parents, like CreateDirectories, with concurrent requests. Duplicate paths
+// and paths which are parents of other paths are skipped, as they are created
+// with the deeper directories, so restoring a tree takes one request per leaf
+// directory.
+func (tp *TriparClient) CreateDirectoriesBatch(ctx context.Context, paths []string) error {
+	return tp.CreateDirectoriesBatchWithOptions(ctx, paths, nil)
+}
+
+func (tp *TriparClient) CreateDirectoriesBatchWithOptions(
+	ctx context.Context,
+	paths []string,
+	opts *CreateDirectoriesOptions,
+) (err error) {
+	defer tp.observe(ctx, "CreateDirectoriesBatch", "", time.Now(), &err)
+
+	if opts == nil {
+		opts = &CreateDirectoriesOptions{}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCreateDirectoriesConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	var errs []error
+
+	for _, path := range leafDirectories(paths) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		path := path
+		wg.Add(1)
+		goLabeled(ctx, "CreateDirectoriesBatch", path, func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := tp.CreateDirectories(ctx, path); err != nil {
+				mx.Lock()
+				defer mx.Unlock()
+
+				if !opts.ContinueOnError {
+					if len(errs) > 0 {
+						return
+					}
+					cancel()
+				}
+				errs = append(errs, xerrors.Errorf("create directories %s error: %w", path, err))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return ctx.Err()
+}
+
+// leafDirectories returns paths without duplicates and without paths which
+// are parents of other paths, sorted.
+func leafDirectories(paths []string) []string {
+	byClean := make(map[string]string, len(paths))
+	for _, path := range paths {
+		clean := pathpkg.Clean("/" + path)
+		if _, ok := byClean[clean]; !ok {
+			byClean[clean] = path
+		}
+	}
+
+	cleaned := make([]string, 0, len(byClean))
+	for clean := range byClean {
+		cleaned = append(cleaned, clean)
+	}
+	sort.Strings(cleaned)
+
+	leaves := make([]string, 0, len(cleaned))
+	for i, clean := range cleaned {
+		// the children of a path sort among the paths it is a prefix of
+		isParent := false
+		for _, other := range cleaned[i+1:] {
+			if strings.HasPrefix(other, strings.TrimSuffix(clean, "/")+"/") {
+				isParent = true
+				break
+			}
+			if !strings.HasPrefix(other, clean) {
+				break
+			}
+		}
+		if !isParent {
+			leaves = append(leaves, byClean[clean])
+		}
+	}
+
+	return leaves
+}
@@ -0,0 +1,82 @@
+package triparclient_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("CreateDirectoriesBatch", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+	})
+
+	It("should create leaf directories with their parents", func() {
+		Expect(client.CreateDirectoriesBatch(ctx, []string{
+			"/root/a",
+			"/root/a/b",
+			"/root/a/b/c",
+			"/root/a/b/d",
+			"root/a/b/d",
+			"/root/a-b",
+			"/root/ab/",
+		})).To(Succeed())
+
+		for _, dir := range []string{"/root/a/b/c", "/root/a/b/d", "/root/a-b", "/root/ab"} {
+			Expect(fake.Exists(dir)).To(BeTrue(), dir)
+		}
+
+		requests := fake.Requests()
+		sort.Strings(requests)
+		Expect(requests).To(Equal([]string{
+			"PUT /root/a-b mkdir",
+			"PUT /root/a/b/c mkdir",
+			"PUT /root/a/b/d mkdir",
+			"PUT /root/ab mkdir",
+		}))
+	})
+
+	It("should create many directories concurrently", func() {
+		paths := []string{}
+		for i := 0; i < 100; i++ {
+			paths = append(paths, fmt.Sprintf("/root/%d/%d", i%10, i))
+		}
+
+		Expect(client.CreateDirectoriesBatchWithOptions(ctx, paths, &CreateDirectoriesOptions{Concurrency: 4})).To(Succeed())
+
+		for _, dir := range paths {
+			Expect(fake.Exists(dir)).To(BeTrue(), dir)
+		}
+		Expect(fake.Requests()).To(HaveLen(100))
+	})
+
+	It("should stop at the first error", func() {
+		fake.PutFile("/root/file", "1")
+
+		err := client.CreateDirectoriesBatchWithOptions(ctx, []string{"/root/file/a", "/root/z"}, &CreateDirectoriesOptions{Concurrency: 1})
+		Expect(err).To(MatchError(ErrNotADirectory))
+		Expect(fake.Exists("/root/z")).To(BeFalse())
+	})
+
+	It("should continue on errors if enabled", func() {
+		fake.PutFile("/root/file", "1")
+
+		err := client.CreateDirectoriesBatchWithOptions(ctx, []string{"/root/file/a", "/root/z"}, &CreateDirectoriesOptions{
+			Concurrency:     1,
+			ContinueOnError: true,
+		})
+		Expect(err).To(MatchError(ErrNotADirectory))
+		Expect(fake.Exists("/root/z")).To(BeTrue())
+	})
+})
@@ -0,0 +1,188 @@
+package triparclient
+
+// Per-call options. Stat, List, GetObject and PutObject take them as a
+// variadic list of functions applied to the operation's options struct, so
+// new options can be added without changing method signatures. The options
+// structs only hold the values set by the functions; ListWithOptions,
+// GetObjectWithOptions and PutObjectWithOptions, which take the structs
+// directly, are deprecated. Bulk operations like CopyTree and
+// DeleteTreeWithOptions take an options struct, as they have no variadic
+// form.
+
+type StatOptions struct {
+	// SkipIdentity skips resolving user and group names even if the client
+	// has an IdentityResolver.
+	SkipIdentity bool
+}
+
+type StatOption func(opts *StatOptions)
+
+func StatSkipIdentity() StatOption {
+	return func(opts *StatOptions) {
+		opts.SkipIdentity = true
+	}
+}
+
+func newStatOptions(options []StatOption) *StatOptions {
+	opts := &StatOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+type ListOption func(opts *ListOptions)
+
+func ListSort(by SortBy, order SortOrder) ListOption {
+	return func(opts *ListOptions) {
+		opts.SortBy = by
+		opts.SortOrder = order
+	}
+}
+
+func newListOptions(options []ListOption) *ListOptions {
+	opts := &ListOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+type GetOptions struct {
+	// ChunkSize overrides the client's chunk size for ranged GET requests,
+	// 0 means the client default.
+	ChunkSize int64
+
+	// SniffContentType detects the content type from the first 512 bytes of
+	// the object with http.DetectContentType if the appliance returns
+	// application/octet-stream, which it does for all objects.
+	SniffContentType bool
+
+	// CacheDir enables a local cache of downloaded objects. Complete reads
+	// are written to a file in CacheDir while they are read, and later reads
+	// of the same object with the same mtime and size, including ranged
+	// reads, are served from that file. The object is still stat-ed for
+	// every read. Nothing evicts files from CacheDir.
+	CacheDir string
+
+	// Stats is set to the statistics of the download's requests once the
+	// returned reader is closed, or when GetObject fails.
+	Stats *TransferStats
+
+	// VerifyChunks reads every chunk of ChunkSize bytes a second time and
+	// compares their CRC32C once the chunk was read. On a mismatch reading
+	// fails with a *ChunkChecksumError, so the corrupted chunk must be
+	// discarded by the caller. Reads served from CacheDir are not verified.
+	VerifyChunks bool
+
+	// Decompress makes GetObject return the decompressed content of objects
+	// which are stored gzip-compressed, i.e. whose name ends with .gz or whose
+	// Content-Encoding is gzip. The returned Stat still describes the
+	// compressed object, e.g. its size. Ranges of compressed objects are not
+	// supported.
+	Decompress bool
+}
+
+type GetOption func(opts *GetOptions)
+
+func GetChunkSize(size int64) GetOption {
+	return func(opts *GetOptions) {
+		opts.ChunkSize = size
+	}
+}
+
+func GetSniffContentType() GetOption {
+	return func(opts *GetOptions) {
+		opts.SniffContentType = true
+	}
+}
+
+func GetCacheDir(dir string) GetOption {
+	return func(opts *GetOptions) {
+		opts.CacheDir = dir
+	}
+}
+
+func GetStats(stats *TransferStats) GetOption {
+	return func(opts *GetOptions) {
+		opts.Stats = stats
+	}
+}
+
+func GetVerifyChunks() GetOption {
+	return func(opts *GetOptions) {
+		opts.VerifyChunks = true
+	}
+}
+
+func GetDecompress() GetOption {
+	return func(opts *GetOptions) {
+		opts.Decompress = true
+	}
+}
+
+func newGetOptions(options []GetOption) *GetOptions {
+	opts := &GetOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+type PutOption func(opts *PutOptions)
+
+func PutSizeHint(size int64) PutOption {
+	return func(opts *PutOptions) {
+		opts.SizeHint = size
+	}
+}
+
+func PutFsyncAfterWrite() PutOption {
+	return func(opts *PutOptions) {
+		opts.FsyncAfterWrite = true
+	}
+}
+
+func PutVerifySize() PutOption {
+	return func(opts *PutOptions) {
+		opts.VerifySize = true
+	}
+}
+
+func PutVerifyChunks() PutOption {
+	return func(opts *PutOptions) {
+		opts.VerifyChunks = true
+	}
+}
+
+func PutStats(stats *TransferStats) PutOption {
+	return func(opts *PutOptions) {
+		opts.Stats = stats
+	}
+}
+
+func PutMaxObjectSize(size int64) PutOption {
+	return func(opts *PutOptions) {
+		opts.MaxObjectSize = size
+	}
+}
+
+func PutCheckSpace() PutOption {
+	return func(opts *PutOptions) {
+		opts.CheckSpace = true
+	}
+}
+
+func PutChunked() PutOption {
+	return func(opts *PutOptions) {
+		opts.Chunked = true
+	}
+}
+
+func newPutOptions(options []PutOption) *PutOptions {
+	opts := &PutOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
@@ -0,0 +1,265 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/koofr/go-ioutils"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Options", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+	})
+
+	It("should apply put options", func() {
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutSizeHint(5), PutFsyncAfterWrite())).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("12345"))
+		Expect(fake.Requests()).To(Equal([]string{"PUT /root/object", "POST /root/object fsync"}))
+	})
+
+	It("should apply list options", func() {
+		fake.PutFile("/root/b", "1")
+		fake.PutFile("/root/a", "12")
+		fake.PutFile("/root/c", "123")
+
+		entries, err := client.List(ctx, "/root", ListSort(SortBySize, SortDescending))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"c", "a", "b"}))
+	})
+
+	It("should apply get options", func() {
+		fake.PutFile("/root/object", "0123456789")
+
+		rd, _, err := client.GetObject(ctx, "/root/object", &ioutils.FileSpan{Start: 0, End: 9}, GetChunkSize(4))
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(string(data)).To(Equal("0123456789"))
+
+		Expect(fake.Requests()).To(Equal([]string{
+			"GET /root/object stat",
+			"GET /root/object",
+			"GET /root/object",
+			"GET /root/object",
+		}))
+
+		_, _, err = client.GetObject(ctx, "/root/object", nil, GetChunkSize(-1))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should still accept options structs in the deprecated variants", func() {
+		Expect(client.PutObjectWithOptions(ctx, "/root/object", bytes.NewBufferString("12345"), &PutOptions{FsyncAfterWrite: true})).To(Succeed())
+		Expect(client.PutObjectWithOptions(ctx, "/root/other", bytes.NewBufferString("1"), nil)).To(Succeed())
+
+		entries, err := client.ListWithOptions(ctx, "/root", &ListOptions{SortBy: SortBySize, SortOrder: SortDescending})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(Equal([]string{"object", "other"}))
+
+		rd, _, err := client.GetObjectWithOptions(ctx, "/root/object", nil, &GetOptions{ChunkSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(string(data)).To(Equal("12345"))
+
+		Expect(fake.Requests()).To(ContainElement("POST /root/object fsync"))
+	})
+
+	It("should apply stat options", func() {
+		fake.PutFile("/root/object", "12345")
+		client.IdentityResolver = &mapIdentityResolver{
+			users:  map[int32]string{0: "root"},
+			groups: map[int32]string{0: "wheel"},
+		}
+
+		info, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.UserName).To(Equal("root"))
+
+		info, err = client.Stat(ctx, "/root/object", StatSkipIdentity())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.UserName).To(BeEmpty())
+	})
+})
+
+var _ = Describe("PutOptions.SizeHint", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var pool *getCountingBufferPool
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		pool = &getCountingBufferPool{BufferPool: NewBufferPool(4, 1024)}
+
+		var err error
+		client, err = NewTriparClientWithOptions(
+			"http://tripar.example.com",
+			WithBasicAuth("user", "pass"),
+			WithShare("share"),
+			WithBufferPool(pool),
+			WithTransport(fake),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		fake.Mkdir("/root")
+	})
+
+	It("should read small objects into an exactly sized buffer", func() {
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutSizeHint(5))).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("12345"))
+		Expect(pool.Gets()).To(BeZero())
+	})
+
+	It("should put objects larger than the size hint", func() {
+		content := strings.Repeat("0123456789", 300)
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(5))).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+		Expect(pool.Gets()).To(BeNumerically(">", 0))
+	})
+
+	It("should write objects which fit in a chunk with a single PUT", func() {
+		client.UploadChunkSize = 4096
+		content := strings.Repeat("0123456789", 300)
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(3000))).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+		Expect(fake.Requests()).To(Equal([]string{"PUT /root/object"}))
+		Expect(pool.Gets()).To(BeZero())
+	})
+
+	It("should upload objects larger than a chunk in pooled buffers", func() {
+		client.UploadChunkSize = 2048
+		content := strings.Repeat("0123456789", 300)
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(3000))).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+		Expect(fake.Requests()).To(HaveLen(2))
+		Expect(pool.Gets()).To(BeNumerically(">", 0))
+	})
+
+	It("should fail for negative size hints", func() {
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutSizeHint(-1))).To(HaveOccurred())
+		Expect(fake.Requests()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("PutOptions.CheckSpace", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.SetQuota(2000)
+	})
+
+	It("should fail before sending the data of objects which don't fit", func() {
+		content := strings.Repeat("0123456789", 300)
+
+		err := client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(3000), PutCheckSpace())
+		Expect(err).To(MatchError(ErrNoSpace))
+
+		Expect(fake.Requests()).To(Equal([]string{
+			"PUT /root/object",
+			"POST /root/object fallocate",
+			"DELETE /root/object",
+		}))
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should check the size of seekable readers", func() {
+		content := strings.Repeat("0123456789", 300)
+
+		err := client.PutObject(ctx, "/root/object", strings.NewReader(content), PutCheckSpace())
+		Expect(err).To(MatchError(ErrNoSpace))
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should put objects which fit", func() {
+		content := strings.Repeat("0123456789", 150)
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(1500), PutCheckSpace())).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+	})
+
+	It("should skip the check on firmware without fallocate", func() {
+		fake.Unsupport("fallocate")
+		content := strings.Repeat("0123456789", 150)
+
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(content), PutSizeHint(1500), PutCheckSpace())).To(Succeed())
+		Expect(client.PutObject(ctx, "/root/other", bytes.NewBufferString("12345"), PutSizeHint(5), PutCheckSpace())).To(Succeed())
+
+		fallocates := 0
+		for _, req := range fake.Requests() {
+			if strings.HasSuffix(req, " fallocate") {
+				fallocates++
+			}
+		}
+		Expect(fallocates).To(Equal(1))
+	})
+})
+
+// getCountingBufferPool counts the buffers taken from the pool.
+type getCountingBufferPool struct {
+	*BufferPool
+	gets int64
+}
+
+func (p *getCountingBufferPool) Get() []byte {
+	atomic.AddInt64(&p.gets, 1)
+	return p.BufferPool.Get()
+}
+
+func (p *getCountingBufferPool) GetContext(ctx context.Context) ([]byte, error) {
+	atomic.AddInt64(&p.gets, 1)
+	return p.BufferPool.GetContext(ctx)
+}
+
+func (p *getCountingBufferPool) TryGet() ([]byte, bool) {
+	atomic.AddInt64(&p.gets, 1)
+	return p.BufferPool.TryGet()
+}
+
+func (p *getCountingBufferPool) Gets() int64 {
+	return atomic.LoadInt64(&p.gets)
+}
@@ -0,0 +1,164 @@
+package triparclient
+
+import (
+	"errors"
+	"fmt"
+	pathpkg "path"
+	"strings"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid path")
+	ErrNameTooLong = errors.New("file name too long")
+	ErrInvalidName = errors.New("invalid file name")
+)
+
+// DefaultMaxNameLength is the maximum length of a path component in bytes
+// accepted by the appliance's filesystem.
+const DefaultMaxNameLength = 255
+
+// InvalidPathError is returned for paths which are rejected before a request
+// is sent, e.g. paths with empty components like "/a//b" or paths which
+// escape "/" like "/a/../../b". It matches ErrInvalidPath.
+type InvalidPathError struct {
+	Path   string
+	Reason string
+}
+
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("invalid path %s: %s", e.Path, e.Reason)
+}
+
+func (e *InvalidPathError) Is(target error) bool {
+	return target == ErrInvalidPath
+}
+
+// NameTooLongError is returned for paths with a component longer than
+// MaxNameLength. It matches ErrNameTooLong.
+type NameTooLongError struct {
+	Path string
+	Name string
+	Max  int
+}
+
+func (e *NameTooLongError) Error() string {
+	return fmt.Sprintf("name too long in path %s: %d bytes, at most %d allowed", e.Path, len(e.Name), e.Max)
+}
+
+func (e *NameTooLongError) Is(target error) bool {
+	return target == ErrNameTooLong
+}
+
+// InvalidNameError is returned for paths with a component containing a
+// character which the filesystem rejects. It matches ErrInvalidName.
+type InvalidNameError struct {
+	Path string
+	Name string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid name %q in path %s", e.Name, e.Path)
+}
+
+func (e *InvalidNameError) Is(target error) bool {
+	return target == ErrInvalidName
+}
+
+// normalizeWindowsPath converts backslashes to slashes and strips a drive
+// letter, e.g. `C:\data\file.txt` becomes "/data/file.txt".
+func normalizeWindowsPath(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	if len(path) >= 2 && path[1] == ':' && isDriveLetter(path[0]) {
+		path = path[2:]
+	}
+	return path
+}
+
+func isDriveLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// checkPath validates a request path, which already includes the root, and
+// returns it cleaned. Paths of chrooted clients are cleaned and checked for
+// escapes in confine.
+func (tp *TriparClient) checkPath(path string) (string, error) {
+	// the root is validated by Chroot
+	rel := strings.TrimPrefix(strings.TrimPrefix(path, tp.root), "/")
+
+	if tp.NormalizeWindowsPaths {
+		if strings.HasPrefix(rel, "/") {
+			return "", &InvalidPathError{Path: tp.unroot(path), Reason: "UNC paths are not supported"}
+		}
+		for _, name := range strings.Split(rel, "/") {
+			if strings.Contains(name, ":") {
+				return "", &InvalidPathError{Path: tp.unroot(path), Reason: fmt.Sprintf("invalid component %q", name)}
+			}
+		}
+	}
+
+	// a trailing slash is allowed, e.g. for directories
+	if strings.Contains("/"+strings.TrimSuffix(rel, "/"), "//") {
+		return "", &InvalidPathError{Path: tp.unroot(path), Reason: "empty component"}
+	}
+
+	if err := tp.checkNames(path, rel); err != nil {
+		return "", err
+	}
+
+	if tp.root != "" {
+		return path, nil
+	}
+
+	depth := 0
+	for _, name := range strings.Split(rel, "/") {
+		switch name {
+		case "..":
+			depth--
+		case ".", "":
+		default:
+			depth++
+		}
+		if depth < 0 {
+			return "", &InvalidPathError{Path: path, Reason: "escapes /"}
+		}
+	}
+
+	return pathpkg.Clean(path), nil
+}
+
+// checkNames checks the components of rel, which is path without the root.
+func (tp *TriparClient) checkNames(path string, rel string) error {
+	max := tp.MaxNameLength
+	if max == 0 {
+		max = DefaultMaxNameLength
+	}
+
+	for _, name := range strings.Split(rel, "/") {
+		if max > 0 && len(name) > max {
+			return &NameTooLongError{Path: tp.unroot(path), Name: name, Max: max}
+		}
+		if strings.ContainsRune(name, 0) {
+			return &InvalidNameError{Path: tp.unroot(path), Name: name}
+		}
+	}
+
+	return nil
+}
+
+// checkPaths validates and cleans the paths of a request.
+func (tp *TriparClient) checkPaths(req *httpclient.RequestData) (err error) {
+	if req.Path, err = tp.checkPath(req.Path); err != nil {
+		return err
+	}
+
+	if dst := req.Params.Get("destination"); dst != "" {
+		if dst, err = tp.checkPath(dst); err != nil {
+			return err
+		}
+		req.Params.Set("destination", dst)
+	}
+
+	return nil
+}
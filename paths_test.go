@@ -0,0 +1,163 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Paths", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/data/dir")
+	})
+
+	It("should clean paths", func() {
+		Expect(client.PutObject(ctx, "/data/./dir/../file.txt", bytes.NewBufferString("1"))).To(Succeed())
+		Expect(fake.Exists("/data/file.txt")).To(BeTrue())
+
+		entries, err := client.List(ctx, "/data/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entryNames(entries)).To(ConsistOf("dir", "file.txt"))
+
+		Expect(client.MoveObject(ctx, "data/file.txt", "/data/dir/./moved.txt")).To(Succeed())
+		Expect(fake.Exists("/data/dir/moved.txt")).To(BeTrue())
+	})
+
+	It("should reject escapes and empty components", func() {
+		requests := len(fake.Requests())
+
+		_, err := client.Stat(ctx, "/data/../../etc/passwd")
+		Expect(err).To(MatchError(ErrInvalidPath))
+		var pathErr *InvalidPathError
+		Expect(errors.As(err, &pathErr)).To(BeTrue())
+		Expect(pathErr.Path).To(Equal("/data/../../etc/passwd"))
+
+		Expect(client.DeleteObject(ctx, "..")).To(MatchError(ErrInvalidPath))
+		Expect(client.CopyObject(ctx, "/data/dir", "/../dir")).To(MatchError(ErrInvalidPath))
+		Expect(client.PutObject(ctx, "/data//file.txt", bytes.NewBufferString("1"))).To(MatchError(ErrInvalidPath))
+		_, err = client.CreateDirectoriesWithResult(ctx, "a/../../b")
+		Expect(err).To(MatchError(ErrInvalidPath))
+
+		Expect(fake.Requests()).To(HaveLen(requests))
+	})
+
+	It("should match escapes of chrooted clients", func() {
+		jail, err := client.Chroot("/data")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = jail.Stat(ctx, "../secret")
+		Expect(err).To(MatchError(ErrInvalidPath))
+		Expect(err).To(MatchError(ErrPathEscape))
+
+		_, err = jail.Stat(ctx, "dir//x")
+		Expect(err).To(MatchError(ErrInvalidPath))
+	})
+
+	Describe("names", func() {
+		It("should reject long names before sending data", func() {
+			requests := len(fake.Requests())
+
+			long := strings.Repeat("a", DefaultMaxNameLength+1)
+			err := client.PutObject(ctx, "/data/"+long, bytes.NewBufferString("12345"))
+			Expect(err).To(MatchError(ErrNameTooLong))
+			var nameErr *NameTooLongError
+			Expect(errors.As(err, &nameErr)).To(BeTrue())
+			Expect(nameErr.Name).To(Equal(long))
+			Expect(nameErr.Max).To(Equal(DefaultMaxNameLength))
+
+			Expect(client.MoveObject(ctx, "/data/dir", "/data/"+long)).To(MatchError(ErrNameTooLong))
+
+			Expect(fake.Requests()).To(HaveLen(requests))
+
+			Expect(client.PutObject(ctx, "/data/"+long[1:], bytes.NewBufferString("1"))).To(Succeed())
+		})
+
+		It("should use MaxNameLength", func() {
+			client.MaxNameLength = 4
+
+			Expect(client.CreateDirectory(ctx, "/data/abcde")).To(MatchError(ErrNameTooLong))
+			Expect(client.CreateDirectory(ctx, "/data/abcd")).To(Succeed())
+
+			client.MaxNameLength = -1
+
+			Expect(client.CreateDirectory(ctx, "/data/"+strings.Repeat("a", 300))).To(Succeed())
+		})
+
+		It("should reject reserved characters", func() {
+			requests := len(fake.Requests())
+
+			err := client.PutObject(ctx, "/data/a\x00b", bytes.NewBufferString("12345"))
+			Expect(err).To(MatchError(ErrInvalidName))
+			var nameErr *InvalidNameError
+			Expect(errors.As(err, &nameErr)).To(BeTrue())
+			Expect(nameErr.Name).To(Equal("a\x00b"))
+
+			Expect(fake.Requests()).To(HaveLen(requests))
+		})
+	})
+
+	Describe("NormalizeWindowsPaths", func() {
+		BeforeEach(func() {
+			client.NormalizeWindowsPaths = true
+		})
+
+		It("should convert separators and strip drive letters", func() {
+			Expect(client.PutObject(ctx, `C:\data\dir\file.txt`, bytes.NewBufferString("12345"))).To(Succeed())
+			data, ok := fake.File("/data/dir/file.txt")
+			Expect(ok).To(BeTrue())
+			Expect(string(data)).To(Equal("12345"))
+
+			info, err := client.Stat(ctx, `data\dir\file.txt`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.Size).To(Equal(int64(5)))
+
+			Expect(client.CopyObject(ctx, `d:\data\dir\file.txt`, `\data\copy.txt`)).To(Succeed())
+			Expect(fake.Exists("/data/copy.txt")).To(BeTrue())
+		})
+
+		It("should reject paths which can't be converted", func() {
+			requests := len(fake.Requests())
+
+			_, err := client.Stat(ctx, `\\server\share\file.txt`)
+			Expect(err).To(MatchError(ErrInvalidPath))
+
+			_, err = client.Stat(ctx, `C:\data\file.txt:stream`)
+			Expect(err).To(MatchError(ErrInvalidPath))
+			var pathErr *InvalidPathError
+			Expect(errors.As(err, &pathErr)).To(BeTrue())
+			Expect(pathErr.Path).To(Equal("/data/file.txt:stream"))
+
+			Expect(client.CopyObject(ctx, `C:\data\dir`, `C:\data\E:\dir`)).To(MatchError(ErrInvalidPath))
+
+			Expect(fake.Requests()).To(HaveLen(requests))
+		})
+
+		It("should work with chrooted clients", func() {
+			jail, err := client.Chroot(`C:\data`)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jail.PutObject(ctx, `dir\file.txt`, bytes.NewBufferString("1"))).To(Succeed())
+			Expect(fake.Exists("/data/dir/file.txt")).To(BeTrue())
+		})
+
+		It("should keep backslashes if disabled", func() {
+			client.NormalizeWindowsPaths = false
+
+			Expect(client.PutObject(ctx, `data\file.txt`, bytes.NewBufferString("1"))).To(Succeed())
+			Expect(fake.Exists(`/data\file.txt`)).To(BeTrue())
+		})
+	})
+})
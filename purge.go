@@ -0,0 +1,182 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/xerrors"
+)
+
+const DefaultPurgeConcurrency = 8
+
+type PurgeOptions struct {
+	// Concurrency is the number of concurrent requests,
+	// DefaultPurgeConcurrency if 0.
+	Concurrency int
+
+	// ContinueOnError makes Purge delete as much as possible and return all
+	// errors joined, instead of stopping at the first one. Directories whose
+	// contents could not be deleted are kept.
+	ContinueOnError bool
+}
+
+type purger struct {
+	tp   *TriparClient
+	ctx  context.Context
+	root string
+	opts *PurgeOptions
+	sem  chan struct{}
+
+	mx     sync.Mutex
+	errs   []error
+	cancel context.CancelFunc
+}
+
+func (p *purger) acquire() error {
+	select {
+	case p.sem <- struct{}{}:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+func (p *purger) release() {
+	<-p.sem
+}
+
+func (p *purger) fail(err error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if !p.opts.ContinueOnError {
+		if len(p.errs) > 0 {
+			return
+		}
+		p.cancel()
+	}
+	p.errs = append(p.errs, err)
+}
+
+func (p *purger) failed() bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return len(p.errs) > 0 && !p.opts.ContinueOnError
+}
+
+// purgeDir deletes dir's contents. The caller must hold a slot, which is used
+// to list dir. Objects are deleted and subdirectories purged concurrently
+// through the same slots, so at most Concurrency requests are in flight
+// regardless of the shape of the tree. It returns false if some of the
+// contents could not be deleted.
+func (p *purger) purgeDir(dir string) bool {
+	entries, err := p.tp.List(p.ctx, dir)
+	p.release()
+	if err != nil {
+		p.fail(xerrors.Errorf("purge list %s error: %w", dir, err))
+		return false
+	}
+
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+
+	for _, entry := range entries.Entries {
+		if p.failed() {
+			failed.Store(true)
+			break
+		}
+
+		path := joinPath(dir, entry.Name)
+
+		isDir, err := p.tp.entryIsDir(p.ctx, path, entry)
+		if err != nil {
+			p.fail(xerrors.Errorf("purge stat %s error: %w", path, err))
+			failed.Store(true)
+			continue
+		}
+
+		if err := p.acquire(); err != nil {
+			p.fail(err)
+			failed.Store(true)
+			break
+		}
+
+		if isDir {
+			wg.Add(1)
+			goLabeled(p.ctx, "Purge", p.root, func() {
+				defer wg.Done()
+
+				if !p.purgeDir(path) {
+					failed.Store(true)
+					return
+				}
+				if err := p.acquire(); err != nil {
+					p.fail(err)
+					failed.Store(true)
+					return
+				}
+				err := p.tp.DeleteDirectory(p.ctx, path)
+				p.release()
+				if err != nil {
+					p.fail(xerrors.Errorf("purge delete directory %s error: %w", path, err))
+					failed.Store(true)
+					return
+				}
+				jobItemDone(p.ctx, 0)
+			})
+			continue
+		}
+
+		wg.Add(1)
+		goLabeled(p.ctx, "Purge", p.root, func() {
+			defer wg.Done()
+			defer p.release()
+
+			if err := p.tp.deleteObject(p.ctx, path); err != nil {
+				p.fail(xerrors.Errorf("purge delete object %s error: %w", path, err))
+				failed.Store(true)
+				return
+			}
+			jobItemDone(p.ctx, 0)
+		})
+	}
+
+	wg.Wait()
+
+	return !failed.Load()
+}
+
+// Purge deletes the contents of the directory at path, but not the directory
+// itself, with concurrent requests.
+func (tp *TriparClient) Purge(ctx context.Context, path string, opts *PurgeOptions) error {
+	if opts == nil {
+		opts = &PurgeOptions{}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultPurgeConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &purger{
+		tp:     tp,
+		ctx:    ctx,
+		root:   path,
+		opts:   opts,
+		sem:    make(chan struct{}, concurrency),
+		cancel: cancel,
+	}
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	p.purgeDir(path)
+
+	return errors.Join(p.errs...)
+}
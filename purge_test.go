@@ -0,0 +1,115 @@
+package triparclient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Purge", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/a/b")
+		fake.Mkdir("/root/c")
+		for i := 0; i < 20; i++ {
+			fake.PutFile(fmt.Sprintf("/root/file%d", i), "x")
+			fake.PutFile(fmt.Sprintf("/root/a/file%d", i), "x")
+			fake.PutFile(fmt.Sprintf("/root/a/b/file%d", i), "x")
+		}
+		fake.PutFile("/root/c/file", "x")
+	})
+
+	It("should delete the contents of a directory", func() {
+		Expect(client.Purge(ctx, "/root", &PurgeOptions{Concurrency: 3})).To(Succeed())
+
+		Expect(fake.Exists("/root")).To(BeTrue())
+		entries, err := client.List(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(BeEmpty())
+	})
+
+	It("should purge subdirectories concurrently within the concurrency", func() {
+		for i := 0; i < 6; i++ {
+			fake.PutFile(fmt.Sprintf("/root/dir%d/file", i), "x")
+		}
+
+		var inFlight, maxInFlight, listsInFlight, maxListsInFlight int32
+		updateMax := func(maxInFlight *int32, n int32) {
+			for {
+				max := atomic.LoadInt32(maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+					break
+				}
+			}
+		}
+		client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+			updateMax(&maxInFlight, atomic.AddInt32(&inFlight, 1))
+			defer atomic.AddInt32(&inFlight, -1)
+			if r.URL.Query().Get("cmd") == "ls" {
+				updateMax(&maxListsInFlight, atomic.AddInt32(&listsInFlight, 1))
+				defer atomic.AddInt32(&listsInFlight, -1)
+				time.Sleep(20 * time.Millisecond)
+			}
+			return fake.RoundTrip(r)
+		})
+
+		Expect(client.Purge(ctx, "/root", &PurgeOptions{Concurrency: 3})).To(Succeed())
+
+		entries, err := client.List(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(BeEmpty())
+		Expect(atomic.LoadInt32(&maxListsInFlight)).To(BeNumerically(">", 1))
+		Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", 3))
+	})
+
+	Context("with failing deletes", func() {
+		BeforeEach(func() {
+			client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+				if r.Method == "DELETE" && strings.HasSuffix(r.URL.Opaque, "/a/b/file7") {
+					return testResponse(http.StatusOK, "application/json", `{
+						"error_code": 13,
+						"long_message": "Permission denied (error code 13)",
+						"short_message": "Permission denied"
+					}`), nil
+				}
+				return fake.RoundTrip(r)
+			})
+		})
+
+		It("should stop at the first error", func() {
+			err := client.Purge(ctx, "/root", nil)
+			Expect(err).To(MatchError(ErrForbidden))
+			Expect(fake.Exists("/root/a/b/file7")).To(BeTrue())
+		})
+
+		It("should continue on error", func() {
+			err := client.Purge(ctx, "/root", &PurgeOptions{ContinueOnError: true})
+			Expect(err).To(MatchError(ErrForbidden))
+			Expect(err.Error()).To(ContainSubstring("/root/a/b/file7"))
+
+			Expect(fake.Exists("/root/a/b/file7")).To(BeTrue())
+			Expect(fake.Exists("/root/a/b/file8")).To(BeFalse())
+			Expect(fake.Exists("/root/a/file1")).To(BeFalse())
+			Expect(fake.Exists("/root/file1")).To(BeFalse())
+			Expect(fake.Exists("/root/c")).To(BeFalse())
+
+			entries, err := client.List(ctx, "/root")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entryNames(entries)).To(Equal([]string{"a"}))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package triparclient
+
+import (
+	"context"
+	"io"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+type RateLimiterOptions struct {
+	// RequestsPerSecond limits the rate of requests, including retries, 0
+	// means unlimited.
+	RequestsPerSecond float64
+
+	// BytesPerSecond limits the combined bandwidth of request and response
+	// bodies, 0 means unlimited.
+	BytesPerSecond int64
+}
+
+// RateLimiter limits the requests and bandwidth of all operations of the
+// clients which share it, see TriparClient.RateLimiter. Unlike the
+// TransferManager's bandwidth limit it applies to every request, e.g. also to
+// listings and metadata requests.
+type RateLimiter struct {
+	requests *rateLimiter
+	bytes    *rateLimiter
+}
+
+func NewRateLimiter(opts RateLimiterOptions) *RateLimiter {
+	l := &RateLimiter{}
+	if opts.RequestsPerSecond > 0 {
+		l.requests = newRateLimiter(opts.RequestsPerSecond)
+	}
+	if opts.BytesPerSecond > 0 {
+		l.bytes = newRateLimiter(float64(opts.BytesPerSecond))
+	}
+	return l
+}
+
+// rateLimitRequest waits until the RateLimiter admits a request and throttles
+// its body. The response body must be throttled with rateLimitResponse.
+func (tp *TriparClient) rateLimitRequest(ctx context.Context, req *httpclient.RequestData) error {
+	l := tp.RateLimiter
+	if l == nil {
+		return nil
+	}
+
+	if l.requests != nil {
+		if err := l.requests.wait(ctx, 1); err != nil {
+			return err
+		}
+	}
+
+	if l.bytes != nil && req.ReqReader != nil {
+		req.ReqReader = newThrottledReader(ctx, req.ReqReader, l.bytes)
+	}
+
+	return nil
+}
+
+// rateLimitResponse throttles reading a response body.
+func (tp *TriparClient) rateLimitResponse(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	l := tp.RateLimiter
+	if l == nil || l.bytes == nil {
+		return body
+	}
+
+	return &throttledReadCloser{
+		Reader: newThrottledReader(ctx, body, l.bytes),
+		Closer: body,
+	}
+}
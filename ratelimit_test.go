@@ -0,0 +1,89 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	data := strings.Repeat("0123456789", 300)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", data)
+	})
+
+	It("should limit the request rate", func() {
+		client.RateLimiter = NewRateLimiter(RateLimiterOptions{RequestsPerSecond: 100})
+
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			_, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("should limit the bandwidth of downloads", func() {
+		client.RateLimiter = NewRateLimiter(RateLimiterOptions{BytesPerSecond: 20000})
+
+		start := time.Now()
+		rd, _, err := client.GetObject(ctx, "/root/object", nil)
+		Expect(err).NotTo(HaveOccurred())
+		read, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(string(read)).To(Equal(data))
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+
+	It("should limit the bandwidth of uploads", func() {
+		client.RateLimiter = NewRateLimiter(RateLimiterOptions{BytesPerSecond: 20000})
+
+		start := time.Now()
+		Expect(client.PutObject(ctx, "/root/uploaded", strings.NewReader(data))).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+
+		written, _ := fake.File("/root/uploaded")
+		Expect(string(written)).To(Equal(data))
+	})
+
+	It("should be shared by clients", func() {
+		limiter := NewRateLimiter(RateLimiterOptions{RequestsPerSecond: 100})
+		client.RateLimiter = limiter
+		other := newTestClient(fake)
+		other.RateLimiter = limiter
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			_, err := client.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = other.Stat(ctx, "/root/object")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("should fail when the context is canceled while waiting", func() {
+		client.RateLimiter = NewRateLimiter(RateLimiterOptions{RequestsPerSecond: 1})
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
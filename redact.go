@@ -0,0 +1,143 @@
+package triparclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+const redacted = "xxxxx"
+
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+var sensitiveParams = []string{
+	"auth",
+	"key",
+	"pass",
+	"secret",
+	"signature",
+	"token",
+}
+
+// redactHeader returns a copy of header with credential values replaced.
+// Headers must go through it before they are included in errors or debug
+// output.
+func redactHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+
+	header = header.Clone()
+	for _, key := range sensitiveHeaders {
+		if values, ok := header[key]; ok {
+			header[key] = make([]string, len(values))
+			for i := range values {
+				header[key][i] = redacted
+			}
+		}
+	}
+
+	return header
+}
+
+// redactURL removes the password and values of query parameters which look
+// like secrets from rawURL.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// don't risk leaking anything we can't parse
+		return redacted
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			lower := strings.ToLower(key)
+			for _, param := range sensitiveParams {
+				if strings.Contains(lower, param) {
+					query.Set(key, redacted)
+					break
+				}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// redactError scrubs credentials from errors returned by httpclient, which
+// include the request URL and the response headers. An error which wraps
+// one of them, e.g. one returned by an error handler, is replaced by an error
+// with the scrubbed message which only wraps the scrubbed error.
+//
+// It does not cover the request dump httpclient prints when HTTPCLIENT_TRACE
+// is set, which includes the Authorization header.
+func redactError(err error) error {
+	switch e := err.(type) {
+	case *url.Error:
+		return &url.Error{
+			Op:  e.Op,
+			URL: redactURL(e.URL),
+			Err: e.Err,
+		}
+	case httpclient.InvalidStatusError:
+		e.Headers = redactHeader(e.Headers)
+		return e
+	case *httpclient.InvalidStatusError:
+		c := *e
+		c.Headers = redactHeader(e.Headers)
+		return &c
+	}
+
+	var inner error
+	var ue *url.Error
+	var ise httpclient.InvalidStatusError
+	var iseptr *httpclient.InvalidStatusError
+	switch {
+	case errors.As(err, &ue):
+		inner = ue
+	case errors.As(err, &ise):
+		inner = ise
+	case errors.As(err, &iseptr):
+		inner = iseptr
+	default:
+		return err
+	}
+
+	scrubbed := redactError(inner)
+	msg := strings.ReplaceAll(err.Error(), inner.Error(), scrubbed.Error())
+	if ue != nil && ue.URL != "" {
+		// in case the wrapper formats the URL itself
+		msg = strings.ReplaceAll(msg, ue.URL, redactURL(ue.URL))
+	}
+
+	return &redactedError{msg: msg, err: scrubbed}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,35 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/xerrors"
+)
+
+type RenameOptions struct {
+	// Overwrite allows replacing an existing destination object.
+	Overwrite bool
+}
+
+// Rename moves src to dst like MoveObject, but fails with ErrAlreadyExists
+// if dst exists unless opts.Overwrite is set. The appliance has no atomic
+// no-clobber move, so a destination created concurrently between the check
+// and the move is still overwritten.
+func (tp *TriparClient) Rename(ctx context.Context, src string, dst string, opts *RenameOptions) error {
+	if opts == nil {
+		opts = &RenameOptions{}
+	}
+
+	if !opts.Overwrite {
+		_, err := tp.Stat(ctx, dst, StatSkipIdentity())
+		if err == nil {
+			return xerrors.Errorf("rename destination %s: %w", dst, ErrAlreadyExists)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return xerrors.Errorf("rename destination stat error: %w", err)
+		}
+	}
+
+	return tp.MoveObject(ctx, src, dst)
+}
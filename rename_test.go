@@ -0,0 +1,49 @@
+package triparclient_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Rename", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/src", "src")
+		fake.PutFile("/root/dst", "dst")
+	})
+
+	It("should rename", func() {
+		Expect(client.Rename(ctx, "/root/src", "/root/new", nil)).To(Succeed())
+		Expect(fake.Exists("/root/src")).To(BeFalse())
+		data, _ := fake.File("/root/new")
+		Expect(string(data)).To(Equal("src"))
+	})
+
+	It("should not overwrite by default", func() {
+		Expect(client.Rename(ctx, "/root/src", "/root/dst", nil)).To(MatchError(ErrAlreadyExists))
+		Expect(fake.Exists("/root/src")).To(BeTrue())
+		data, _ := fake.File("/root/dst")
+		Expect(string(data)).To(Equal("dst"))
+	})
+
+	It("should overwrite if enabled", func() {
+		Expect(client.Rename(ctx, "/root/src", "/root/dst", &RenameOptions{Overwrite: true})).To(Succeed())
+		data, _ := fake.File("/root/dst")
+		Expect(string(data)).To(Equal("src"))
+	})
+
+	It("should fail if the source does not exist", func() {
+		Expect(client.Rename(ctx, "/root/missing", "/root/new", nil)).To(MatchError(ErrNotFound))
+	})
+})
@@ -0,0 +1,61 @@
+package triparclient
+
+import (
+	"net/http"
+)
+
+// minRequestSize is the smallest limit learned from rejected writes.
+const minRequestSize = 64 * 1024
+
+// requestSizeLimit returns the maximum body size of a write request, the
+// smaller of MaxRequestSize and the limit learned from rejected writes, or 0
+// if there is none.
+func (tp *TriparClient) requestSizeLimit() int64 {
+	limit := tp.MaxRequestSize
+	if learned := tp.learnedRequestSize.Load(); learned > 0 && (limit <= 0 || learned < limit) {
+		limit = learned
+	}
+	return limit
+}
+
+// limitRequestSize returns size capped to the request size limit.
+func (tp *TriparClient) limitRequestSize(size int64) int64 {
+	if limit := tp.requestSizeLimit(); limit > 0 && size > limit {
+		return limit
+	}
+	return size
+}
+
+// isRequestTooLarge reports whether a write was rejected because of its size.
+func isRequestTooLarge(err error) bool {
+	ise, ok := asInvalidStatusError(err)
+	return ok && ise.Got == http.StatusRequestEntityTooLarge
+}
+
+// lowerRequestSize halves the learned request size limit after a write of
+// size bytes was rejected. It returns false if the limit can't be lowered any
+// further, in which case the write should fail.
+func (tp *TriparClient) lowerRequestSize(size int64) bool {
+	limit := size / 2
+	if limit < minRequestSize {
+		return false
+	}
+
+	for {
+		learned := tp.learnedRequestSize.Load()
+		if learned > 0 && learned <= limit {
+			// lowered concurrently
+			return true
+		}
+		if tp.learnedRequestSize.CompareAndSwap(learned, limit) {
+			return true
+		}
+	}
+}
+
+// RequestSizeLimit returns the maximum body size of write requests, which
+// is MaxRequestSize or lower if the appliance rejected larger writes, or 0 if
+// writes are not limited.
+func (tp *TriparClient) RequestSizeLimit() int64 {
+	return tp.requestSizeLimit()
+}
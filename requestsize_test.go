@@ -0,0 +1,100 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("MaxRequestSize", func() {
+	var ctx context.Context
+	var fake *fakeTripar
+	var mx sync.Mutex
+	var sizes []int64
+	var serverLimit int64
+
+	transport := funcTransport(func(r *http.Request) (*http.Response, error) {
+		if (r.Method == "PUT" || r.Method == "POST") && r.URL.Query().Get("cmd") == "" {
+			if serverLimit > 0 && r.ContentLength > serverLimit {
+				return testResponse(http.StatusRequestEntityTooLarge, "text/html", "<h1>413 Request Entity Too Large</h1>"), nil
+			}
+			mx.Lock()
+			sizes = append(sizes, r.ContentLength)
+			mx.Unlock()
+		}
+		return fake.RoundTrip(r)
+	})
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		sizes = nil
+		serverLimit = 0
+	})
+
+	It("should split pieces into requests of MaxRequestSize", func() {
+		client := newTestClient(transport)
+		client.MaxRequestSize = 1000
+
+		data := strings.Repeat("x", 2500)
+		Expect(client.PutObject(ctx, "/root/object", io.MultiReader(strings.NewReader(data)))).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(sizes).To(Equal([]int64{1000, 24, 1000, 24, 452}))
+		Expect(client.RequestSizeLimit()).To(Equal(int64(1000)))
+	})
+
+	It("should split seekable readers into requests of MaxRequestSize", func() {
+		client := newTestClient(transport)
+		client.MaxRequestSize = 1000
+
+		data := strings.Repeat("x", 2500)
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(sizes).To(Equal([]int64{1000, 1000, 500}))
+	})
+
+	It("should lower the limit if the appliance rejects large writes", func() {
+		client, err := NewTriparClient("http://tripar.example.com", "user", "pass", "share", NewBufferPool(4, 256*1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: transport}
+		serverLimit = 100 * 1024
+
+		data := strings.Repeat("0123456789", 60*1024)
+		Expect(client.PutObject(ctx, "/root/object", io.MultiReader(strings.NewReader(data)))).To(Succeed())
+
+		written, _ := fake.File("/root/object")
+		Expect(string(written)).To(Equal(data))
+		Expect(client.RequestSizeLimit()).To(Equal(int64(64 * 1024)))
+		for _, size := range sizes {
+			Expect(size).To(BeNumerically("<=", 64*1024))
+		}
+
+		// the limit is kept for later uploads
+		sizes = nil
+		Expect(client.PutObject(ctx, "/root/object2", strings.NewReader(data))).To(Succeed())
+		Expect(sizes[0]).To(Equal(int64(64 * 1024)))
+	})
+
+	It("should fail if the limit can't be lowered", func() {
+		client := newTestClient(transport)
+		serverLimit = 100
+
+		err := client.PutObject(ctx, "/root/object", strings.NewReader(strings.Repeat("x", 2500)))
+		Expect(err).To(HaveOccurred())
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+		Expect(client.RequestSizeLimit()).To(Equal(int64(0)))
+	})
+})
@@ -0,0 +1,301 @@
+package triparclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// DefaultResumableStateSuffix is appended to the local path to get the state
+// file of DownloadResumable if the options don't set one.
+const DefaultResumableStateSuffix = ".tripar-download"
+
+type ResumableDownloadOptions struct {
+	// StatePath is the file which records the progress of the download,
+	// DefaultResumableStateSuffix appended to the local path by default.
+	StatePath string
+
+	// ChunkSize is the size of the ranges which are downloaded and recorded,
+	// the client's chunk size by default.
+	ChunkSize int64
+
+	// Concurrency is the number of ranges downloaded concurrently,
+	// DefaultParallelChunks by default.
+	Concurrency int
+}
+
+// downloadState is the content of the state file of DownloadResumable.
+type downloadState struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	ModTime   int64  `json:"mtime"`
+	ChunkSize int64  `json:"chunkSize"`
+
+	// LocalModTime is the modification time of the local file when the state
+	// was saved, in nanoseconds.
+	LocalModTime int64 `json:"localMtime"`
+
+	// Completed are the indexes of the chunks which were written and synced
+	// to the local file.
+	Completed []int64 `json:"completed"`
+}
+
+// DownloadResumable downloads the object at path to the local file at
+// localPath. Completed ranges are recorded in a state file, so if the
+// download fails or the process is restarted, calling DownloadResumable
+// again only downloads the missing ranges into the partially written file.
+// If the object's size or modification time changed since the state was
+// recorded, or the local file is missing, has a different size or is older
+// than the state, the download starts over. The state file is deleted once the
+// download is complete.
+func (tp *TriparClient) DownloadResumable(
+	ctx context.Context,
+	path string,
+	localPath string,
+	opts *ResumableDownloadOptions,
+) (n int64, err error) {
+	defer tp.observe(ctx, "DownloadResumable", path, time.Now(), &err)
+
+	if opts == nil {
+		opts = &ResumableDownloadOptions{}
+	}
+	statePath := opts.StatePath
+	if statePath == "" {
+		statePath = localPath + DefaultResumableStateSuffix
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = tp.getChunkSize
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultParallelChunks
+	}
+
+	info, err := tp.Stat(ctx, path)
+	if err != nil {
+		return 0, xerrors.Errorf("download resumable stat error: %w", err)
+	}
+	if info.IsDir() {
+		return 0, xerrors.Errorf("download resumable %s: %w", path, ErrNotAFile)
+	}
+
+	state := &downloadState{
+		Path:      path,
+		Size:      info.Status.Size,
+		ModTime:   info.Status.ModTime().UnixNano(),
+		ChunkSize: chunkSize,
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	previous, err := loadDownloadState(statePath)
+	if err != nil {
+		return 0, xerrors.Errorf("download resumable state load error: %w", err)
+	}
+	if previous != nil && previous.Path == state.Path && previous.Size == state.Size &&
+		previous.ModTime == state.ModTime && previous.ChunkSize == state.ChunkSize &&
+		localFileMatches(localPath, previous) {
+		state.Completed = previous.Completed
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(localPath, flags, 0o644)
+	if err != nil {
+		return 0, xerrors.Errorf("download resumable open error: %w", err)
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil && cerr != nil {
+			err = xerrors.Errorf("download resumable close error: %w", cerr)
+		}
+	}()
+
+	if err := file.Truncate(state.Size); err != nil {
+		return 0, xerrors.Errorf("download resumable truncate error: %w", err)
+	}
+	if err := state.setLocalModTime(file); err != nil {
+		return 0, xerrors.Errorf("download resumable stat error: %w", err)
+	}
+	if err := state.save(statePath); err != nil {
+		return 0, xerrors.Errorf("download resumable state save error: %w", err)
+	}
+
+	if err := tp.downloadMissingChunks(ctx, path, file, state, statePath, concurrency); err != nil {
+		return 0, err
+	}
+
+	if err := file.Sync(); err != nil {
+		return 0, xerrors.Errorf("download resumable sync error: %w", err)
+	}
+	if err := os.Remove(statePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, xerrors.Errorf("download resumable state remove error: %w", err)
+	}
+
+	return state.Size, nil
+}
+
+// downloadMissingChunks downloads the chunks which are not completed in
+// state and records them in the state file.
+func (tp *TriparClient) downloadMissingChunks(
+	ctx context.Context,
+	path string,
+	file *os.File,
+	state *downloadState,
+	statePath string,
+	concurrency int,
+) error {
+	completed := map[int64]bool{}
+	for _, index := range state.Completed {
+		completed[index] = true
+	}
+
+	ctx, cancel := context.WithCancel(tp.beginTransfer(ctx))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// record syncs the written chunk before it is recorded, so that a
+	// recorded chunk is never missing from the file after a crash
+	record := func(index int64) error {
+		mx.Lock()
+		defer mx.Unlock()
+
+		if err := file.Sync(); err != nil {
+			return xerrors.Errorf("download resumable sync error: %w", err)
+		}
+		if err := state.setLocalModTime(file); err != nil {
+			return xerrors.Errorf("download resumable stat error: %w", err)
+		}
+		state.Completed = append(state.Completed, index)
+		sort.Slice(state.Completed, func(i, j int) bool { return state.Completed[i] < state.Completed[j] })
+		if err := state.save(statePath); err != nil {
+			return xerrors.Errorf("download resumable state save error: %w", err)
+		}
+		return nil
+	}
+
+	sem := make(chan struct{}, concurrency)
+	for index := int64(0); index*state.ChunkSize < state.Size; index++ {
+		if completed[index] {
+			continue
+		}
+
+		start := index * state.ChunkSize
+		end := start + state.ChunkSize - 1
+		if end >= state.Size {
+			end = state.Size - 1
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		index := index
+		wg.Add(1)
+		goLabeled(ctx, "DownloadResumable", path, func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := tp.writeRangeTo(ctx, "DownloadResumable", path, file, start, start, end); err != nil {
+				fail(err)
+				return
+			}
+			if err := record(index); err != nil {
+				fail(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return xerrors.Errorf("download resumable error: %w", firstErr)
+	}
+
+	return ctx.Err()
+}
+
+// loadDownloadState returns the state recorded at path, or nil if there is
+// none or it can't be parsed, e.g. after a crash while it was written.
+func loadDownloadState(path string) (*downloadState, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &downloadState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, nil
+	}
+	return state, nil
+}
+
+// localFileMatches returns true if the local file at path can be resumed with
+// state, i.e. it exists with the object's size and was not modified before the
+// state was saved. The check is coarse, as chunks which were in flight when the
+// state was saved may have modified the file afterwards.
+func localFileMatches(path string, state *downloadState) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Size() == state.Size && info.ModTime().UnixNano() >= state.LocalModTime
+}
+
+func (s *downloadState) setLocalModTime(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	s.LocalModTime = info.ModTime().UnixNano()
+	return nil
+}
+
+// save replaces the state file at path, so that it is never partially
+// written.
+func (s *downloadState) save(path string) error {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
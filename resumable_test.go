@@ -0,0 +1,174 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("DownloadResumable", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failRange atomic.Value
+	var mx sync.Mutex
+	var ranges []string
+	var localPath string
+
+	data := strings.Repeat("0123456789", 300)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", data)
+
+		failRange.Store("")
+		mx.Lock()
+		ranges = nil
+		mx.Unlock()
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if rng := r.Header.Get("Range"); rng != "" {
+				if rng == failRange.Load().(string) {
+					return testResponse(http.StatusForbidden, "text/plain", "error"), nil
+				}
+				mx.Lock()
+				ranges = append(ranges, rng)
+				mx.Unlock()
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		dir, err := os.MkdirTemp("", "triparclient-resumable")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		localPath = filepath.Join(dir, "object")
+	})
+
+	downloaded := func() []string {
+		mx.Lock()
+		defer mx.Unlock()
+		return append([]string{}, ranges...)
+	}
+
+	It("should download the object", func() {
+		n, err := client.DownloadResumable(ctx, "/root/object", localPath, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(len(data))))
+
+		content, err := os.ReadFile(localPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(data))
+		Expect(localPath + DefaultResumableStateSuffix).NotTo(BeAnExistingFile())
+		Expect(downloaded()).To(ConsistOf("bytes=0-1023", "bytes=1024-2047", "bytes=2048-2999"))
+	})
+
+	It("should only download missing ranges when resumed", func() {
+		failRange.Store("bytes=1024-2047")
+		opts := &ResumableDownloadOptions{Concurrency: 1}
+
+		_, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(localPath + DefaultResumableStateSuffix).To(BeAnExistingFile())
+
+		failRange.Store("")
+		before := len(downloaded())
+
+		n, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(len(data))))
+		Expect(downloaded()[before:]).NotTo(ContainElement("bytes=0-1023"))
+		Expect(downloaded()[before:]).To(ContainElement("bytes=1024-2047"))
+
+		content, err := os.ReadFile(localPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(data))
+		Expect(localPath + DefaultResumableStateSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("should start over if the local file was deleted or truncated", func() {
+		failRange.Store("bytes=1024-2047")
+		opts := &ResumableDownloadOptions{Concurrency: 1}
+
+		_, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(os.Truncate(localPath, 1024)).To(Succeed())
+
+		failRange.Store("")
+		before := len(downloaded())
+
+		_, err = client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloaded()[before:]).To(Equal([]string{"bytes=0-1023", "bytes=1024-2047", "bytes=2048-2999"}))
+
+		failRange.Store("bytes=1024-2047")
+		_, err = client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(os.Remove(localPath)).To(Succeed())
+
+		failRange.Store("")
+		before = len(downloaded())
+
+		_, err = client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloaded()[before:]).To(Equal([]string{"bytes=0-1023", "bytes=1024-2047", "bytes=2048-2999"}))
+
+		content, err := os.ReadFile(localPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(data))
+	})
+
+	It("should start over if the local file is older than the state", func() {
+		failRange.Store("bytes=1024-2047")
+		opts := &ResumableDownloadOptions{Concurrency: 1}
+
+		_, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).To(HaveOccurred())
+		old := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(localPath, old, old)).To(Succeed())
+
+		failRange.Store("")
+		before := len(downloaded())
+
+		_, err = client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloaded()[before:]).To(ContainElement("bytes=0-1023"))
+
+		content, err := os.ReadFile(localPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(data))
+	})
+
+	It("should start over if the object changed", func() {
+		failRange.Store("bytes=1024-2047")
+		opts := &ResumableDownloadOptions{Concurrency: 1}
+
+		_, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).To(HaveOccurred())
+
+		changed := strings.Repeat("abcdefghij", 250)
+		fake.PutFile("/root/object", changed)
+		failRange.Store("")
+		before := len(downloaded())
+
+		n, err := client.DownloadResumable(ctx, "/root/object", localPath, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(len(changed))))
+		Expect(downloaded()[before:]).To(Equal([]string{"bytes=0-1023", "bytes=1024-2047", "bytes=2048-2499"}))
+
+		content, err := os.ReadFile(localPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(changed))
+	})
+})
@@ -0,0 +1,402 @@
+package triparclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	// MaxAttempts and Backoff apply to errors of classes which are not in
+	// Classes.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, see Multiplier.
+	Backoff time.Duration
+
+	// Multiplier makes the backoff exponential, the backoff before the n-th
+	// retry is Backoff * Multiplier^(n-1). Values up to 1 keep the backoff
+	// constant.
+	Multiplier float64
+
+	// MaxBackoff caps the backoff, 0 means uncapped.
+	MaxBackoff time.Duration
+
+	// Jitter randomly shortens each backoff by up to this fraction, e.g. 0.5
+	// waits between half and all of the backoff, so that clients which
+	// failed at the same time don't retry in lockstep.
+	Jitter float64
+
+	// RetryableStatuses replaces the statuses which are retried, by default
+	// 503 and other 5xx responses except 501 and 507, which are reported as
+	// ErrNotSupported and ErrNoSpace. 429 and 503 are of ErrorClassThrottled,
+	// other statuses of ErrorClassServer. Listing 429 retries it even
+	// without ErrorClassThrottled in Classes.
+	RetryableStatuses []int
+
+	// RetryableError decides whether errors without a response are retried,
+	// e.g. to only retry connection resets. By default all of them are,
+	// except context cancellation.
+	RetryableError func(err error) bool
+
+	// RetryNonIdempotent enables retrying requests which are not idempotent
+	// (PUT and POST data writes, mv, mkdir without parents). Enable it only if
+	// preconditions make repeating them safe, e.g. when a single writer owns
+	// the paths. Appends of PutObject are retried regardless, as the object
+	// is stat'ed first to resend only the bytes which were not persisted.
+	RetryNonIdempotent bool
+
+	// Budget limits retries across all requests sharing the policy, so that
+	// retries don't multiply the load on an appliance which is failing.
+	Budget *RetryBudget
+
+	// Classes overrides MaxAttempts and Backoff for errors of a class, e.g.
+	// to keep retrying while the appliance is throttling. 429 Too Many
+	// Requests responses are only retried if ErrorClassThrottled is set.
+	Classes map[ErrorClass]RetryClassPolicy
+
+	// MaxDuration is the time budget for retrying, 0 means unlimited. No
+	// retry is made whose backoff would end after the budget is spent. The
+	// budget starts with the first attempt of a request, or for a policy set
+	// with WithRetryPolicy, when the context was created, so it covers all
+	// requests of the operations using the context.
+	MaxDuration time.Duration
+}
+
+type RetryClassPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request whose last
+	// attempt failed with an error of the class.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, it grows with the
+	// policy's Multiplier.
+	Backoff time.Duration
+}
+
+// ErrorClass is the kind of a retryable error, see RetryPolicy.Classes.
+type ErrorClass int
+
+const (
+	// ErrorClassNetwork are errors without a response, e.g. connection
+	// failures and timeouts.
+	ErrorClassNetwork ErrorClass = iota
+
+	// ErrorClassThrottled are 429 Too Many Requests and 503 Service
+	// Unavailable responses.
+	ErrorClassThrottled
+
+	// ErrorClassServer are other 5xx responses.
+	ErrorClassServer
+)
+
+type retryPolicyContext struct {
+	policy *RetryPolicy
+	start  time.Time
+}
+
+// WithRetryPolicy returns a context whose requests are retried with policy
+// instead of the client's RetryPolicy, e.g. a short MaxDuration for
+// interactive requests and generous Classes for background jobs. A nil policy
+// disables retries. The policy's MaxDuration starts now.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyContextKey, retryPolicyContext{
+		policy: policy,
+		start:  time.Now(),
+	})
+}
+
+// retryPolicy returns the policy for requests with ctx and the start of its
+// time budget, which is zero if the budget starts with the request.
+func (tp *TriparClient) retryPolicy(ctx context.Context) (*RetryPolicy, time.Time) {
+	if ctx != nil {
+		if rpc, ok := ctx.Value(retryPolicyContextKey).(retryPolicyContext); ok {
+			return rpc.policy, rpc.start
+		}
+	}
+	return tp.RetryPolicy, time.Time{}
+}
+
+// RetryBudget is a token bucket. Every retry takes a token and every
+// successful request adds Ratio tokens, up to Max. Retries are suppressed
+// while the bucket is empty.
+type RetryBudget struct {
+	mx     sync.Mutex
+	max    float64
+	ratio  float64
+	tokens float64
+}
+
+// NewRetryBudget returns a full budget of max retries which is replenished
+// by ratio retries per successful request, e.g. 0.1 allows one retry per ten
+// successes once the initial budget is used up.
+func NewRetryBudget(max int, ratio float64) *RetryBudget {
+	return &RetryBudget{
+		max:    float64(max),
+		ratio:  ratio,
+		tokens: float64(max),
+	}
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *RetryBudget) deposit() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func isIdempotent(req *httpclient.RequestData) bool {
+	cmd := req.Params.Get("cmd")
+
+	switch req.Method {
+	case "GET", "HEAD", "DELETE":
+		return true
+	case "PUT":
+		return cmd == "cp" || (cmd == "mkdir" && req.Params.Get("parents") == "true")
+	case "POST":
+		return cmd == "fsync"
+	default:
+		return false
+	}
+}
+
+// classifyError returns the class of err, or false if err can't be retried
+// with policy.
+func classifyError(policy *RetryPolicy, err error) (ErrorClass, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return 0, false
+	}
+
+	if ise, ok := asInvalidStatusError(err); ok {
+		if perr, jsonErr := UnmarshalError([]byte(ise.Content)); jsonErr == nil && perr != nil {
+			return 0, false
+		}
+		if policy.RetryableStatuses != nil && !policy.retryableStatus(ise.Got) {
+			return 0, false
+		}
+		switch {
+		case (ise.Got == http.StatusNotImplemented || ise.Got == http.StatusInsufficientStorage) && policy.RetryableStatuses == nil:
+			// ErrNotSupported and ErrNoSpace don't go away by retrying
+			return 0, false
+		case ise.Got == http.StatusTooManyRequests || ise.Got == http.StatusServiceUnavailable:
+			return ErrorClassThrottled, true
+		case ise.Got >= http.StatusInternalServerError || policy.RetryableStatuses != nil:
+			return ErrorClassServer, true
+		default:
+			return 0, false
+		}
+	}
+
+	if policy.RetryableError != nil && !policy.RetryableError(err) {
+		return 0, false
+	}
+
+	return ErrorClassNetwork, true
+}
+
+func (p *RetryPolicy) retryableStatus(status int) bool {
+	for _, retryable := range p.RetryableStatuses {
+		if retryable == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the backoff before the retry following the attempt'th
+// attempt, starting from base.
+func (p *RetryPolicy) backoff(base time.Duration, attempt int) time.Duration {
+	backoff := float64(base)
+	if p.Multiplier > 1 {
+		backoff *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+func (tp *TriparClient) shouldRetry(req *httpclient.RequestData, first time.Time, event RetryEvent) (backoff time.Duration, ok bool) {
+	policy, _ := tp.retryPolicy(req.Context)
+	if policy == nil {
+		return 0, false
+	}
+
+	if !policy.RetryNonIdempotent && !isIdempotent(req) {
+		return 0, false
+	}
+
+	if req.ReqReader != nil {
+		if _, ok := req.ReqReader.(io.Seeker); !ok {
+			return 0, false
+		}
+	}
+
+	return tp.takeRetry(req.Context, first, event)
+}
+
+// takeRetry checks whether a request whose event.Attempt'th attempt failed
+// with event.Err can be retried and accounts for the retry. first is the time
+// of the first attempt. It returns the backoff before the retry. A retry
+// suppressed by the budget is reported to the OnRetry hook.
+func (tp *TriparClient) takeRetry(ctx context.Context, first time.Time, event RetryEvent) (backoff time.Duration, ok bool) {
+	attempt, err := event.Attempt, event.Err
+	policy, start := tp.retryPolicy(ctx)
+	if policy == nil {
+		return 0, false
+	}
+
+	class, ok := classifyError(policy, err)
+	if !ok {
+		return 0, false
+	}
+
+	maxAttempts, backoff := policy.MaxAttempts, policy.Backoff
+	if classPolicy, ok := policy.Classes[class]; ok {
+		maxAttempts, backoff = classPolicy.MaxAttempts, classPolicy.Backoff
+	} else if ise, ok := asInvalidStatusError(err); ok && ise.Got == http.StatusTooManyRequests && !policy.retryableStatus(ise.Got) {
+		return 0, false
+	}
+	if attempt >= maxAttempts {
+		return 0, false
+	}
+	backoff = policy.backoff(backoff, attempt)
+
+	if policy.MaxDuration > 0 {
+		if start.IsZero() {
+			start = first
+		}
+		if time.Since(start)+backoff >= policy.MaxDuration {
+			return 0, false
+		}
+	}
+
+	if policy.Budget != nil && !policy.Budget.withdraw() {
+		atomic.AddInt64(&tp.stats.retriesSuppressed, 1)
+		event.Suppressed = true
+		tp.hookRetry(ctx, event)
+		return 0, false
+	}
+
+	atomic.AddInt64(&tp.stats.retries, 1)
+	if counter := transferCounterFrom(ctx); counter != nil {
+		atomic.AddInt64(&counter.retries, 1)
+	}
+
+	return backoff, true
+}
+
+func (tp *TriparClient) retrySucceeded(ctx context.Context) {
+	if policy, _ := tp.retryPolicy(ctx); policy != nil && policy.Budget != nil {
+		policy.Budget.deposit()
+	}
+}
+
+func (tp *TriparClient) retryWait(ctx context.Context, req *httpclient.RequestData, backoff time.Duration) error {
+	if seeker, ok := req.ReqReader.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	return retryBackoff(ctx, backoff)
+}
+
+func retryBackoff(ctx context.Context, backoff time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if backoff <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// appendRange writes size bytes at offset into an existing object. The bytes
+// are read from body(skip), which returns the bytes following the first skip
+// bytes. A failed write may have been applied partially or completely, so
+// before it is retried the object is stat'ed and only the bytes which were not
+// persisted are sent again. This makes it safe to retry appends even if
+// RetryNonIdempotent is not set.
+func (tp *TriparClient) appendRange(
+	ctx context.Context,
+	path string,
+	offset int64,
+	size int64,
+	body func(skip int64) io.Reader,
+) error {
+	skip := int64(0)
+	first := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := tp.writeRange(ctx, path, offset+skip, body(skip), size-skip, false)
+		if err == nil {
+			return nil
+		}
+
+		event := RetryEvent{
+			Method:  "POST",
+			Path:    path,
+			Attempt: attempt,
+			Err:     err,
+		}
+		backoff, ok := tp.takeRetry(ctx, first, event)
+		if !ok {
+			return err
+		}
+		event.Backoff = backoff
+		tp.hookRetry(ctx, event)
+		if waitErr := retryBackoff(ctx, backoff); waitErr != nil {
+			return err
+		}
+
+		info, statErr := tp.Stat(ctx, path, StatSkipIdentity())
+		if statErr != nil {
+			return err
+		}
+		persisted := info.Status.Size - offset
+		if persisted < 0 || persisted > size {
+			// the object was changed by someone else
+			return err
+		}
+		if persisted == size {
+			return nil
+		}
+		skip = persisted
+	}
+}
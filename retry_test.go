@@ -0,0 +1,504 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failures int32
+	var requests int32
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+
+		atomic.StoreInt32(&failures, 0)
+		atomic.StoreInt32(&requests, 0)
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				return testResponse(http.StatusServiceUnavailable, "text/plain", "unavailable"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 3,
+		}
+	})
+
+	It("should retry idempotent requests", func() {
+		atomic.StoreInt32(&failures, 2)
+
+		info, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(5)))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should give up after max attempts", func() {
+		atomic.StoreInt32(&failures, 3)
+
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should not retry non-idempotent requests", func() {
+		atomic.StoreInt32(&failures, 1)
+
+		err := client.MoveObject(ctx, "/root/object", "/root/object2")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should retry non-idempotent requests if enabled", func() {
+		client.RetryPolicy.RetryNonIdempotent = true
+		atomic.StoreInt32(&failures, 1)
+
+		err := client.MoveObject(ctx, "/root/object", "/root/object2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Exists("/root/object2")).To(BeTrue())
+	})
+
+	It("should suppress retries when the budget is exhausted", func() {
+		client.RetryPolicy.Budget = NewRetryBudget(2, 0.5)
+
+		atomic.StoreInt32(&failures, 2)
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+
+		// the budget is empty, one success adds half a retry
+		atomic.StoreInt32(&failures, 1)
+		atomic.StoreInt32(&requests, 0)
+		_, err = client.Stat(ctx, "/root/object")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+
+		// two successes add a whole retry
+		_, err = client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		atomic.StoreInt32(&failures, 1)
+		atomic.StoreInt32(&requests, 0)
+		_, err = client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+
+		stats := client.Stats()
+		Expect(stats.Retries).To(Equal(int64(3)))
+		Expect(stats.RetriesSuppressed).To(Equal(int64(1)))
+	})
+
+	It("should report suppressed retries to the OnRetry hook", func() {
+		client.RetryPolicy.Budget = NewRetryBudget(0, 0)
+		var events []RetryEvent
+		client.Hooks.OnRetry = func(ctx context.Context, event RetryEvent) {
+			events = append(events, event)
+		}
+
+		atomic.StoreInt32(&failures, 1)
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).To(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Suppressed).To(BeTrue())
+		Expect(events[0].Path).To(Equal("/root/object"))
+		Expect(events[0].Attempt).To(Equal(1))
+		Expect(events[0].Err).To(HaveOccurred())
+	})
+
+	It("should not retry tripar errors", func() {
+		_, err := client.Stat(ctx, "/root/nonexistent")
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should not retry if context is canceled", func() {
+		client.HTTPClient.Client = &http.Client{
+			Transport: funcTransport(func(r *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&requests, 1)
+				return nil, errors.New("connection reset")
+			}),
+		}
+
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := client.PutObject(cctx, "/root/new", bytes.NewBufferString("12345"))
+		Expect(err).To(HaveOccurred())
+		Expect(strings.Contains(err.Error(), "context canceled")).To(BeTrue())
+	})
+})
+
+var _ = Describe("append retries", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var fail func(r *http.Request) *http.Response
+	var posts int32
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		fail = nil
+		atomic.StoreInt32(&posts, 0)
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.Method == "POST" && r.URL.Query().Get("cmd") == "" {
+				if atomic.AddInt32(&posts, 1) == 1 && fail != nil {
+					return fail(r), nil
+				}
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 3,
+		}
+		client.UploadChunkSize = 1024
+	})
+
+	data := strings.Repeat("0123456789", 300)
+
+	It("should not resend bytes which were persisted", func() {
+		fail = func(r *http.Request) *http.Response {
+			_, err := fake.RoundTrip(r)
+			Expect(err).NotTo(HaveOccurred())
+			return testResponse(http.StatusBadGateway, "text/plain", "bad gateway")
+		}
+
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		written, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(written)).To(Equal(data))
+	})
+
+	It("should not resend bytes which were persisted from pieces", func() {
+		fail = func(r *http.Request) *http.Response {
+			_, err := fake.RoundTrip(r)
+			Expect(err).NotTo(HaveOccurred())
+			return testResponse(http.StatusBadGateway, "text/plain", "bad gateway")
+		}
+
+		// not seekable, so the reader is copied into pieces
+		Expect(client.PutObject(ctx, "/root/object", io.MultiReader(strings.NewReader(data)))).To(Succeed())
+
+		written, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(written)).To(Equal(data))
+	})
+
+	It("should resume from the persisted offset", func() {
+		fail = func(r *http.Request) *http.Response {
+			// only the first half of the range is applied
+			var start, end int
+			_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			Expect(err).NotTo(HaveOccurred())
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			half := len(body) / 2
+
+			partial := r.Clone(ctx)
+			partial.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+half-1))
+			partial.Body = io.NopCloser(bytes.NewReader(body[:half]))
+			partial.ContentLength = int64(half)
+			_, err = fake.RoundTrip(partial)
+			Expect(err).NotTo(HaveOccurred())
+
+			return testResponse(http.StatusBadGateway, "text/plain", "bad gateway")
+		}
+
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(Succeed())
+
+		written, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(written)).To(Equal(data))
+		Expect(client.Stats().Retries).To(Equal(int64(1)))
+	})
+
+	It("should not retry without a policy", func() {
+		client.RetryPolicy = nil
+		fail = func(r *http.Request) *http.Response {
+			return testResponse(http.StatusBadGateway, "text/plain", "bad gateway")
+		}
+
+		Expect(client.PutObject(ctx, "/root/object", strings.NewReader(data))).To(HaveOccurred())
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+})
+
+var _ = Describe("RetryPolicy classes", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failures int32
+	var requests int32
+	var status int
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		atomic.StoreInt32(&failures, 0)
+		atomic.StoreInt32(&requests, 0)
+		status = http.StatusServiceUnavailable
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				return testResponse(status, "text/plain", "failed"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 2,
+		}
+	})
+
+	It("should use the attempts of the error's class", func() {
+		client.RetryPolicy.Classes = map[ErrorClass]RetryClassPolicy{
+			ErrorClassThrottled: {MaxAttempts: 4},
+		}
+		atomic.StoreInt32(&failures, 3)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(4)))
+	})
+
+	It("should fall back to MaxAttempts for other classes", func() {
+		client.RetryPolicy.Classes = map[ErrorClass]RetryClassPolicy{
+			ErrorClassThrottled: {MaxAttempts: 4},
+		}
+		status = http.StatusInternalServerError
+		atomic.StoreInt32(&failures, 3)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+	})
+
+	It("should only retry 429 if throttled errors are configured", func() {
+		status = http.StatusTooManyRequests
+		atomic.StoreInt32(&failures, 1)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+
+		client.RetryPolicy.Classes = map[ErrorClass]RetryClassPolicy{
+			ErrorClassThrottled: {MaxAttempts: 2},
+		}
+		atomic.StoreInt32(&failures, 1)
+
+		_, err = client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should stop retrying when the time budget is spent", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 100,
+			Backoff:     20 * time.Millisecond,
+			MaxDuration: 50 * time.Millisecond,
+		}
+		atomic.StoreInt32(&failures, 100)
+
+		start := time.Now()
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should use the policy of the context", func() {
+		client.RetryPolicy = nil
+		atomic.StoreInt32(&failures, 1)
+
+		_, err := client.Stat(WithRetryPolicy(ctx, &RetryPolicy{MaxAttempts: 2}), "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+	})
+
+	It("should disable retries with a nil policy in the context", func() {
+		atomic.StoreInt32(&failures, 1)
+
+		_, err := client.Stat(WithRetryPolicy(ctx, nil), "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should share the time budget of the context across requests", func() {
+		policyCtx := WithRetryPolicy(ctx, &RetryPolicy{
+			MaxAttempts: 2,
+			MaxDuration: 30 * time.Millisecond,
+		})
+		time.Sleep(30 * time.Millisecond)
+		atomic.StoreInt32(&failures, 1)
+
+		_, err := client.Stat(policyCtx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+})
+
+var _ = Describe("RetryPolicy backoff", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failures int32
+	var requests int32
+	var status int
+	var backoffs []time.Duration
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		atomic.StoreInt32(&failures, 0)
+		atomic.StoreInt32(&requests, 0)
+		status = http.StatusInternalServerError
+		backoffs = nil
+
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				return testResponse(status, "text/plain", "failed"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+		client.Hooks.OnRetry = func(ctx context.Context, event RetryEvent) {
+			backoffs = append(backoffs, event.Backoff)
+		}
+	})
+
+	It("should grow the backoff exponentially up to MaxBackoff", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 5,
+			Backoff:     time.Millisecond,
+			Multiplier:  2,
+			MaxBackoff:  5 * time.Millisecond,
+		}
+		atomic.StoreInt32(&failures, 4)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backoffs).To(Equal([]time.Duration{
+			time.Millisecond,
+			2 * time.Millisecond,
+			4 * time.Millisecond,
+			5 * time.Millisecond,
+		}))
+	})
+
+	It("should shorten the backoff by the jitter", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 20,
+			Backoff:     time.Millisecond,
+			Jitter:      0.5,
+		}
+		atomic.StoreInt32(&failures, 19)
+
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backoffs).To(HaveLen(19))
+		distinct := map[time.Duration]bool{}
+		for _, backoff := range backoffs {
+			Expect(backoff).To(BeNumerically(">=", 500*time.Microsecond))
+			Expect(backoff).To(BeNumerically("<=", time.Millisecond))
+			distinct[backoff] = true
+		}
+		Expect(len(distinct)).To(BeNumerically(">", 1))
+	})
+
+	It("should only retry the configured statuses", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts:       2,
+			RetryableStatuses: []int{http.StatusBadGateway, http.StatusTooManyRequests},
+		}
+
+		atomic.StoreInt32(&failures, 1)
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+
+		for _, status = range []int{http.StatusBadGateway, http.StatusTooManyRequests} {
+			atomic.StoreInt32(&requests, 0)
+			atomic.StoreInt32(&failures, 1)
+			_, err = client.Stat(ctx, "/root")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+		}
+	})
+
+	It("should not retry unsupported commands and a full share", func() {
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 2,
+		}
+
+		for code, sentinel := range map[int]error{
+			http.StatusNotImplemented:      ErrNotSupported,
+			http.StatusInsufficientStorage: ErrNoSpace,
+		} {
+			status = code
+			atomic.StoreInt32(&requests, 0)
+			atomic.StoreInt32(&failures, 1)
+			_, err := client.Stat(ctx, "/root")
+			Expect(err).To(MatchError(sentinel))
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+		}
+	})
+
+	It("should only retry errors accepted by RetryableError", func() {
+		reset := errors.New("connection reset by peer")
+		var fail error
+		client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				return nil, fail
+			}
+			return fake.RoundTrip(r)
+		})
+		client.RetryPolicy = &RetryPolicy{
+			MaxAttempts: 2,
+			RetryableError: func(err error) bool {
+				return errors.Is(err, reset)
+			},
+		}
+
+		fail = errors.New("no route to host")
+		atomic.StoreInt32(&failures, 1)
+		_, err := client.Stat(ctx, "/root")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+
+		fail = reset
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&failures, 1)
+		_, err = client.Stat(ctx, "/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+	})
+})
@@ -0,0 +1,60 @@
+package triparclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+type ServerInfo struct {
+	// Server is the raw Server response header, e.g.
+	// "HPE-3PAR-FilePersona/1.6.3 (build 42)".
+	Server  string
+	Product string
+	Version string
+}
+
+func parseServerHeader(server string) (info ServerInfo) {
+	info.Server = server
+
+	product := strings.Fields(server)
+	if len(product) == 0 {
+		return info
+	}
+
+	parts := strings.SplitN(product[0], "/", 2)
+	info.Product = parts[0]
+	if len(parts) == 2 {
+		info.Version = parts[1]
+	}
+
+	return info
+}
+
+// ServerInfo returns the appliance version as reported in the Server header
+// of a request to the share root, as the Object Access API has no dedicated
+// version call.
+func (tp *TriparClient) ServerInfo(ctx context.Context) (info ServerInfo, err error) {
+	defer tp.observe(ctx, "ServerInfo", "", time.Now(), &err)
+
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "GET",
+		Path:           tp.path("/"),
+		Params:         tp.cmd("stat"),
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		return ServerInfo{}, xerrors.Errorf("server info request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		return ServerInfo{}, xerrors.Errorf("server info response error: %w", err)
+	}
+
+	return parseServerHeader(rsp.Header.Get("Server")), nil
+}
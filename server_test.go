@@ -0,0 +1,39 @@
+package triparclient_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("ServerInfo", func() {
+	It("should parse the server header", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			rsp := testStatResponse(4096)
+			rsp.Header.Set("Server", "HPE-3PAR-FilePersona/1.6.3 (build 42)")
+			return rsp, nil
+		}))
+
+		info, err := client.ServerInfo(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(ServerInfo{
+			Server:  "HPE-3PAR-FilePersona/1.6.3 (build 42)",
+			Product: "HPE-3PAR-FilePersona",
+			Version: "1.6.3",
+		}))
+	})
+
+	It("should return an empty info if there is no server header", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testStatResponse(4096), nil
+		}))
+
+		info, err := client.ServerInfo(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(ServerInfo{}))
+	})
+})
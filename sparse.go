@@ -0,0 +1,162 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+// WriteAt writes size bytes from reader at offset of an existing object. The
+// offset may be past the end of the object, in which case the gap becomes a
+// hole which is read as zeros and does not need to be transferred.
+func (tp *TriparClient) WriteAt(ctx context.Context, path string, offset int64, reader io.Reader, size int64) (err error) {
+	defer tp.observe(ctx, "WriteAt", path, time.Now(), &err)
+
+	if offset < 0 || size < 0 {
+		return xerrors.Errorf("write at invalid range: %w", ErrBadRange)
+	}
+	if size == 0 {
+		return nil
+	}
+	if tp.planned(ctx, PlannedOperation{Op: "WriteAt", Path: path, Offset: offset, Size: size}) {
+		return nil
+	}
+
+	if err := tp.writeRange(ctx, path, offset, reader, size, false); err != nil {
+		return xerrors.Errorf("write at error: %w", err)
+	}
+
+	return nil
+}
+
+// PunchHole deallocates length bytes at offset of an object, which are read as
+// zeros afterwards. The object size does not change. It fails with
+// ErrNotSupported if the appliance or the underlying file system can't
+// deallocate ranges.
+func (tp *TriparClient) PunchHole(ctx context.Context, path string, offset int64, length int64) (err error) {
+	defer tp.observe(ctx, "PunchHole", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "PunchHole", Path: path, Offset: offset, Size: length}) {
+		return nil
+	}
+
+	if err := tp.fallocate(ctx, path, "punch_hole", offset, length); err != nil {
+		return xerrors.Errorf("punch hole error: %w", err)
+	}
+
+	return nil
+}
+
+// Preallocate reserves size bytes of space for an object before it is
+// written, so that parallel segment uploads with WriteAt don't run out of
+// space halfway through. The object is created if it does not exist and
+// extended to size if it is shorter, existing data is kept. It fails with
+// ErrNoSpace if the space can't be reserved.
+func (tp *TriparClient) Preallocate(ctx context.Context, path string, size int64) (err error) {
+	defer tp.observe(ctx, "Preallocate", path, time.Now(), &err)
+
+	if size < 0 {
+		return xerrors.Errorf("preallocate invalid size: %w", ErrBadRange)
+	}
+	if tp.planned(ctx, PlannedOperation{Op: "Preallocate", Path: path, Size: size}) {
+		return nil
+	}
+
+	if _, err := tp.Stat(ctx, path, StatSkipIdentity()); errors.Is(err, ErrNotFound) {
+		if err := tp.putChunk(ctx, path, 0, bytes.NewReader(nil), 0); err != nil {
+			return xerrors.Errorf("preallocate create error: %w", err)
+		}
+	} else if err != nil {
+		return xerrors.Errorf("preallocate stat error: %w", err)
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	if err := tp.fallocate(ctx, path, "", 0, size); err != nil {
+		return xerrors.Errorf("preallocate error: %w", err)
+	}
+
+	return nil
+}
+
+// Truncate changes the size of an object. Objects can be truncated to 0 or
+// extended, extended ranges are allocated and read as zeros. The API can't
+// shrink objects to other sizes, which fails with ErrNotSupported.
+func (tp *TriparClient) Truncate(ctx context.Context, path string, size int64) (err error) {
+	defer tp.observe(ctx, "Truncate", path, time.Now(), &err)
+
+	if size < 0 {
+		return xerrors.Errorf("truncate invalid size: %w", ErrBadRange)
+	}
+
+	info, err := tp.Stat(ctx, path, StatSkipIdentity())
+	if err != nil {
+		return xerrors.Errorf("truncate stat error: %w", err)
+	}
+	if info.IsDir() {
+		return xerrors.Errorf("truncate %s: %w", path, ErrNotAFile)
+	}
+
+	current := info.Status.Size
+	if size == current {
+		return nil
+	}
+	if size != 0 && size < current {
+		return xerrors.Errorf("truncate from %d to %d bytes: %w", current, size, ErrNotSupported)
+	}
+
+	if tp.planned(ctx, PlannedOperation{Op: "Truncate", Path: path, Size: size}) {
+		return nil
+	}
+
+	if size == 0 {
+		err = tp.putChunk(ctx, path, 0, bytes.NewReader(nil), 0)
+	} else {
+		err = tp.fallocate(ctx, path, "", 0, size)
+	}
+	if err != nil {
+		return xerrors.Errorf("truncate error: %w", err)
+	}
+
+	return nil
+}
+
+// fallocate manipulates the allocated space of an object with the fallocate
+// command. An empty mode allocates the range.
+func (tp *TriparClient) fallocate(ctx context.Context, path string, mode string, offset int64, length int64) (err error) {
+	if offset < 0 || length <= 0 {
+		return xerrors.Errorf("fallocate invalid range: %w", ErrBadRange)
+	}
+
+	params := tp.cmd("fallocate")
+	params.Set("offset", strconv.FormatInt(offset, 10))
+	params.Set("length", strconv.FormatInt(length, 10))
+	if mode != "" {
+		params.Set("mode", mode)
+	}
+	rsp, err := tp.request(&httpclient.RequestData{
+		Context:        ctx,
+		Method:         "POST",
+		Path:           tp.path(path),
+		Params:         params,
+		ExpectedStatus: []int{http.StatusOK},
+	})
+	if err != nil {
+		return xerrors.Errorf("fallocate request error: %w", err)
+	}
+
+	if err := UnmarshalTriparError(rsp); err != nil {
+		return xerrors.Errorf("fallocate response error: %w", err)
+	}
+
+	return nil
+}
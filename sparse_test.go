@@ -0,0 +1,108 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Sparse", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "0123456789")
+	})
+
+	Describe("WriteAt", func() {
+		It("should write into objects", func() {
+			Expect(client.WriteAt(ctx, "/root/object", 2, bytes.NewBufferString("ab"), 2)).To(Succeed())
+			data, _ := fake.File("/root/object")
+			Expect(string(data)).To(Equal("01ab456789"))
+		})
+
+		It("should write past the end of objects", func() {
+			Expect(client.WriteAt(ctx, "/root/object", 12, bytes.NewBufferString("ab"), 2)).To(Succeed())
+			data, _ := fake.File("/root/object")
+			Expect(data).To(Equal([]byte("0123456789\x00\x00ab")))
+			Expect(fake.Requests()).To(Equal([]string{"POST /root/object"}))
+		})
+
+		It("should fail for missing objects and invalid ranges", func() {
+			Expect(client.WriteAt(ctx, "/root/missing", 0, bytes.NewBufferString("ab"), 2)).To(MatchError(ErrNotFound))
+			Expect(client.WriteAt(ctx, "/root/object", -1, bytes.NewBufferString("ab"), 2)).To(MatchError(ErrBadRange))
+		})
+	})
+
+	Describe("PunchHole", func() {
+		It("should punch holes", func() {
+			Expect(client.PunchHole(ctx, "/root/object", 8, 4)).To(Succeed())
+			data, _ := fake.File("/root/object")
+			Expect(data).To(Equal([]byte("01234567\x00\x00")))
+		})
+
+		It("should fail if it is not supported", func() {
+			fake.Unsupport("fallocate")
+			Expect(client.PunchHole(ctx, "/root/object", 0, 4)).To(MatchError(ErrNotSupported))
+		})
+
+		It("should fail for invalid ranges", func() {
+			Expect(client.PunchHole(ctx, "/root/object", 0, 0)).To(MatchError(ErrBadRange))
+			Expect(fake.Requests()).To(BeEmpty())
+		})
+	})
+
+	Describe("Preallocate", func() {
+		It("should create and extend objects", func() {
+			Expect(client.Preallocate(ctx, "/root/new", 4)).To(Succeed())
+			data, ok := fake.File("/root/new")
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal([]byte{0, 0, 0, 0}))
+
+			Expect(client.WriteAt(ctx, "/root/new", 2, bytes.NewBufferString("ab"), 2)).To(Succeed())
+			data, _ = fake.File("/root/new")
+			Expect(data).To(Equal([]byte("\x00\x00ab")))
+		})
+
+		It("should keep existing data", func() {
+			Expect(client.Preallocate(ctx, "/root/object", 12)).To(Succeed())
+			data, _ := fake.File("/root/object")
+			Expect(data).To(Equal([]byte("0123456789\x00\x00")))
+
+			Expect(client.Preallocate(ctx, "/root/object", 4)).To(Succeed())
+			data, _ = fake.File("/root/object")
+			Expect(data).To(HaveLen(12))
+		})
+
+		It("should fail if it is not supported", func() {
+			fake.Unsupport("fallocate")
+			Expect(client.Preallocate(ctx, "/root/object", 4)).To(MatchError(ErrNotSupported))
+		})
+	})
+
+	Describe("Truncate", func() {
+		It("should truncate and extend objects", func() {
+			Expect(client.Truncate(ctx, "/root/object", 12)).To(Succeed())
+			data, _ := fake.File("/root/object")
+			Expect(data).To(Equal([]byte("0123456789\x00\x00")))
+
+			Expect(client.Truncate(ctx, "/root/object", 0)).To(Succeed())
+			data, _ = fake.File("/root/object")
+			Expect(data).To(BeEmpty())
+		})
+
+		It("should fail for shrinking objects", func() {
+			Expect(client.Truncate(ctx, "/root/object", 4)).To(MatchError(ErrNotSupported))
+			Expect(client.Truncate(ctx, "/root/missing", 0)).To(MatchError(ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,216 @@
+package triparclient
+
+import (
+	pathpkg "path"
+	"strings"
+	"sync"
+	"time"
+
+	httpclient "github.com/koofr/go-httpclient"
+	"golang.org/x/xerrors"
+)
+
+type StatCacheOptions struct {
+	// TTL is how long Stat results are cached.
+	TTL time.Duration
+
+	// NegativeTTL is how long ErrNotFound results are cached, 0 disables
+	// negative caching.
+	NegativeTTL time.Duration
+
+	// MaxEntries limits the number of cached results, 0 means unlimited.
+	// Once it is reached, expired results are dropped, and if that is not
+	// enough, arbitrary other ones.
+	MaxEntries int
+}
+
+// statCacheMinSweep is the number of entries at which a stat cache first
+// drops its expired entries. Later sweeps happen once the cache has doubled
+// in size since the last one, so that setting entries stays cheap.
+const statCacheMinSweep = 1024
+
+type statCacheEntry struct {
+	info     Stat
+	notFound bool
+	expires  time.Time
+}
+
+// statCache caches Stat results by the path on the appliance, so that it can
+// be shared by clients with different roots.
+type statCache struct {
+	opts StatCacheOptions
+
+	mx      sync.Mutex
+	entries map[string]statCacheEntry
+	sweepAt int
+}
+
+func newStatCache(opts StatCacheOptions) *statCache {
+	c := &statCache{
+		opts:    opts,
+		entries: map[string]statCacheEntry{},
+	}
+	c.setSweepAt()
+	return c
+}
+
+func (c *statCache) setSweepAt() {
+	c.sweepAt = 2 * len(c.entries)
+	if c.sweepAt < statCacheMinSweep {
+		c.sweepAt = statCacheMinSweep
+	}
+	if c.opts.MaxEntries > 0 && c.sweepAt > c.opts.MaxEntries {
+		c.sweepAt = c.opts.MaxEntries
+	}
+}
+
+// store sets the entry of key, sweeping the cache first if it has grown too
+// large. It must be called with mx held.
+func (c *statCache) store(key string, entry statCacheEntry) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.sweepAt {
+		c.sweep()
+	}
+	c.entries[key] = entry
+}
+
+// sweep drops the expired entries, and arbitrary other ones while the cache
+// is full. It must be called with mx held.
+func (c *statCache) sweep() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if c.opts.MaxEntries > 0 {
+		for key := range c.entries {
+			if len(c.entries) < c.opts.MaxEntries {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	c.setSweepAt()
+}
+
+func (c *statCache) get(key string) (statCacheEntry, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return statCacheEntry{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return statCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *statCache) set(key string, info Stat) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.store(key, statCacheEntry{
+		info:    info,
+		expires: time.Now().Add(c.opts.TTL),
+	})
+}
+
+func (c *statCache) setNotFound(key string) {
+	if c.opts.NegativeTTL <= 0 {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.store(key, statCacheEntry{
+		notFound: true,
+		expires:  time.Now().Add(c.opts.NegativeTTL),
+	})
+}
+
+func (c *statCache) invalidate(key string, tree bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	delete(c.entries, key)
+	if !tree {
+		return
+	}
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	for k := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// EnableStatCache makes Stat cache its results, and GetObject, which stats
+// the object first, use them. The cache is shared with clients derived from
+// this one, e.g. with Chroot or WithCredentials. Requests which modify the
+// share through any of these clients invalidate the affected paths, changes
+// made by others are only seen once the TTL expires or InvalidatePath or
+// InvalidateTree is called.
+func (tp *TriparClient) EnableStatCache(opts StatCacheOptions) error {
+	if opts.TTL <= 0 {
+		return xerrors.Errorf("invalid stat cache ttl: %s", opts.TTL)
+	}
+	if opts.NegativeTTL < 0 {
+		return xerrors.Errorf("invalid stat cache negative ttl: %s", opts.NegativeTTL)
+	}
+	if opts.MaxEntries < 0 {
+		return xerrors.Errorf("invalid stat cache max entries: %d", opts.MaxEntries)
+	}
+
+	tp.statCache.Store(newStatCache(opts))
+
+	return nil
+}
+
+// DisableStatCache drops the stat cache.
+func (tp *TriparClient) DisableStatCache() {
+	tp.statCache.Store(nil)
+}
+
+func (tp *TriparClient) statCacheKey(path string) string {
+	return pathpkg.Clean(tp.path(path))
+}
+
+// InvalidatePath drops the cached Stat result of path. It is meant for
+// change events from outside the client, e.g. a watcher.
+func (tp *TriparClient) InvalidatePath(path string) {
+	if cache := tp.statCache.Load(); cache != nil {
+		cache.invalidate(tp.statCacheKey(path), false)
+	}
+}
+
+// InvalidateTree drops the cached Stat results of path and everything below
+// it.
+func (tp *TriparClient) InvalidateTree(path string) {
+	if cache := tp.statCache.Load(); cache != nil {
+		cache.invalidate(tp.statCacheKey(path), true)
+	}
+}
+
+// invalidateRequest drops the cached results a modifying request could have
+// changed: the paths involved, everything below them in case they are
+// directories, and their parent directories.
+func (tp *TriparClient) invalidateRequest(req *httpclient.RequestData) {
+	cache := tp.statCache.Load()
+	if cache == nil {
+		return
+	}
+
+	paths := []string{req.Path}
+	if dst := req.Params.Get("destination"); dst != "" {
+		paths = append(paths, dst)
+	}
+	for _, path := range paths {
+		path = pathpkg.Clean(path)
+		cache.invalidate(path, true)
+		cache.invalidate(pathpkg.Dir(path), false)
+	}
+}
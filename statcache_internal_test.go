@@ -0,0 +1,33 @@
+package triparclient
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("stat cache size", func() {
+	It("should drop expired entries when growing", func() {
+		c := newStatCache(StatCacheOptions{TTL: time.Millisecond, NegativeTTL: time.Millisecond})
+		for i := 0; i < statCacheMinSweep; i++ {
+			c.set(fmt.Sprintf("/object%d", i), Stat{})
+		}
+		time.Sleep(2 * time.Millisecond)
+
+		c.setNotFound("/missing")
+		Expect(c.entries).To(HaveLen(1))
+	})
+
+	It("should limit the number of entries", func() {
+		c := newStatCache(StatCacheOptions{TTL: time.Hour, MaxEntries: 10})
+		for i := 0; i < 100; i++ {
+			c.set(fmt.Sprintf("/object%d", i), Stat{})
+			Expect(len(c.entries)).To(BeNumerically("<=", 10))
+		}
+
+		_, ok := c.get("/object99")
+		Expect(ok).To(BeTrue())
+	})
+})
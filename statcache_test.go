@@ -0,0 +1,128 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("StatCache", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	stats := func() (n int) {
+		for _, req := range fake.Requests() {
+			if req == "GET /root/object stat" {
+				n++
+			}
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "12345")
+
+		Expect(client.EnableStatCache(StatCacheOptions{
+			TTL:         time.Minute,
+			NegativeTTL: time.Minute,
+		})).To(Succeed())
+	})
+
+	It("should cache stat results", func() {
+		info, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(5)))
+
+		info, err = client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(5)))
+		Expect(info.Path).To(Equal("/root/object"))
+
+		Expect(stats()).To(Equal(1))
+	})
+
+	It("should cache not found results", func() {
+		_, err := client.Stat(ctx, "/root/missing")
+		Expect(err).To(MatchError(ErrNotFound))
+		fake.PutFile("/root/missing", "1")
+		_, err = client.Stat(ctx, "/root/missing")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should invalidate on mutations through the client", func() {
+		_, err := client.Stat(ctx, "/root/new")
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(client.PutObject(ctx, "/root/new", bytes.NewBufferString("1"))).To(Succeed())
+		_, err = client.Stat(ctx, "/root/new")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.MoveObject(ctx, "/root/new", "/root/object")).To(Succeed())
+		info, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(1)))
+		_, err = client.Stat(ctx, "/root/new")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should invalidate trees", func() {
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+		fake.PutFile("/root/object", "1")
+
+		client.InvalidatePath("/root")
+		info, _ := client.Stat(ctx, "/root/object")
+		Expect(info.Status.Size).To(Equal(int64(5)))
+
+		client.InvalidateTree("/root")
+		info, _ = client.Stat(ctx, "/root/object")
+		Expect(info.Status.Size).To(Equal(int64(1)))
+
+		fake.PutFile("/root/object", "123")
+		client.InvalidatePath("/root/object")
+		info, _ = client.Stat(ctx, "/root/object")
+		Expect(info.Status.Size).To(Equal(int64(3)))
+	})
+
+	It("should be shared with chrooted clients", func() {
+		_, err := client.Stat(ctx, "/root/object")
+		Expect(err).NotTo(HaveOccurred())
+
+		chrooted, err := client.Chroot("/root")
+		Expect(err).NotTo(HaveOccurred())
+		info, err := chrooted.Stat(ctx, "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Path).To(Equal("/object"))
+		Expect(stats()).To(Equal(1))
+
+		Expect(chrooted.DeleteObject(ctx, "/object")).To(Succeed())
+		_, err = client.Stat(ctx, "/root/object")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should be disabled", func() {
+		client.DisableStatCache()
+		client.Stat(ctx, "/root/object")
+		client.Stat(ctx, "/root/object")
+		Expect(stats()).To(Equal(2))
+	})
+
+	It("should reject invalid ttls", func() {
+		Expect(client.EnableStatCache(StatCacheOptions{})).NotTo(Succeed())
+	})
+
+	It("should reject a negative max entries", func() {
+		Expect(client.EnableStatCache(StatCacheOptions{TTL: time.Minute, MaxEntries: -1})).NotTo(Succeed())
+	})
+})
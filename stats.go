@@ -0,0 +1,188 @@
+package triparclient
+
+import (
+	"crypto/tls"
+	"expvar"
+	"io"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	httpclient "github.com/koofr/go-httpclient"
+)
+
+type Stats struct {
+	Requests        map[string]int64 `json:"requests"`
+	BytesIn         int64            `json:"bytes_in"`
+	BytesOut        int64            `json:"bytes_out"`
+	ActiveTransfers int64            `json:"active_transfers"`
+	PoolInUse       int              `json:"pool_in_use"`
+	PoolCapacity    int              `json:"pool_capacity"`
+
+	// Connection counters are only collected with the client's default
+	// transport. IdleConns is estimated from the open connections and the
+	// requests in progress.
+	NewConns      int64 `json:"new_conns"`
+	ReusedConns   int64 `json:"reused_conns"`
+	DNSLookups    int64 `json:"dns_lookups"`
+	TLSHandshakes int64 `json:"tls_handshakes"`
+	OpenConns     int64 `json:"open_conns"`
+	IdleConns     int64 `json:"idle_conns"`
+
+	// RetriesSuppressed counts retries skipped because the RetryPolicy's
+	// budget was exhausted.
+	Retries           int64 `json:"retries"`
+	RetriesSuppressed int64 `json:"retries_suppressed"`
+}
+
+type clientStats struct {
+	mx              sync.Mutex
+	requests        map[string]int64
+	bytesIn         int64
+	bytesOut        int64
+	activeTransfers int64
+	newConns        int64
+	reusedConns     int64
+	dnsLookups      int64
+	tlsHandshakes   int64
+	openConns       int64
+	activeConns     int64
+
+	retries           int64
+	retriesSuppressed int64
+}
+
+func newClientStats() *clientStats {
+	return &clientStats{
+		requests: map[string]int64{},
+	}
+}
+
+func requestOp(req *httpclient.RequestData) string {
+	if cmd := req.Params.Get("cmd"); cmd != "" {
+		return cmd
+	}
+	return strings.ToLower(req.Method)
+}
+
+func (s *clientStats) request(req *httpclient.RequestData) {
+	s.mx.Lock()
+	s.requests[requestOp(req)]++
+	s.mx.Unlock()
+
+	if req.ReqContentLength > 0 {
+		atomic.AddInt64(&s.bytesOut, req.ReqContentLength)
+	}
+}
+
+// trace returns a ClientTrace counting connections of a request. release must
+// be called once the request's response body is closed or the request fails.
+func (s *clientStats) trace() (trace *httptrace.ClientTrace, release func()) {
+	conns := int64(0)
+
+	trace = &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddInt64(&s.reusedConns, 1)
+			} else {
+				atomic.AddInt64(&s.newConns, 1)
+			}
+			atomic.AddInt64(&conns, 1)
+			atomic.AddInt64(&s.activeConns, 1)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			atomic.AddInt64(&s.dnsLookups, 1)
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			atomic.AddInt64(&s.tlsHandshakes, 1)
+		},
+	}
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			atomic.AddInt64(&s.activeConns, -atomic.LoadInt64(&conns))
+		})
+	}
+
+	return trace, release
+}
+
+func (s *clientStats) startTransfer() (done func()) {
+	atomic.AddInt64(&s.activeTransfers, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&s.activeTransfers, -1)
+		})
+	}
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	atomic.AddInt64(r.count, int64(n))
+	return n, err
+}
+
+type doneReadCloser struct {
+	io.ReadCloser
+	done func()
+}
+
+func (r *doneReadCloser) Close() error {
+	r.done()
+	return r.ReadCloser.Close()
+}
+
+func (tp *TriparClient) Stats() Stats {
+	tp.stats.mx.Lock()
+	requests := make(map[string]int64, len(tp.stats.requests))
+	for op, count := range tp.stats.requests {
+		requests[op] = count
+	}
+	tp.stats.mx.Unlock()
+
+	stats := Stats{
+		Requests:        requests,
+		BytesIn:         atomic.LoadInt64(&tp.stats.bytesIn),
+		BytesOut:        atomic.LoadInt64(&tp.stats.bytesOut),
+		ActiveTransfers: atomic.LoadInt64(&tp.stats.activeTransfers),
+		NewConns:        atomic.LoadInt64(&tp.stats.newConns),
+		ReusedConns:     atomic.LoadInt64(&tp.stats.reusedConns),
+		DNSLookups:      atomic.LoadInt64(&tp.stats.dnsLookups),
+		TLSHandshakes:   atomic.LoadInt64(&tp.stats.tlsHandshakes),
+		OpenConns:       atomic.LoadInt64(&tp.stats.openConns),
+
+		Retries:           atomic.LoadInt64(&tp.stats.retries),
+		RetriesSuppressed: atomic.LoadInt64(&tp.stats.retriesSuppressed),
+	}
+
+	if idle := stats.OpenConns - atomic.LoadInt64(&tp.stats.activeConns); idle > 0 {
+		stats.IdleConns = idle
+	}
+
+	if pool, ok := tp.bufferPool.(interface {
+		InUse() int
+		Capacity() int
+	}); ok {
+		stats.PoolInUse = pool.InUse()
+		stats.PoolCapacity = pool.Capacity()
+	}
+
+	return stats
+}
+
+// PublishExpvar publishes the client's Stats as an expvar variable so that
+// they are served on /debug/vars. It panics if the name is already in use.
+func (tp *TriparClient) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return tp.Stats()
+	}))
+}
@@ -0,0 +1,170 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Stats", func() {
+	It("should count requests, bytes and transfers", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		ctx := context.Background()
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"))).To(Succeed())
+
+		reader, _, err := client.GetObject(ctx, "/root/object", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Stats().ActiveTransfers).To(Equal(int64(1)))
+
+		data, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reader.Close()).To(Succeed())
+		Expect(string(data)).To(Equal("12345"))
+
+		stats := client.Stats()
+		Expect(stats.Requests).To(Equal(map[string]int64{
+			"put":  1,
+			"stat": 1,
+			"get":  1,
+		}))
+		Expect(stats.BytesOut).To(Equal(int64(5)))
+		Expect(stats.BytesIn).To(BeNumerically(">=", 5))
+		Expect(stats.ActiveTransfers).To(BeZero())
+		Expect(stats.PoolCapacity).To(Equal(4))
+		Expect(stats.PoolInUse).To(BeZero())
+	})
+
+	It("should count connections", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewTriparClient(server.URL, "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		Expect(client.SetTLSConfig(&tls.Config{RootCAs: roots})).To(Succeed())
+
+		for i := 0; i < 3; i++ {
+			_, err = client.Stat(context.Background(), "/object")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		stats := client.Stats()
+		Expect(stats.NewConns).To(Equal(int64(1)))
+		Expect(stats.ReusedConns).To(Equal(int64(2)))
+		Expect(stats.TLSHandshakes).To(Equal(int64(1)))
+		Expect(stats.OpenConns).To(Equal(int64(1)))
+		Expect(stats.IdleConns).To(Equal(int64(1)))
+
+		client.HTTPClient.Client.CloseIdleConnections()
+		Expect(client.Stats().OpenConns).To(BeZero())
+	})
+
+	It("should close idle connections of replaced transports", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewTriparClientWithOptions(server.URL, WithInsecureSkipVerify())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Stats().IdleConns).To(Equal(int64(1)))
+
+		Expect(client.EnableExpectContinue(time.Second)).To(Succeed())
+		Expect(client.Stats().OpenConns).To(BeZero())
+	})
+
+	It("should not close transports shared with other clients", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewTriparClientWithOptions(server.URL, WithInsecureSkipVerify())
+		Expect(err).NotTo(HaveOccurred())
+		other := client.WithCredentials("other", "pass")
+
+		_, err = other.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Stats().IdleConns).To(Equal(int64(1)))
+
+		Expect(client.EnableExpectContinue(time.Second)).To(Succeed())
+		Expect(other.EnableExpectContinue(time.Second)).To(Succeed())
+		Expect(client.Stats().IdleConns).To(Equal(int64(1)))
+	})
+
+	It("should not close transports supplied by the caller", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		defer server.Close()
+
+		var closed int32
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return &closeCountingConn{Conn: conn, closed: &closed}, nil
+			},
+		}
+		defer transport.CloseIdleConnections()
+
+		client, err := NewTriparClientWithOptions(server.URL, WithTransport(transport))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.EnableExpectContinue(time.Second)).To(Succeed())
+		Expect(client.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})).To(Succeed())
+		Expect(atomic.LoadInt32(&closed)).To(BeZero())
+	})
+
+	It("should publish stats via expvar", func() {
+		client, _ := newFakeTriparClient()
+		client.PublishExpvar("tripar-stats-test")
+
+		stats := Stats{}
+		Expect(json.Unmarshal([]byte(expvar.Get("tripar-stats-test").String()), &stats)).To(Succeed())
+		Expect(stats.PoolCapacity).To(Equal(4))
+	})
+})
+
+// closeCountingConn counts how often connections are closed.
+type closeCountingConn struct {
+	net.Conn
+	closed *int32
+}
+
+func (c *closeCountingConn) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return c.Conn.Close()
+}
@@ -0,0 +1,156 @@
+package triparclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+var ErrInvalidResponse = errors.New("invalid response")
+
+// maxInvalidResponseBody bounds InvalidResponseError.Body.
+const maxInvalidResponseBody = 4 * 1024
+
+// InvalidResponseError is returned with StrictResponses if a response does
+// not match the expected shape. It matches ErrInvalidResponse.
+type InvalidResponseError struct {
+	// Problems lists unknown fields, missing required fields and values
+	// of the wrong type.
+	Problems []string
+
+	// Body is the response body, truncated to 4KB.
+	Body []byte
+}
+
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("invalid response: %s: %q", strings.Join(e.Problems, ", "), e.Body)
+}
+
+func (e *InvalidResponseError) Is(target error) bool {
+	return target == ErrInvalidResponse
+}
+
+func newInvalidResponseError(problems []string, body []byte) *InvalidResponseError {
+	if len(body) > maxInvalidResponseBody {
+		body = body[:maxInvalidResponseBody]
+	}
+	return &InvalidResponseError{
+		Problems: problems,
+		Body:     append([]byte{}, body...),
+	}
+}
+
+// unmarshalResponse is UnmarshalTriparResponse, which also validates the
+// response with StrictResponses.
+func (tp *TriparClient) unmarshalResponse(r *http.Response, v interface{}) error {
+	if !tp.StrictResponses {
+		return UnmarshalTriparResponse(r, v)
+	}
+
+	body, err := readTriparResponse(r)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return xerrors.Errorf("failed to json unmarshal response: %w", newInvalidResponseError([]string{err.Error()}, body))
+	}
+
+	var raw interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return xerrors.Errorf("failed to json unmarshal response: %w", newInvalidResponseError([]string{err.Error()}, body))
+	}
+
+	var problems []string
+	validateResponse(raw, reflect.TypeOf(v), "", &problems)
+	if len(problems) > 0 {
+		return newInvalidResponseError(problems, body)
+	}
+
+	return nil
+}
+
+// validateResponse checks raw, a decoded JSON value, against t. Struct fields
+// tagged `tripar:"required"` must be present.
+func validateResponse(raw interface{}, t reflect.Type, path string, problems *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an object", fieldPath(path, "")))
+			return
+		}
+
+		known := map[string]bool{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			known[name] = true
+
+			value, ok := object[name]
+			if !ok || value == nil {
+				if field.Tag.Get("tripar") == "required" {
+					*problems = append(*problems, fmt.Sprintf("%s: missing required field", fieldPath(path, name)))
+				}
+				continue
+			}
+			validateResponse(value, field.Type, fieldPath(path, name), problems)
+		}
+
+		unknown := []string{}
+		for name := range object {
+			if !known[name] {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			*problems = append(*problems, fmt.Sprintf("%s: unknown field", fieldPath(path, name)))
+		}
+
+	case reflect.Slice:
+		array, ok := raw.([]interface{})
+		if !ok {
+			// type mismatches are reported by json.Unmarshal
+			return
+		}
+		for i, value := range array {
+			validateResponse(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+func fieldPath(path string, name string) string {
+	switch {
+	case path == "" && name == "":
+		return "response"
+	case path == "":
+		return name
+	case name == "":
+		return path
+	default:
+		return path + "." + name
+	}
+}
@@ -0,0 +1,82 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("StrictResponses", func() {
+	var ctx context.Context
+	var body string
+	var client *TriparClient
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(http.StatusOK, "application/json", body), nil
+		}))
+		client.StrictResponses = true
+	})
+
+	It("should accept valid responses", func() {
+		body = `{"path": "/file", "status": {"mode": 33188, "size": 3, "mtime": 1700000000.5}}`
+
+		info, err := client.Stat(ctx, "/file")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(3)))
+
+		body = `{"entries": [{"name": "a"}, {"name": "b", "type": "file", "size": 1}]}`
+
+		entries, err := client.List(ctx, "/dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(HaveLen(2))
+	})
+
+	It("should report unknown fields", func() {
+		body = `{"path": "/file", "extra": 1, "status": {"mode": 33188, "size": 3, "flags": 2}}`
+
+		_, err := client.Stat(ctx, "/file")
+		Expect(err).To(MatchError(ErrInvalidResponse))
+		var invalidErr *InvalidResponseError
+		Expect(errors.As(err, &invalidErr)).To(BeTrue())
+		Expect(invalidErr.Problems).To(Equal([]string{"status.flags: unknown field", "extra: unknown field"}))
+		Expect(string(invalidErr.Body)).To(Equal(body))
+	})
+
+	It("should report missing required fields", func() {
+		body = `{"entries": [{"name": "a"}, {"type": "file"}]}`
+
+		_, err := client.List(ctx, "/dir")
+		Expect(err).To(MatchError(ErrInvalidResponse))
+		var invalidErr *InvalidResponseError
+		Expect(errors.As(err, &invalidErr)).To(BeTrue())
+		Expect(invalidErr.Problems).To(Equal([]string{"entries[1].name: missing required field"}))
+	})
+
+	It("should report type mismatches", func() {
+		body = `{"path": "/file", "status": {"mode": 33188, "size": "3"}}`
+
+		_, err := client.Stat(ctx, "/file")
+		Expect(err).To(MatchError(ErrInvalidResponse))
+		var invalidErr *InvalidResponseError
+		Expect(errors.As(err, &invalidErr)).To(BeTrue())
+		Expect(invalidErr.Problems).To(HaveLen(1))
+		Expect(invalidErr.Problems[0]).To(ContainSubstring("size"))
+		Expect(string(invalidErr.Body)).To(Equal(body))
+	})
+
+	It("should decode leniently by default", func() {
+		client.StrictResponses = false
+		body = `{"path": "/file", "extra": 1, "status": {"size": 3}}`
+
+		info, err := client.Stat(ctx, "/file")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(3)))
+	})
+})
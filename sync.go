@@ -0,0 +1,445 @@
+package triparclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// syncMtimeSlack is how much newer a local file has to be than the remote
+// object to be considered changed, as the appliance may store mtimes with
+// less precision than the local file system.
+const syncMtimeSlack = time.Second
+
+type SyncOptions struct {
+	// StateFile is a local file in which SyncDir and Mirror record the size,
+	// mtime and checksum of every entry after a successful run. Later runs
+	// skip local files whose size and mtime match the recorded ones without
+	// issuing any requests, and files whose content matches the recorded
+	// checksum are not uploaded again. The state is only used for the same
+	// local and remote directory.
+	StateFile string
+
+	// Checkpoint records the files which have been synced, by path relative
+	// to the local directory, so that an interrupted run resumes without
+	// examining them again when it is called with the same checkpoint.
+	Checkpoint Checkpoint
+
+	// CheckpointInterval is how often completed items are recorded,
+	// DefaultCheckpointInterval if 0.
+	CheckpointInterval time.Duration
+}
+
+type SyncResult struct {
+	Uploaded  int
+	Unchanged int
+	Deleted   int
+
+	// Bytes is the number of bytes uploaded.
+	Bytes int64
+}
+
+type syncStateEntry struct {
+	Dir      bool   `json:"dir,omitempty"`
+	Size     int64  `json:"size"`
+	Mtime    int64  `json:"mtime"`
+	Checksum string `json:"sha256,omitempty"`
+}
+
+type syncState struct {
+	Local   string                    `json:"local"`
+	Remote  string                    `json:"remote"`
+	Entries map[string]syncStateEntry `json:"entries"`
+}
+
+func loadSyncState(file string, local string, remote string) (state *syncState, found bool, err error) {
+	state = &syncState{
+		Local:   local,
+		Remote:  remote,
+		Entries: map[string]syncStateEntry{},
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	loaded := &syncState{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		return nil, false, err
+	}
+	if loaded.Local != local || loaded.Remote != remote || loaded.Entries == nil {
+		// the state of another sync
+		return state, false, nil
+	}
+
+	return loaded, true, nil
+}
+
+func (s *syncState) save(file string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+type syncer struct {
+	tp     *TriparClient
+	ctx    context.Context
+	local  string
+	remote string
+	opts   *SyncOptions
+
+	cp      *checkpointer
+	prev    *syncState
+	hasPrev bool
+	next    *syncState
+	result  SyncResult
+
+	utimeUnsupported bool
+}
+
+func (tp *TriparClient) newSyncer(ctx context.Context, localDir string, remoteDir string, opts *SyncOptions) (*syncer, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+
+	local, err := filepath.Abs(localDir)
+	if err != nil {
+		return nil, xerrors.Errorf("sync local dir error: %w", err)
+	}
+
+	s := &syncer{
+		tp:     tp,
+		ctx:    ctx,
+		local:  local,
+		remote: remoteDir,
+		opts:   opts,
+	}
+
+	s.cp, err = tp.newCheckpointer(opts.Checkpoint, opts.CheckpointInterval)
+	if err != nil {
+		return nil, xerrors.Errorf("sync error: %w", err)
+	}
+
+	remote := tp.HTTPClient.BaseURL.String() + pathpkg.Clean(tp.path(remoteDir))
+	if opts.StateFile != "" {
+		s.prev, s.hasPrev, err = loadSyncState(opts.StateFile, local, remote)
+		if err != nil {
+			return nil, xerrors.Errorf("sync load state error: %w", err)
+		}
+	} else {
+		s.prev = &syncState{Entries: map[string]syncStateEntry{}}
+	}
+	s.next = &syncState{
+		Local:   local,
+		Remote:  remote,
+		Entries: map[string]syncStateEntry{},
+	}
+
+	return s, nil
+}
+
+func (s *syncer) remotePath(rel string) string {
+	return joinPath(s.remote, rel)
+}
+
+// syncDir uploads the local tree. Directories which are in the previous
+// state are assumed to exist.
+func (s *syncer) syncDir() error {
+	if err := s.tp.CreateDirectories(s.ctx, s.remote); err != nil {
+		return xerrors.Errorf("sync create directory error: %w", err)
+	}
+
+	return filepath.WalkDir(s.local, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		if localPath == s.local {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.local, localPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			return s.syncDirectory(rel)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return s.syncFile(rel, localPath, info)
+	})
+}
+
+func (s *syncer) syncDirectory(rel string) error {
+	if prev, ok := s.prev.Entries[rel]; !ok || !prev.Dir {
+		err := s.tp.CreateDirectory(s.ctx, s.remotePath(rel))
+		if err != nil && !errors.Is(err, ErrAlreadyExists) {
+			return xerrors.Errorf("sync create directory %s error: %w", rel, err)
+		}
+	}
+
+	s.next.Entries[rel] = syncStateEntry{Dir: true}
+
+	return nil
+}
+
+func (s *syncer) syncFile(rel string, localPath string, info fs.FileInfo) error {
+	entry := syncStateEntry{
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+	}
+
+	prev, hasPrev := s.prev.Entries[rel]
+	if hasPrev && !prev.Dir && prev.Size == entry.Size && prev.Mtime == entry.Mtime {
+		entry.Checksum = prev.Checksum
+	}
+
+	if s.cp.done(rel) {
+		// synced by an interrupted run
+		s.next.Entries[rel] = entry
+		s.result.Unchanged++
+		return nil
+	}
+
+	if hasPrev && !prev.Dir && prev.Size == entry.Size {
+		if prev.Mtime == entry.Mtime {
+			s.next.Entries[rel] = prev
+			s.result.Unchanged++
+			return s.cp.record(rel)
+		}
+
+		// touched, but possibly not modified
+		if prev.Checksum != "" {
+			checksum, err := fileChecksum(localPath)
+			if err != nil {
+				return xerrors.Errorf("sync checksum %s error: %w", rel, err)
+			}
+			if checksum == prev.Checksum {
+				entry.Checksum = checksum
+				s.next.Entries[rel] = entry
+				s.result.Unchanged++
+				return s.cp.record(rel)
+			}
+		}
+	}
+
+	if !hasPrev {
+		// no state, so the remote object has to be checked
+		remote, err := s.tp.Stat(s.ctx, s.remotePath(rel), StatSkipIdentity())
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return xerrors.Errorf("sync stat %s error: %w", rel, err)
+		}
+		if err == nil && !remote.IsDir() && remote.Status.Size == entry.Size &&
+			!info.ModTime().After(remote.Status.ModTime().Add(syncMtimeSlack)) {
+			s.next.Entries[rel] = entry
+			s.result.Unchanged++
+			return s.cp.record(rel)
+		}
+	}
+
+	checksum, err := s.upload(rel, localPath, info)
+	if err != nil {
+		return err
+	}
+
+	entry.Checksum = checksum
+	s.next.Entries[rel] = entry
+	s.result.Uploaded++
+	s.result.Bytes += entry.Size
+
+	return s.cp.record(rel)
+}
+
+func (s *syncer) upload(rel string, localPath string, info fs.FileInfo) (checksum string, err error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", xerrors.Errorf("sync open %s error: %w", rel, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	remotePath := s.remotePath(rel)
+
+	if err := s.tp.PutObject(s.ctx, remotePath, io.TeeReader(file, hash), PutSizeHint(info.Size())); err != nil {
+		return "", xerrors.Errorf("sync upload %s error: %w", rel, err)
+	}
+
+	// keeping the local mtime lets runs without state detect unchanged
+	// objects
+	if !s.utimeUnsupported {
+		err := s.tp.Utime(s.ctx, remotePath, info.ModTime(), info.ModTime())
+		if errors.Is(err, ErrNotSupported) {
+			s.utimeUnsupported = true
+		} else if err != nil {
+			return "", xerrors.Errorf("sync utime %s error: %w", rel, err)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// deleteExtraneous deletes the remote entries which are not in the local
+// tree. With a previous state only the entries recorded in it are
+// considered, otherwise the remote tree is listed.
+func (s *syncer) deleteExtraneous() error {
+	extraneous := []string{}
+
+	if s.hasPrev {
+		for rel := range s.prev.Entries {
+			if _, ok := s.next.Entries[rel]; !ok {
+				extraneous = append(extraneous, rel)
+			}
+		}
+	} else {
+		prefix := strings.TrimSuffix(s.remote, "/") + "/"
+		err := s.tp.ListRecursive(s.ctx, s.remote, nil, func(entry WalkEntry) error {
+			rel := strings.TrimPrefix(entry.Path, prefix)
+			if _, ok := s.next.Entries[rel]; !ok {
+				extraneous = append(extraneous, rel)
+				return fs.SkipDir
+			}
+			return nil
+		})
+		// in dry-run mode the remote directory may not have been created
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return xerrors.Errorf("sync list error: %w", err)
+		}
+	}
+
+	// parents sort before their children, which are deleted with them
+	sort.Strings(extraneous)
+	deleted := ""
+	for _, rel := range extraneous {
+		if deleted != "" && strings.HasPrefix(rel, deleted+"/") {
+			continue
+		}
+
+		err := s.tp.DeleteTree(s.ctx, s.remotePath(rel))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return xerrors.Errorf("sync delete %s error: %w", rel, err)
+		}
+		deleted = rel
+		if err == nil {
+			s.result.Deleted++
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) run(mirror bool) (SyncResult, error) {
+	err := s.cp.finish(s.sync(mirror))
+	return s.result, err
+}
+
+func (s *syncer) sync(mirror bool) error {
+	if err := s.syncDir(); err != nil {
+		return err
+	}
+
+	if mirror {
+		if err := s.deleteExtraneous(); err != nil {
+			return err
+		}
+	}
+
+	// a dry run's state would list files which were never uploaded
+	if s.opts.StateFile != "" && s.tp.dryRun == nil {
+		if err := s.next.save(s.opts.StateFile); err != nil {
+			return xerrors.Errorf("sync save state error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SyncDir uploads the files in localDir which are missing or changed in
+// remoteDir, creating directories as needed. Without a state file, an object
+// is considered unchanged if it has the same size as the local file and is
+// at most a second older. Uploaded objects get the local mtime if the
+// appliance supports utime. Remote entries missing locally are kept, see
+// Mirror.
+func (tp *TriparClient) SyncDir(ctx context.Context, localDir string, remoteDir string, opts *SyncOptions) (result SyncResult, err error) {
+	defer tp.observe(ctx, "SyncDir", remoteDir, time.Now(), &err)
+
+	s, err := tp.newSyncer(ctx, localDir, remoteDir, opts)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	return s.run(false)
+}
+
+// Mirror is like SyncDir but also deletes remote entries which are missing
+// locally, with DeleteTree. With a state file, only entries recorded by the
+// previous run are deleted, so entries created in remoteDir by others are
+// kept.
+func (tp *TriparClient) Mirror(ctx context.Context, localDir string, remoteDir string, opts *SyncOptions) (result SyncResult, err error) {
+	defer tp.observe(ctx, "Mirror", remoteDir, time.Now(), &err)
+
+	s, err := tp.newSyncer(ctx, localDir, remoteDir, opts)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	return s.run(true)
+}
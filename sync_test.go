@@ -0,0 +1,155 @@
+package triparclient_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("SyncDir", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var local string
+	var stateFile string
+
+	writeLocal := func(rel string, content string) {
+		p := filepath.Join(local, rel)
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		Expect(os.WriteFile(p, []byte(content), 0o644)).To(Succeed())
+	}
+
+	puts := func() (n int) {
+		for _, req := range fake.Requests() {
+			if req == "PUT /remote/a" || req == "PUT /remote/dir/b" {
+				n++
+			}
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		var err error
+		local, err = os.MkdirTemp("", "triparclient-sync")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, local)
+		stateDir, err := os.MkdirTemp("", "triparclient-sync-state")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, stateDir)
+		stateFile = filepath.Join(stateDir, "state.json")
+
+		writeLocal("a", "aaa")
+		writeLocal("dir/b", "bbbbb")
+	})
+
+	It("should upload new and changed files", func() {
+		result, err := client.SyncDir(ctx, local, "/remote", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Uploaded: 2, Bytes: 8}))
+		data, _ := fake.File("/remote/dir/b")
+		Expect(string(data)).To(Equal("bbbbb"))
+
+		result, err = client.SyncDir(ctx, local, "/remote", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Unchanged: 2}))
+		Expect(puts()).To(Equal(2))
+
+		writeLocal("a", "changed")
+		result, err = client.SyncDir(ctx, local, "/remote", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Uploaded: 1, Unchanged: 1, Bytes: 7}))
+		data, _ = fake.File("/remote/a")
+		Expect(string(data)).To(Equal("changed"))
+	})
+
+	It("should skip unchanged files without requests using the state file", func() {
+		opts := &SyncOptions{StateFile: stateFile}
+		_, err := client.SyncDir(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		before := len(fake.Requests())
+		result, err := client.SyncDir(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Unchanged: 2}))
+		// only the remote root is created
+		Expect(fake.Requests()[before:]).To(Equal([]string{"PUT /remote mkdir"}))
+	})
+
+	It("should not upload touched files with the same checksum", func() {
+		opts := &SyncOptions{StateFile: stateFile}
+		_, err := client.SyncDir(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(filepath.Join(local, "a"), later, later)).To(Succeed())
+		result, err := client.SyncDir(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Unchanged: 2}))
+
+		writeLocal("a", "AAA")
+		result, err = client.SyncDir(ctx, local, "/remote", opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SyncResult{Uploaded: 1, Unchanged: 1, Bytes: 3}))
+	})
+
+	It("should ignore the state of other directories", func() {
+		_, err := client.SyncDir(ctx, local, "/remote", &SyncOptions{StateFile: stateFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := client.SyncDir(ctx, local, "/other", &SyncOptions{StateFile: stateFile})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Uploaded).To(Equal(2))
+	})
+
+	Describe("Mirror", func() {
+		It("should delete remote entries missing locally", func() {
+			_, err := client.Mirror(ctx, local, "/remote", nil)
+			Expect(err).NotTo(HaveOccurred())
+			fake.PutFile("/remote/extra", "x")
+			Expect(os.RemoveAll(filepath.Join(local, "dir"))).To(Succeed())
+
+			result, err := client.Mirror(ctx, local, "/remote", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(SyncResult{Unchanged: 1, Deleted: 2}))
+			Expect(fake.Exists("/remote/dir")).To(BeFalse())
+			Expect(fake.Exists("/remote/extra")).To(BeFalse())
+			Expect(fake.Exists("/remote/a")).To(BeTrue())
+		})
+
+		It("should only delete entries of the previous run with the state file", func() {
+			opts := &SyncOptions{StateFile: stateFile}
+			_, err := client.Mirror(ctx, local, "/remote", opts)
+			Expect(err).NotTo(HaveOccurred())
+			fake.PutFile("/remote/extra", "x")
+			Expect(os.RemoveAll(filepath.Join(local, "dir"))).To(Succeed())
+
+			result, err := client.Mirror(ctx, local, "/remote", opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(SyncResult{Unchanged: 1, Deleted: 1}))
+			Expect(fake.Exists("/remote/dir")).To(BeFalse())
+			Expect(fake.Exists("/remote/dir/b")).To(BeFalse())
+			Expect(fake.Exists("/remote/extra")).To(BeTrue())
+		})
+
+		It("should not count entries which were already deleted", func() {
+			opts := &SyncOptions{StateFile: stateFile}
+			_, err := client.Mirror(ctx, local, "/remote", opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.RemoveAll(filepath.Join(local, "dir"))).To(Succeed())
+			Expect(client.DeleteTree(ctx, "/remote/dir")).To(Succeed())
+
+			result, err := client.Mirror(ctx, local, "/remote", opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(SyncResult{Unchanged: 1}))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package triparclient
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// requestTimeout cancels the request if no progress was made for the given
+// duration, first while waiting for the response and then between reads of
+// the response body.
+type requestTimeout struct {
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	expired int32
+}
+
+func newRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, *requestTimeout) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	t := &requestTimeout{
+		timeout: timeout,
+		cancel:  cancel,
+	}
+
+	t.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&t.expired, 1)
+		cancel()
+	})
+
+	return ctx, t
+}
+
+func (t *requestTimeout) err(err error) error {
+	if err != nil && atomic.LoadInt32(&t.expired) == 1 {
+		return xerrors.Errorf("request timed out after %s: %w", t.timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+func (t *requestTimeout) stop() {
+	t.timer.Stop()
+	t.cancel()
+}
+
+type timeoutReadCloser struct {
+	io.ReadCloser
+	t *requestTimeout
+}
+
+func (r *timeoutReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.t.timer.Reset(r.t.timeout)
+	}
+	if err == io.EOF {
+		return n, err
+	}
+	return n, r.t.err(err)
+}
+
+func (r *timeoutReadCloser) Close() error {
+	r.t.stop()
+	return r.ReadCloser.Close()
+}
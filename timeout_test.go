@@ -0,0 +1,78 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+type stallingReader struct {
+	ctx  context.Context
+	data io.Reader
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err != io.EOF {
+		return n, err
+	}
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+var _ = Describe("DefaultTimeout", func() {
+	It("should time out a request if context has no deadline", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}))
+		client.DefaultTimeout = 50 * time.Millisecond
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should not time out a request if context has a deadline", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			time.Sleep(100 * time.Millisecond)
+			return testStatResponse(5), nil
+		}))
+		client.DefaultTimeout = 50 * time.Millisecond
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		info, err := client.Stat(ctx, "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Size).To(Equal(int64(5)))
+	})
+
+	It("should time out a stalled response body", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if strings.Contains(r.URL.RawQuery, "cmd=stat") {
+				return testStatResponse(10), nil
+			}
+			rsp := testResponse(http.StatusOK, "application/octet-stream", "")
+			rsp.Body = io.NopCloser(&stallingReader{
+				ctx:  r.Context(),
+				data: strings.NewReader("12345"),
+			})
+			return rsp, nil
+		}))
+		client.DefaultTimeout = 50 * time.Millisecond
+
+		reader, _, err := client.GetObject(context.Background(), "/object", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+
+		data, err := ioutil.ReadAll(reader)
+		Expect(string(data)).To(Equal("12345"))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
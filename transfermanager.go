@@ -0,0 +1,349 @@
+package triparclient
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type TransferManagerOptions struct {
+	// Concurrency is the maximum number of chunk requests in flight across
+	// all transfers, 0 means unlimited. A GetObject reader holds a slot until
+	// it is closed, so piping a download into an upload needs at least two.
+	Concurrency int
+
+	// BytesPerSecond limits the combined bandwidth of all transfers, 0 means
+	// unlimited.
+	BytesPerSecond int64
+
+	// MemoryLimit creates a MemoryBudget of that many bytes, which is used
+	// by clients without their own MemoryBudget. 0 means unlimited.
+	MemoryLimit int64
+}
+
+// TransferManager schedules the chunk requests of all GetObject and PutObject
+// operations of the clients which share it, see TriparClient.TransferManager.
+// A chunk request waits for a free slot, and slots are handed to the waiting
+// transfers in turns, so a transfer with many parallel chunks can't starve
+// the others. Transfers with PriorityInteractive are always served before
+// waiting transfers with PriorityBackground, see WithTransferPriority.
+// Request and response bodies are throttled to the bandwidth limit.
+type TransferManager struct {
+	concurrency int
+	limiter     *rateLimiter
+	memory      *MemoryBudget
+
+	mx      sync.Mutex
+	active  int
+	waiting [priorityCount][]*managedTransfer
+}
+
+type TransferManagerStats struct {
+	// Transfers is the number of operations which have chunk requests
+	// waiting for a slot.
+	Transfers int
+
+	// Active and Waiting are the numbers of chunk requests in flight and
+	// waiting for a slot.
+	Active  int
+	Waiting int
+}
+
+func NewTransferManager(opts TransferManagerOptions) *TransferManager {
+	tm := &TransferManager{
+		concurrency: opts.Concurrency,
+	}
+	if opts.BytesPerSecond > 0 {
+		tm.limiter = newRateLimiter(float64(opts.BytesPerSecond))
+	}
+	if opts.MemoryLimit > 0 {
+		tm.memory = NewMemoryBudget(opts.MemoryLimit)
+	}
+	return tm
+}
+
+// MemoryBudget returns the budget created for MemoryLimit, or nil.
+func (tm *TransferManager) MemoryBudget() *MemoryBudget {
+	return tm.memory
+}
+
+func (tm *TransferManager) Stats() TransferManagerStats {
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	stats := TransferManagerStats{
+		Active: tm.active,
+	}
+	for _, waiting := range tm.waiting {
+		stats.Transfers += len(waiting)
+		for _, t := range waiting {
+			stats.Waiting += len(t.waiters)
+		}
+	}
+	return stats
+}
+
+// managedTransfer is a single GetObject or PutObject operation.
+type managedTransfer struct {
+	priority Priority
+	waiters  []chan struct{}
+}
+
+// acquire waits for a slot for a chunk request of t.
+func (tm *TransferManager) acquire(ctx context.Context, t *managedTransfer) error {
+	if tm.concurrency <= 0 {
+		return nil
+	}
+
+	tm.mx.Lock()
+	if tm.active < tm.concurrency && !tm.hasWaiting() {
+		tm.active++
+		tm.mx.Unlock()
+		return nil
+	}
+	granted := make(chan struct{}, 1)
+	if len(t.waiters) == 0 {
+		tm.waiting[t.priority] = append(tm.waiting[t.priority], t)
+	}
+	t.waiters = append(t.waiters, granted)
+	tm.mx.Unlock()
+
+	select {
+	case <-granted:
+		return nil
+	case <-ctx.Done():
+	}
+
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	for i, waiter := range t.waiters {
+		if waiter == granted {
+			t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+			if len(t.waiters) == 0 {
+				tm.dequeue(t)
+			}
+			return ctx.Err()
+		}
+	}
+
+	// the slot was granted concurrently
+	tm.active--
+	tm.dispatch()
+	return ctx.Err()
+}
+
+func (tm *TransferManager) release() {
+	if tm.concurrency <= 0 {
+		return
+	}
+
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	tm.active--
+	tm.dispatch()
+}
+
+// hasWaiting reports whether any transfer waits for a slot. It must be
+// called with mx held.
+func (tm *TransferManager) hasWaiting() bool {
+	for _, waiting := range tm.waiting {
+		if len(waiting) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch hands free slots to waiting transfers in turns, starting with the
+// highest priority. It must be called with mx held.
+func (tm *TransferManager) dispatch() {
+	for priority := range tm.waiting {
+		for tm.active < tm.concurrency && len(tm.waiting[priority]) > 0 {
+			t := tm.waiting[priority][0]
+			tm.waiting[priority] = tm.waiting[priority][1:]
+
+			granted := t.waiters[0]
+			t.waiters = t.waiters[1:]
+			if len(t.waiters) > 0 {
+				tm.waiting[priority] = append(tm.waiting[priority], t)
+			}
+
+			tm.active++
+			granted <- struct{}{}
+		}
+	}
+}
+
+// dequeue removes t from the waiting transfers. It must be called with mx
+// held.
+func (tm *TransferManager) dequeue(t *managedTransfer) {
+	waiting := tm.waiting[t.priority]
+	for i, other := range waiting {
+		if other == t {
+			tm.waiting[t.priority] = append(waiting[:i], waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// beginTransfer returns a context which attributes chunk requests to a new
+// transfer, unless ctx already belongs to one.
+func (tp *TriparClient) beginTransfer(ctx context.Context) context.Context {
+	if tp.TransferManager == nil || ctx == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(managedTransferContextKey).(*managedTransfer); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, managedTransferContextKey, &managedTransfer{
+		priority: TransferPriority(ctx),
+	})
+}
+
+// unscheduled returns a context whose requests bypass the TransferManager's
+// slots and bandwidth limit, for requests made while the transfer already
+// holds a slot.
+func unscheduled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, managedTransferContextKey, (*managedTransfer)(nil))
+}
+
+// scheduleChunk waits for the TransferManager to admit a chunk request. The
+// returned function must be called once the request is complete.
+func (tp *TriparClient) scheduleChunk(ctx context.Context) (release func(), err error) {
+	tm := tp.TransferManager
+	if tm == nil || ctx == nil {
+		return func() {}, nil
+	}
+
+	t, ok := ctx.Value(managedTransferContextKey).(*managedTransfer)
+	if ok && t == nil {
+		return func() {}, nil
+	}
+	if t == nil {
+		// a chunk request outside of GetObject and PutObject
+		t = &managedTransfer{priority: TransferPriority(ctx)}
+	}
+
+	if err := tm.acquire(ctx, t); err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(tm.release)
+	}, nil
+}
+
+// throttle limits reading from rd to the TransferManager's bandwidth.
+func (tp *TriparClient) throttle(ctx context.Context, rd io.Reader) io.Reader {
+	if tp.TransferManager == nil || tp.TransferManager.limiter == nil || rd == nil {
+		return rd
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t, ok := ctx.Value(managedTransferContextKey).(*managedTransfer); ok && t == nil {
+		return rd
+	}
+	return newThrottledReader(ctx, rd, tp.TransferManager.limiter)
+}
+
+func newThrottledReader(ctx context.Context, rd io.Reader, limiter *rateLimiter) io.Reader {
+	throttled := &throttledReader{
+		reader:  rd,
+		limiter: limiter,
+		ctx:     ctx,
+	}
+	if _, ok := rd.(io.Seeker); ok {
+		// retries rewind request bodies
+		return &throttledReadSeeker{throttled}
+	}
+	return throttled
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter *rateLimiter
+	ctx     context.Context
+}
+
+func (r *throttledReader) Read(p []byte) (n int, err error) {
+	if max := r.limiter.burst(); int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err = r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, int64(n)); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+type throttledReadSeeker struct {
+	*throttledReader
+}
+
+func (r *throttledReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.(io.Seeker).Seek(offset, whence)
+}
+
+type throttledReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// rateLimiter is a token bucket, e.g. of bytes, which allows bursts of a
+// tenth of a second.
+type rateLimiter struct {
+	mx     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{
+		rate: perSecond,
+		last: time.Now(),
+	}
+}
+
+func (l *rateLimiter) burst() int64 {
+	burst := int64(l.rate / 10)
+	if burst < 1 {
+		burst = 1
+	}
+	return burst
+}
+
+// wait takes n tokens and waits until the bucket is no longer in debt.
+func (l *rateLimiter) wait(ctx context.Context, n int64) error {
+	l.mx.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if max := float64(l.burst()); l.tokens > max {
+		l.tokens = max
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	debt := -l.tokens
+	l.mx.Unlock()
+
+	if debt <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(debt / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package triparclient
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransferManager scheduling", func() {
+	It("should hand out slots to transfers in turns", func() {
+		ctx := context.Background()
+		tm := NewTransferManager(TransferManagerOptions{Concurrency: 1})
+		a := &managedTransfer{}
+		b := &managedTransfer{}
+
+		Expect(tm.acquire(ctx, a)).To(Succeed())
+
+		granted := make(chan string, 4)
+		wait := func(name string, t *managedTransfer) {
+			go func() {
+				defer GinkgoRecover()
+				Expect(tm.acquire(ctx, t)).To(Succeed())
+				granted <- name
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			wait("a", a)
+		}
+		Eventually(func() int { return tm.Stats().Waiting }).Should(Equal(3))
+		wait("b", b)
+		Eventually(func() int { return tm.Stats().Waiting }).Should(Equal(4))
+
+		order := []string{}
+		for i := 0; i < 4; i++ {
+			tm.release()
+			order = append(order, <-granted)
+		}
+		Expect(order).To(Equal([]string{"a", "b", "a", "a"}))
+
+		tm.release()
+		Expect(tm.Stats()).To(Equal(TransferManagerStats{}))
+	})
+
+	It("should serve interactive transfers before background transfers", func() {
+		ctx := context.Background()
+		tm := NewTransferManager(TransferManagerOptions{Concurrency: 1})
+		background := &managedTransfer{priority: PriorityBackground}
+		interactive := &managedTransfer{priority: PriorityInteractive}
+
+		Expect(tm.acquire(ctx, background)).To(Succeed())
+
+		granted := make(chan string, 4)
+		wait := func(name string, t *managedTransfer, waiting int) {
+			go func() {
+				defer GinkgoRecover()
+				Expect(tm.acquire(ctx, t)).To(Succeed())
+				granted <- name
+			}()
+			Eventually(func() int { return tm.Stats().Waiting }).Should(Equal(waiting))
+		}
+		wait("background", background, 1)
+		wait("background", background, 2)
+		wait("interactive", interactive, 3)
+		wait("interactive", interactive, 4)
+
+		order := []string{}
+		for i := 0; i < 4; i++ {
+			tm.release()
+			order = append(order, <-granted)
+		}
+		Expect(order).To(Equal([]string{"interactive", "interactive", "background", "background"}))
+
+		tm.release()
+		Expect(tm.Stats()).To(Equal(TransferManagerStats{}))
+	})
+
+	It("should use the priority of the context", func() {
+		client := &TriparClient{TransferManager: NewTransferManager(TransferManagerOptions{})}
+
+		ctx := client.beginTransfer(context.Background())
+		Expect(ctx.Value(managedTransferContextKey).(*managedTransfer).priority).To(Equal(PriorityInteractive))
+
+		ctx = client.beginTransfer(WithTransferPriority(context.Background(), PriorityBackground))
+		Expect(ctx.Value(managedTransferContextKey).(*managedTransfer).priority).To(Equal(PriorityBackground))
+	})
+
+	It("should give up waiting when the context is done", func() {
+		tm := NewTransferManager(TransferManagerOptions{Concurrency: 1})
+		Expect(tm.acquire(context.Background(), &managedTransfer{})).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(tm.acquire(ctx, &managedTransfer{})).To(MatchError(context.Canceled))
+		Expect(tm.Stats()).To(Equal(TransferManagerStats{Active: 1}))
+	})
+})
@@ -0,0 +1,126 @@
+package triparclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("TransferManager", func() {
+	var ctx context.Context
+	var fake *fakeTripar
+	var inFlight int32
+	var maxInFlight int32
+
+	transport := funcTransport(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Query().Get("cmd") != "" {
+			return fake.RoundTrip(r)
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return fake.RoundTrip(r)
+	})
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", strings.Repeat("0123456789", 1000))
+
+		atomic.StoreInt32(&inFlight, 0)
+		atomic.StoreInt32(&maxInFlight, 0)
+	})
+
+	It("should limit concurrent chunk requests across clients", func() {
+		tm := NewTransferManager(TransferManagerOptions{Concurrency: 2})
+		clients := []*TriparClient{newTestClient(transport), newTestClient(transport)}
+		for _, client := range clients {
+			client.TransferManager = tm
+		}
+
+		done := make(chan error, 4)
+		for i := 0; i < 4; i++ {
+			client := clients[i%2]
+			name := filepath.Join(GinkgoT().TempDir(), "file")
+			go func() {
+				defer GinkgoRecover()
+				rd, err := client.OpenReader(ctx, "/root/object")
+				if err != nil {
+					done <- err
+					return
+				}
+				defer rd.Close()
+				file, err := os.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+				defer file.Close()
+				_, err = io.Copy(file, rd)
+				done <- err
+			}()
+		}
+		for i := 0; i < 4; i++ {
+			Expect(<-done).To(Succeed())
+		}
+
+		Expect(atomic.LoadInt32(&maxInFlight)).To(Equal(int32(2)))
+		Expect(tm.Stats()).To(Equal(TransferManagerStats{}))
+	})
+
+	It("should limit the bandwidth", func() {
+		client := newTestClient(transport)
+		client.TransferManager = NewTransferManager(TransferManagerOptions{BytesPerSecond: 20000})
+
+		start := time.Now()
+		rd, _, err := client.GetObject(ctx, "/root/object", nil)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rd.Close()).To(Succeed())
+		Expect(data).To(HaveLen(10000))
+
+		// the first 2000 bytes are a burst
+		Expect(time.Since(start)).To(BeNumerically(">=", 350*time.Millisecond))
+	})
+
+	It("should limit the bandwidth of copied ranges", func() {
+		client := newTestClient(transport)
+		client.TransferManager = NewTransferManager(TransferManagerOptions{BytesPerSecond: 20000})
+
+		start := time.Now()
+		Expect(client.CopyRange(ctx, "/root/object", 1, "/root/copy", 0, 9999)).To(Succeed())
+
+		// the range is read and written, and the first 2000 bytes are a burst
+		Expect(time.Since(start)).To(BeNumerically(">=", 850*time.Millisecond))
+		data, ok := fake.File("/root/copy")
+		Expect(ok).To(BeTrue())
+		Expect(data).To(HaveLen(9999))
+	})
+
+	It("should use its memory budget", func() {
+		tm := NewTransferManager(TransferManagerOptions{MemoryLimit: 1024})
+		client := newTestClient(transport)
+		client.TransferManager = tm
+
+		Expect(tm.MemoryBudget().Acquire(ctx, 1024)).To(Succeed())
+		tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err := client.PutObject(tctx, "/root/new", io.MultiReader(strings.NewReader("12345")))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
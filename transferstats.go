@@ -0,0 +1,65 @@
+package triparclient
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// TransferStats describes the requests made by a single operation, see
+// PutOptions.Stats and GetOptions.Stats.
+type TransferStats struct {
+	// BytesIn and BytesOut count the bodies of all requests, including
+	// metadata requests like Stat.
+	BytesIn  int64
+	BytesOut int64
+	Requests int64
+	Retries  int64
+
+	// Duration is the time from the start of the operation until it
+	// returned, or for GetObject until the reader was closed.
+	Duration time.Duration
+}
+
+// Throughput returns the number of bytes transferred per second.
+func (s TransferStats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.BytesIn+s.BytesOut) / s.Duration.Seconds()
+}
+
+// transferCounter counts the requests made with a context returned by
+// withTransferCounter.
+type transferCounter struct {
+	start    time.Time
+	bytesIn  int64
+	bytesOut int64
+	requests int64
+	retries  int64
+}
+
+func withTransferCounter(ctx context.Context) (context.Context, *transferCounter) {
+	counter := &transferCounter{
+		start: time.Now(),
+	}
+	return context.WithValue(ctx, transferCounterContextKey, counter), counter
+}
+
+func transferCounterFrom(ctx context.Context) *transferCounter {
+	if ctx == nil {
+		return nil
+	}
+	counter, _ := ctx.Value(transferCounterContextKey).(*transferCounter)
+	return counter
+}
+
+func (c *transferCounter) stats() TransferStats {
+	return TransferStats{
+		BytesIn:  atomic.LoadInt64(&c.bytesIn),
+		BytesOut: atomic.LoadInt64(&c.bytesOut),
+		Requests: atomic.LoadInt64(&c.requests),
+		Retries:  atomic.LoadInt64(&c.retries),
+		Duration: time.Since(c.start),
+	}
+}
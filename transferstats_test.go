@@ -0,0 +1,82 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("TransferStats", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+	var failStats int32
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = newFakeTripar()
+		failStats = 0
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Query().Get("cmd") == "stat" && atomic.AddInt32(&failStats, -1) >= 0 {
+				return testResponse(http.StatusServiceUnavailable, "text/plain", "unavailable"), nil
+			}
+			return fake.RoundTrip(r)
+		}))
+
+		fake.Mkdir("/root")
+	})
+
+	It("should collect upload stats", func() {
+		var stats TransferStats
+		data := strings.Repeat("x", 3000)
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString(data), PutStats(&stats))).To(Succeed())
+
+		Expect(stats.BytesOut).To(Equal(int64(3000)))
+		Expect(stats.Requests).To(Equal(int64(3)))
+		Expect(stats.Retries).To(Equal(int64(0)))
+		Expect(stats.Duration).To(BeNumerically(">", 0))
+		Expect(stats.Throughput()).To(BeNumerically(">", 0))
+	})
+
+	It("should collect chunked upload stats", func() {
+		var stats TransferStats
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutChunked(), PutStats(&stats))).To(Succeed())
+		Expect(stats.BytesOut).To(Equal(int64(5)))
+		Expect(stats.Requests).To(Equal(int64(1)))
+	})
+
+	It("should collect download stats once the reader is closed", func() {
+		fake.PutFile("/root/object", "12345")
+		client.RetryPolicy = &RetryPolicy{MaxAttempts: 3}
+		failStats = 1
+
+		var stats TransferStats
+		rd, _, err := client.GetObject(ctx, "/root/object", nil, GetStats(&stats))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.Requests).To(Equal(int64(0)))
+
+		data, err := io.ReadAll(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("12345"))
+		Expect(rd.Close()).To(Succeed())
+
+		Expect(stats.BytesIn).To(BeNumerically(">=", 5))
+		Expect(stats.Requests).To(Equal(int64(3)))
+		Expect(stats.Retries).To(Equal(int64(1)))
+	})
+
+	It("should collect stats of failed downloads", func() {
+		var stats TransferStats
+		_, _, err := client.GetObject(ctx, "/root/missing", nil, GetStats(&stats))
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(stats.Requests).To(Equal(int64(1)))
+	})
+})
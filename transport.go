@@ -0,0 +1,118 @@
+package triparclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// httpTransport returns a clone of the client's *http.Transport so that it can
+// be reconfigured without affecting other clients sharing the transport, e.g.
+// clients created with WithCredentials.
+func (tp *TriparClient) httpTransport() (*http.Transport, error) {
+	rt := tp.HTTPClient.Client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported transport: %T", rt)
+	}
+
+	return transport.Clone(), nil
+}
+
+// ownedTransport is a transport which a client created itself. It is shared
+// once the client is cloned, e.g. with WithCredentials.
+type ownedTransport struct {
+	transport *http.Transport
+	shared    atomic.Bool
+}
+
+// setHTTPTransport replaces the client's transport. owned is set for
+// transports created by the client. The idle connections of the replaced
+// transport are closed if the client created it and it is not shared, as
+// nothing else will reuse them. Transports supplied by the caller are never
+// closed.
+func (tp *TriparClient) setHTTPTransport(transport http.RoundTripper, owned *http.Transport) {
+	client := *tp.HTTPClient.Client
+	replaced := client.Transport
+	client.Transport = transport
+	tp.HTTPClient.Client = &client
+
+	if own := tp.ownTransport; own != nil && !own.shared.Load() {
+		if rt, ok := replaced.(*http.Transport); ok && rt == own.transport {
+			rt.CloseIdleConnections()
+		}
+	}
+
+	tp.ownTransport = nil
+	if owned != nil {
+		tp.ownTransport = &ownedTransport{transport: owned}
+	}
+}
+
+// EnableExpectContinue makes data uploads send Expect: 100-continue and wait
+// up to timeout for the appliance to accept the request before sending the
+// body, so uploads rejected because of quota or permissions don't transmit
+// the data. The client's transport is replaced with a reconfigured clone and
+// must be an *http.Transport.
+func (tp *TriparClient) EnableExpectContinue(timeout time.Duration) error {
+	if timeout <= 0 {
+		return xerrors.Errorf("invalid expect continue timeout: %s", timeout)
+	}
+
+	transport, err := tp.httpTransport()
+	if err != nil {
+		return xerrors.Errorf("enable expect continue error: %w", err)
+	}
+	transport.ExpectContinueTimeout = timeout
+
+	tp.setHTTPTransport(transport, transport)
+	tp.expectContinue = true
+
+	return nil
+}
+
+// SetTLSConfig replaces the TLS configuration of the client's transport, e.g.
+// to verify the appliance's certificate against a custom CA pool in RootCAs
+// or a ServerName. Setting InsecureSkipVerify disables the verification for
+// appliances with self-signed certificates. The config is cloned. The
+// client's transport is replaced with a reconfigured clone and must be an
+// *http.Transport.
+func (tp *TriparClient) SetTLSConfig(config *tls.Config) error {
+	transport, err := tp.httpTransport()
+	if err != nil {
+		return xerrors.Errorf("set tls config error: %w", err)
+	}
+	transport.TLSClientConfig = config.Clone()
+
+	tp.setHTTPTransport(transport, transport)
+
+	return nil
+}
+
+// SetClientCertificate makes the client authenticate with cert, for
+// appliances which require mutual TLS. The rest of the TLS configuration is
+// kept. The client's transport is replaced with a reconfigured clone and must
+// be an *http.Transport.
+func (tp *TriparClient) SetClientCertificate(cert tls.Certificate) error {
+	transport, err := tp.httpTransport()
+	if err != nil {
+		return xerrors.Errorf("set client certificate error: %w", err)
+	}
+	if transport.TLSClientConfig != nil {
+		transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+	} else {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+
+	tp.setHTTPTransport(transport, transport)
+
+	return nil
+}
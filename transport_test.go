@@ -0,0 +1,270 @@
+package triparclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("EnableExpectContinue", func() {
+	var server *httptest.Server
+	var client *TriparClient
+	var mx sync.Mutex
+	var expects []string
+	var reject bool
+	var received string
+
+	BeforeEach(func() {
+		expects = nil
+		reject = false
+		received = ""
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mx.Lock()
+			defer mx.Unlock()
+
+			expects = append(expects, r.Header.Get("Expect"))
+
+			if reject {
+				// the body is not read, so the server doesn't send 100 Continue
+				w.WriteHeader(http.StatusInsufficientStorage)
+				return
+			}
+
+			data, _ := io.ReadAll(r.Body)
+			received = string(data)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		var err error
+		client, err = NewTriparClient(server.URL, "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{
+			Transport: &http.Transport{},
+		}
+		Expect(client.EnableExpectContinue(5 * time.Second)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should send Expect: 100-continue with uploads", func() {
+		Expect(client.PutObject(context.Background(), "/object", bytes.NewBufferString("12345"))).To(Succeed())
+
+		mx.Lock()
+		defer mx.Unlock()
+		Expect(expects).To(Equal([]string{"100-continue"}))
+		Expect(received).To(Equal("12345"))
+	})
+
+	It("should fail rejected uploads", func() {
+		mx.Lock()
+		reject = true
+		mx.Unlock()
+
+		err := client.PutObject(context.Background(), "/object", strings.NewReader(strings.Repeat("x", 100000)))
+		Expect(err).To(MatchError(ErrNoSpace))
+	})
+
+	It("should not modify the original transport", func() {
+		transport := &http.Transport{}
+		other, err := NewTriparClient(server.URL, "user", "pass", "share", NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		other.HTTPClient.Client = &http.Client{
+			Transport: transport,
+		}
+
+		Expect(other.EnableExpectContinue(time.Second)).To(Succeed())
+		Expect(transport.ExpectContinueTimeout).To(BeZero())
+		Expect(other.HTTPClient.Client.Transport.(*http.Transport).ExpectContinueTimeout).To(Equal(time.Second))
+	})
+
+	It("should require an *http.Transport", func() {
+		other := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testStatResponse(5), nil
+		}))
+
+		Expect(other.EnableExpectContinue(time.Second)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("SetTLSConfig", func() {
+	var server *httptest.Server
+	var roots *x509.CertPool
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		DeferCleanup(server.Close)
+
+		roots = x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+	})
+
+	stat := func(client *TriparClient) error {
+		_, err := client.Stat(context.Background(), "/object")
+		return err
+	}
+
+	It("should verify certificates by default", func() {
+		client, err := NewTriparClientWithOptions(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(MatchError(ContainSubstring("certificate")))
+
+		client, err = NewTriparClient(server.URL, "user", "pass", "", nil, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(MatchError(ContainSubstring("certificate")))
+	})
+
+	It("should skip verification only if explicitly enabled", func() {
+		client, err := NewTriparClientWithOptions(server.URL, WithInsecureSkipVerify())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(Succeed())
+
+		client, err = NewTriparClientWithOptions(server.URL, WithTLSConfig(&tls.Config{ServerName: "tripar.invalid"}), WithInsecureSkipVerify())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(Succeed())
+
+		client, err = NewTriparClient(server.URL, "user", "pass", "", nil, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})).To(Succeed())
+		Expect(stat(client)).To(Succeed())
+	})
+
+	It("should verify certificates", func() {
+		client, err := NewTriparClientWithOptions(server.URL, WithTLSConfig(&tls.Config{RootCAs: roots}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(Succeed())
+
+		client, err = NewTriparClientWithOptions(server.URL, WithTLSConfig(&tls.Config{}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(MatchError(ContainSubstring("certificate")))
+	})
+
+	It("should verify the server name", func() {
+		client, err := NewTriparClient(server.URL, "user", "pass", "", nil, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.SetTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"})).To(Succeed())
+		Expect(stat(client)).To(Succeed())
+
+		Expect(client.SetTLSConfig(&tls.Config{RootCAs: roots, ServerName: "tripar.invalid"})).To(Succeed())
+		Expect(stat(client)).To(MatchError(ContainSubstring("certificate")))
+	})
+})
+
+var _ = Describe("SetClientCertificate", func() {
+	var server *httptest.Server
+	var cert tls.Certificate
+	var certFile string
+	var keyFile string
+
+	BeforeEach(func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).NotTo(HaveOccurred())
+		keyDer, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+		cert, err = tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err := os.MkdirTemp("", "triparclient-mtls")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		certFile = filepath.Join(dir, "client.crt")
+		keyFile = filepath.Join(dir, "client.key")
+		Expect(os.WriteFile(certFile, certPEM, 0o600)).To(Succeed())
+		Expect(os.WriteFile(keyFile, keyPEM, 0o600)).To(Succeed())
+
+		leaf, err := x509.ParseCertificate(der)
+		Expect(err).NotTo(HaveOccurred())
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(leaf)
+
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path": "/object", "status": {"mode": 33188, "size": 5}}`))
+		}))
+		server.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		server.StartTLS()
+		DeferCleanup(server.Close)
+	})
+
+	stat := func(client *TriparClient) error {
+		_, err := client.Stat(context.Background(), "/object")
+		return err
+	}
+
+	It("should authenticate with the client certificate", func() {
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+
+		client, err := NewTriparClient(server.URL, "user", "pass", "", nil, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.SetTLSConfig(&tls.Config{RootCAs: roots})).To(Succeed())
+		Expect(stat(client)).NotTo(Succeed())
+
+		Expect(client.SetClientCertificate(cert)).To(Succeed())
+		Expect(stat(client)).To(Succeed())
+	})
+
+	It("should keep the TLS config", func() {
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+
+		client, err := NewTriparClientWithOptions(
+			server.URL,
+			WithTLSConfig(&tls.Config{RootCAs: roots}),
+			WithClientCertificate(cert),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(Succeed())
+		Expect(client.HTTPClient.Client.Transport.(*http.Transport).TLSClientConfig.RootCAs).To(Equal(roots))
+	})
+
+	It("should load the client certificate from files", func() {
+		client, err := NewTriparClientWithOptions(server.URL, WithClientCertificateFiles(certFile, keyFile), WithInsecureSkipVerify())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat(client)).To(Succeed())
+
+		_, err = NewTriparClientWithOptions(server.URL, WithClientCertificateFiles(certFile, certFile))
+		Expect(err).To(HaveOccurred())
+	})
+})
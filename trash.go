@@ -0,0 +1,304 @@
+package triparclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// trashIDLayout is the layout of the time part of trash item IDs, which sorts
+// in deletion order.
+const trashIDLayout = "20060102T150405.000000000Z"
+
+// trashOriginName is the object in a trash item which holds the original
+// path of the deleted entry.
+const trashOriginName = ".origin"
+
+type TrashItem struct {
+	// ID identifies the item in RestoreFromTrash.
+	ID string
+
+	// Path is the original path of the deleted entry.
+	Path string
+
+	DeletedAt time.Time
+}
+
+func (tp *TriparClient) trashEnabled(path string) bool {
+	if tp.TrashDir == "" {
+		return false
+	}
+
+	trash := pathpkg.Clean("/" + tp.TrashDir)
+	path = pathpkg.Clean("/" + path)
+
+	return path != trash && !strings.HasPrefix(path, strings.TrimSuffix(trash, "/")+"/")
+}
+
+func (tp *TriparClient) trashItemPath(id string) string {
+	return joinPath(tp.TrashDir, id)
+}
+
+func newTrashID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return now.UTC().Format(trashIDLayout) + "-" + hex.EncodeToString(suffix), nil
+}
+
+// moveToTrash moves path into a new trash item, which is a directory holding
+// the entry and its original path.
+func (tp *TriparClient) moveToTrash(ctx context.Context, path string) error {
+	if tp.planned(ctx, PlannedOperation{Op: "MoveObject", Path: path, Destination: tp.TrashDir}) {
+		return nil
+	}
+
+	id, err := newTrashID(time.Now())
+	if err != nil {
+		return xerrors.Errorf("trash id error: %w", err)
+	}
+	item := tp.trashItemPath(id)
+
+	if err := tp.CreateDirectories(ctx, item); err != nil {
+		return xerrors.Errorf("trash create item error: %w", err)
+	}
+
+	origin := pathpkg.Clean("/" + path)
+	if err := tp.PutObject(ctx, joinPath(item, trashOriginName), bytes.NewBufferString(origin), PutSizeHint(int64(len(origin)))); err != nil {
+		tp.Purge(ctx, item, nil)
+		tp.DeleteDirectory(ctx, item)
+		return xerrors.Errorf("trash origin error: %w", err)
+	}
+
+	if err := tp.MoveObject(ctx, path, joinPath(item, pathpkg.Base(origin))); err != nil {
+		tp.Purge(ctx, item, nil)
+		tp.DeleteDirectory(ctx, item)
+		return xerrors.Errorf("trash move error: %w", err)
+	}
+
+	return nil
+}
+
+type DeleteTreeOptions struct {
+	// Checkpoint records the deleted children of the directory. Deleted
+	// entries are not listed again, so an interrupted DeleteTree continues
+	// with what is left when it is called again. With a checkpoint, it also
+	// succeeds if the directory itself was already deleted by the
+	// interrupted run.
+	Checkpoint Checkpoint
+
+	// CheckpointInterval is how often completed items are recorded,
+	// DefaultCheckpointInterval if 0.
+	CheckpointInterval time.Duration
+}
+
+// DeleteTree deletes an object or a directory with its contents. If TrashDir
+// is set, the entry is moved to the trash instead.
+func (tp *TriparClient) DeleteTree(ctx context.Context, path string) (err error) {
+	return tp.DeleteTreeWithOptions(ctx, path, nil)
+}
+
+func (tp *TriparClient) DeleteTreeWithOptions(ctx context.Context, path string, opts *DeleteTreeOptions) (err error) {
+	defer tp.observe(ctx, "DeleteTree", path, time.Now(), &err)
+
+	if opts == nil {
+		opts = &DeleteTreeOptions{}
+	}
+
+	cp, err := tp.newCheckpointer(opts.Checkpoint, opts.CheckpointInterval)
+	if err != nil {
+		return xerrors.Errorf("delete tree error: %w", err)
+	}
+
+	info, err := tp.Stat(ctx, path, StatSkipIdentity())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) && cp.resumed() {
+			return cp.finish(nil)
+		}
+		return xerrors.Errorf("delete tree stat error: %w", err)
+	}
+
+	if tp.trashEnabled(path) {
+		err = tp.moveToTrash(ctx, path)
+	} else if !info.IsDir() {
+		err = tp.deleteObject(ctx, path)
+	} else if opts.Checkpoint == nil {
+		if err := tp.Purge(ctx, path, nil); err != nil {
+			return xerrors.Errorf("delete tree purge error: %w", err)
+		}
+		err = tp.DeleteDirectory(ctx, path)
+	} else {
+		err = tp.deleteTreeChildren(ctx, path, cp)
+	}
+	if err == nil {
+		jobItemDone(ctx, 0)
+	}
+
+	return cp.finish(err)
+}
+
+// deleteTreeChildren deletes the children of path one by one, recording
+// each, and then path itself.
+func (tp *TriparClient) deleteTreeChildren(ctx context.Context, path string, cp *checkpointer) error {
+	entries, err := tp.List(ctx, path)
+	if err != nil {
+		return xerrors.Errorf("delete tree list error: %w", err)
+	}
+
+	for _, entry := range entries.Entries {
+		child := joinPath(path, entry.Name)
+
+		isDir, err := tp.entryIsDir(ctx, child, entry)
+		if err != nil {
+			return xerrors.Errorf("delete tree stat error: %w", err)
+		}
+
+		if isDir {
+			if err := tp.Purge(ctx, child, nil); err != nil {
+				return xerrors.Errorf("delete tree purge error: %w", err)
+			}
+			err = tp.DeleteDirectory(ctx, child)
+		} else {
+			err = tp.deleteObject(ctx, child)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		jobItemDone(ctx, 0)
+
+		if err := cp.record(child); err != nil {
+			return err
+		}
+	}
+
+	return tp.DeleteDirectory(ctx, path)
+}
+
+func (tp *TriparClient) trashItem(ctx context.Context, id string) (item TrashItem, err error) {
+	timePart, _, _ := strings.Cut(id, "-")
+	deletedAt, err := time.Parse(trashIDLayout, timePart)
+	if err != nil {
+		return TrashItem{}, xerrors.Errorf("invalid trash item %s: %w", id, ErrNotFound)
+	}
+
+	rd, _, err := tp.GetObject(ctx, joinPath(tp.trashItemPath(id), trashOriginName), nil)
+	if err != nil {
+		return TrashItem{}, xerrors.Errorf("trash item origin error: %w", err)
+	}
+	defer rd.Close()
+
+	origin, err := io.ReadAll(rd)
+	if err != nil {
+		return TrashItem{}, xerrors.Errorf("trash item origin error: %w", err)
+	}
+
+	return TrashItem{
+		ID:        id,
+		Path:      string(origin),
+		DeletedAt: deletedAt,
+	}, nil
+}
+
+// ListTrash returns the items in the trash, oldest first.
+func (tp *TriparClient) ListTrash(ctx context.Context) (items []TrashItem, err error) {
+	if tp.TrashDir == "" {
+		return nil, xerrors.Errorf("list trash: TrashDir is not set")
+	}
+
+	entries, err := tp.List(ctx, tp.TrashDir)
+	if errors.Is(err, ErrNotFound) {
+		return []TrashItem{}, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("list trash error: %w", err)
+	}
+
+	items = []TrashItem{}
+	for _, entry := range entries.Entries {
+		item, err := tp.trashItem(ctx, entry.Name)
+		if errors.Is(err, ErrNotFound) {
+			// not a trash item or one being created or removed concurrently
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+
+	return items, nil
+}
+
+// RestoreFromTrash moves a trash item's entry back to its original path,
+// creating missing parent directories. It fails with ErrAlreadyExists if the
+// original path exists.
+func (tp *TriparClient) RestoreFromTrash(ctx context.Context, id string) (err error) {
+	defer tp.observe(ctx, "RestoreFromTrash", "", time.Now(), &err)
+
+	if tp.TrashDir == "" {
+		return xerrors.Errorf("restore from trash: TrashDir is not set")
+	}
+
+	item, err := tp.trashItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	itemPath := tp.trashItemPath(id)
+
+	if err := tp.CreateDirectories(ctx, pathpkg.Dir(item.Path)); err != nil {
+		return xerrors.Errorf("restore from trash create directories error: %w", err)
+	}
+
+	if err := tp.Rename(ctx, joinPath(itemPath, pathpkg.Base(item.Path)), item.Path, nil); err != nil {
+		return xerrors.Errorf("restore from trash error: %w", err)
+	}
+
+	if err := tp.deleteObject(ctx, joinPath(itemPath, trashOriginName)); err != nil {
+		return xerrors.Errorf("restore from trash cleanup error: %w", err)
+	}
+
+	return tp.DeleteDirectory(ctx, itemPath)
+}
+
+// EmptyTrash permanently deletes trash items deleted more than olderThan ago,
+// or all items if olderThan is 0.
+func (tp *TriparClient) EmptyTrash(ctx context.Context, olderThan time.Duration) (err error) {
+	defer tp.observe(ctx, "EmptyTrash", "", time.Now(), &err)
+
+	items, err := tp.ListTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, item := range items {
+		if olderThan > 0 && !item.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		itemPath := tp.trashItemPath(item.ID)
+		if err := tp.Purge(ctx, itemPath, nil); err != nil {
+			return xerrors.Errorf("empty trash purge error: %w", err)
+		}
+		if err := tp.DeleteDirectory(ctx, itemPath); err != nil {
+			return xerrors.Errorf("empty trash delete error: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package triparclient_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("Trash", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/dir/sub")
+		fake.PutFile("/root/object", "12345")
+		fake.PutFile("/root/dir/sub/object", "123")
+	})
+
+	Describe("DeleteTree", func() {
+		It("should delete objects and directories", func() {
+			Expect(client.DeleteTree(ctx, "/root/object")).To(Succeed())
+			Expect(client.DeleteTree(ctx, "/root/dir")).To(Succeed())
+			Expect(fake.Exists("/root/object")).To(BeFalse())
+			Expect(fake.Exists("/root/dir")).To(BeFalse())
+			Expect(client.DeleteTree(ctx, "/root/dir")).To(MatchError(ErrNotFound))
+		})
+	})
+
+	Describe("with TrashDir", func() {
+		BeforeEach(func() {
+			client.TrashDir = "/root/.trash"
+		})
+
+		It("should move deleted entries to the trash and restore them", func() {
+			Expect(client.DeleteObject(ctx, "/root/object")).To(Succeed())
+			Expect(client.DeleteTree(ctx, "/root/dir")).To(Succeed())
+			Expect(fake.Exists("/root/object")).To(BeFalse())
+			Expect(fake.Exists("/root/dir")).To(BeFalse())
+
+			items, err := client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(HaveLen(2))
+			Expect(items[0].Path).To(Equal("/root/object"))
+			Expect(items[0].DeletedAt).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(items[1].Path).To(Equal("/root/dir"))
+
+			Expect(client.RestoreFromTrash(ctx, items[1].ID)).To(Succeed())
+			data, ok := fake.File("/root/dir/sub/object")
+			Expect(ok).To(BeTrue())
+			Expect(string(data)).To(Equal("123"))
+
+			fake.PutFile("/root/object", "new")
+			Expect(client.RestoreFromTrash(ctx, items[0].ID)).To(MatchError(ErrAlreadyExists))
+
+			items, err = client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+		})
+
+		It("should keep DeleteObject errors", func() {
+			Expect(client.DeleteObject(ctx, "/root/missing")).To(MatchError(ErrNotFound))
+			Expect(client.DeleteObject(ctx, "/root/dir")).To(MatchError(ErrNotAFile))
+
+			items, err := client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(BeEmpty())
+		})
+
+		It("should empty the trash", func() {
+			Expect(client.DeleteObject(ctx, "/root/object")).To(Succeed())
+			Expect(client.DeleteTree(ctx, "/root/dir")).To(Succeed())
+
+			Expect(client.EmptyTrash(ctx, time.Hour)).To(Succeed())
+			items, err := client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(HaveLen(2))
+
+			Expect(client.EmptyTrash(ctx, 0)).To(Succeed())
+			items, err = client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(BeEmpty())
+			Expect(fake.Exists("/root/.trash")).To(BeTrue())
+		})
+
+		It("should delete entries in the trash", func() {
+			Expect(client.DeleteObject(ctx, "/root/object")).To(Succeed())
+			items, err := client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.DeleteTree(ctx, "/root/.trash/"+items[0].ID)).To(Succeed())
+			items, err = client.ListTrash(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(BeEmpty())
+		})
+	})
+})
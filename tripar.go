@@ -7,12 +7,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	pathpkg "path"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	httpclient "github.com/koofr/go-httpclient"
 	ioutils "github.com/koofr/go-ioutils"
@@ -22,15 +27,138 @@ import (
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrNotAFile      = errors.New("not a file")
+	ErrNotADirectory = errors.New("not a directory")
+	ErrNotEmpty      = errors.New("directory not empty")
 	ErrAlreadyExists = errors.New("already exists")
 	ErrBadRange      = errors.New("bad range")
+	ErrNoSpace       = errors.New("no space left on device")
+	ErrNotSupported  = errors.New("operation not supported")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
 	ErrOther         = errors.New("unknown error")
 )
 
 type TriparClient struct {
-	HTTPClient   *httpclient.HTTPClient
-	bufferPool   BufferPoolIface
-	getChunkSize int64
+	HTTPClient *httpclient.HTTPClient
+
+	// UploadChunkSize is the number of bytes written per PUT/POST request. If
+	// it is 0, every BufferPool buffer is written with a separate request.
+	// Chunks larger than the buffer size are assembled from multiple buffers.
+	// If the pool runs out of buffers before a chunk is complete, the partial
+	// chunk is written so that its buffers are returned to the pool.
+	UploadChunkSize int64
+
+	// MaxObjectSize limits the size of objects written with PutObject, 0
+	// means unlimited. Once the reader returns more bytes, PutObject fails
+	// with an *ObjectTooLargeError and the partially written object is
+	// deleted.
+	MaxObjectSize int64
+
+	// DefaultTimeout is applied to requests whose context has no deadline.
+	// The request is cancelled if the response does not arrive in time or if
+	// reading the response body stalls for longer than the timeout.
+	DefaultTimeout time.Duration
+
+	// RunAsHeader is the header used to pass the user set with WithRunAsUser.
+	// It has no default, as the header depends on how impersonation is set
+	// up on the appliance. Requests with a run-as user fail with
+	// ErrRunAsHeaderNotConfigured if it is empty.
+	RunAsHeader string
+
+	// IdentityResolver is used by Stat to fill in user and group names.
+	IdentityResolver IdentityResolver
+
+	// RetryPolicy enables retrying failed requests. Only idempotent requests
+	// are retried unless RetryNonIdempotent is set.
+	RetryPolicy *RetryPolicy
+
+	// ObserveLatency is called after every operation which issues requests,
+	// with the operation's method name (e.g. "Stat") and its result. Composite
+	// operations like ListRecursive are observed through their requests.
+	ObserveLatency func(op string, d time.Duration, err error)
+
+	// ObserveContext is like ObserveLatency but also gets the operation's
+	// context, so Tags and RunAsUser can be used for attribution.
+	ObserveContext func(ctx context.Context, op string, d time.Duration, err error)
+
+	// TrashDir enables soft deletes. If it is set, DeleteObject and
+	// DeleteTree move entries into a new item in TrashDir instead of deleting
+	// them, see RestoreFromTrash and EmptyTrash. Entries already in TrashDir
+	// are deleted. Purge and DeleteDirectory are not affected.
+	TrashDir string
+
+	// CredentialsProvider is used to refresh the credentials when a request
+	// fails with 401. The request is then repeated once, unless its body can't
+	// be rewound.
+	CredentialsProvider CredentialsProvider
+
+	// NormalizeWindowsPaths makes the client accept paths from Windows
+	// tooling. Backslashes are converted to slashes and drive letters are
+	// stripped, e.g. `C:\data\file.txt` is sent as "/data/file.txt". Paths
+	// which can't be converted, like UNC paths or names containing a colon,
+	// fail with an *InvalidPathError without being sent.
+	NormalizeWindowsPaths bool
+
+	// StrictResponses enables validation of decoded responses. Responses
+	// with unknown fields, missing required fields or values of the wrong
+	// type fail with an *InvalidResponseError, which includes the raw body,
+	// instead of being decoded leniently.
+	StrictResponses bool
+
+	// MaxNameLength limits the length of path components in bytes, longer
+	// names fail with a *NameTooLongError without a request being sent.
+	// DefaultMaxNameLength is used if it is 0, a negative value disables the
+	// check.
+	MaxNameLength int
+
+	// MaxRequestSize limits the body size of the PUT and POST requests of
+	// PutObject, pieces and chunks are split into multiple requests
+	// accordingly. If the appliance rejects a write with 413 Request Entity
+	// Too Large, the client halves the limit for this and later uploads and
+	// repeats the write, see RequestSizeLimit. 0 means unlimited until a
+	// write is rejected. Chunked uploads are not split.
+	MaxRequestSize int64
+
+	// MemoryBudget limits the bytes of buffers held by transfers, across all
+	// clients sharing it. Uploads which copy the reader into pieces and
+	// downloads which copy through a pool buffer wait for the budget before
+	// taking a buffer, or fail with the context's error.
+	MemoryBudget *MemoryBudget
+
+	// TransferManager schedules the chunk requests of GetObject and
+	// PutObject across all clients sharing it. Its MemoryBudget is used if
+	// MemoryBudget is not set.
+	TransferManager *TransferManager
+
+	// RateLimiter limits the request rate and bandwidth of all requests
+	// across all clients sharing it.
+	RateLimiter *RateLimiter
+
+	// CircuitBreaker makes requests fail fast with a *CircuitOpenError while
+	// the appliance is failing.
+	CircuitBreaker *CircuitBreaker
+
+	// Hooks are called on requests, retries, chunks and operations, e.g. for
+	// custom telemetry or progress reporting.
+	Hooks Hooks
+
+	user           string
+	auth           *authState
+	expectContinue bool
+	bufferPool     BufferPoolIface
+	getChunkSize   int64
+	dialer         *dialer
+	ownTransport   *ownedTransport
+	stats          *clientStats
+	throughput     *throughputEstimator
+	caps           *capabilities
+	root           string
+	dryRun         func(ctx context.Context, op PlannedOperation)
+	statCache      *atomic.Pointer[statCache]
+	clock          *clockSkew
+	hostLimiter    *HostLimiter
+
+	learnedRequestSize *atomic.Int64
 }
 
 func basicAuth(user string, pass string) string {
@@ -39,12 +167,24 @@ func basicAuth(user string, pass string) string {
 
 func translateError(err *Error) error {
 	switch err.Code {
+	case 1, 13:
+		return ErrForbidden
 	case 2:
 		return ErrNotFound
 	case 17:
 		return ErrAlreadyExists
+	case 20:
+		return ErrNotADirectory
 	case 21:
 		return ErrNotAFile
+	case 28:
+		return ErrNoSpace
+	case 36:
+		return ErrNameTooLong
+	case 39:
+		return ErrNotEmpty
+	case 95:
+		return ErrNotSupported
 	case 10004:
 		return ErrBadRange
 	default:
@@ -52,6 +192,92 @@ func translateError(err *Error) error {
 	}
 }
 
+func translateStatus(status int) error {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusRequestedRangeNotSatisfiable:
+		return ErrBadRange
+	case http.StatusInsufficientStorage:
+		return ErrNoSpace
+	case http.StatusNotImplemented:
+		return ErrNotSupported
+	default:
+		return nil
+	}
+}
+
+// translatedError is a sentinel error translated from a tripar error, which
+// keeps the original *Error available to errors.As.
+type translatedError struct {
+	sentinel error
+	perr     *Error
+}
+
+func (e *translatedError) Error() string {
+	return e.sentinel.Error()
+}
+
+func (e *translatedError) Unwrap() []error {
+	return []error{e.sentinel, e.perr}
+}
+
+func triparError(perr *Error) error {
+	err := translateError(perr)
+	if err != error(perr) {
+		err = &translatedError{
+			sentinel: err,
+			perr:     perr,
+		}
+	}
+	return xerrors.Errorf("tripar error: %s: %w", perr.LMsg, err)
+}
+
+// asInvalidStatusError is like httpclient.IsInvalidStatusError but also
+// finds wrapped errors.
+func asInvalidStatusError(err error) (*httpclient.InvalidStatusError, bool) {
+	var ise httpclient.InvalidStatusError
+	if errors.As(err, &ise) {
+		return &ise, true
+	}
+	var iseptr *httpclient.InvalidStatusError
+	if errors.As(err, &iseptr) {
+		return iseptr, true
+	}
+	return nil, false
+}
+
+// translateRequestError translates tripar errors returned with an unexpected
+// HTTP status and maps the status to a sentinel error if there is no tripar
+// error body, as the server or an intermediary can fail without one.
+func translateRequestError(err error) error {
+	err = redactError(err)
+
+	ise, ok := asInvalidStatusError(err)
+	if !ok {
+		return err
+	}
+
+	if perr, jsonErr := UnmarshalError([]byte(ise.Content)); jsonErr == nil && perr != nil {
+		return triparError(perr)
+	}
+
+	if sentinel := translateStatus(ise.Got); sentinel != nil {
+		return fmt.Errorf("%w: %w", sentinel, err)
+	}
+
+	return err
+}
+
+// NewTriparClient creates a client for share at endpoint. Uploads read
+// pieces into buffers from bp; if bp is nil a pool of
+// DefaultBufferPoolCapacity buffers of DefaultBufferSize bytes is used.
+// Downloads of ranges larger than getChunkSize are split into requests of
+// getChunkSize bytes.
 func NewTriparClient(
 	endpoint string,
 	user string,
@@ -69,27 +295,300 @@ func NewTriparClient(
 
 	u, err := url.Parse(endpoint)
 	if err != nil {
-		return nil, err
+		return nil, redactError(err)
+	}
+
+	if bp == nil {
+		bp = NewBufferPool(DefaultBufferPoolCapacity, DefaultBufferSize)
 	}
 
-	client := httpclient.Insecure()
+	stats := newClientStats()
+	dialer := newDialer(stats)
+
+	// every client gets its own transport with a dialer counting connections
+	transport := httpclient.HttpTransport.Clone()
+	transport.DialContext = dialer.DialContext
+
+	client := httpclient.New()
+	client.Client = &http.Client{
+		Transport: transport,
+	}
 	client.BaseURL = u
 	client.Headers.Set("Authorization", basicAuth(user, pass))
 
 	tp = &TriparClient{
 		HTTPClient:   client,
+		user:         user,
+		auth:         &authState{},
 		bufferPool:   bp,
 		getChunkSize: getChunkSize,
+		dialer:       dialer,
+		ownTransport: &ownedTransport{transport: transport},
+		stats:        stats,
+		throughput:   &throughputEstimator{},
+		caps:         newCapabilities(),
+		statCache:    &atomic.Pointer[statCache]{},
+		clock:        newClockSkew(),
+
+		learnedRequestSize: &atomic.Int64{},
 	}
 
 	return tp, nil
 }
 
+func (tp *TriparClient) clone() *TriparClient {
+	c := *tp
+
+	// the clone shares the transport, so neither client may close it
+	if tp.ownTransport != nil {
+		tp.ownTransport.shared.Store(true)
+	}
+	c.ownTransport = nil
+
+	httpClient := *tp.HTTPClient
+	httpClient.Headers = tp.HTTPClient.Headers.Clone()
+	c.HTTPClient = &httpClient
+
+	return &c
+}
+
+func (tp *TriparClient) WithCredentials(user string, pass string) *TriparClient {
+	c := tp.clone()
+	c.HTTPClient.Headers.Set("Authorization", basicAuth(user, pass))
+	c.user = user
+	// explicit credentials replace refreshed ones
+	c.CredentialsProvider = nil
+	c.auth = &authState{}
+	return c
+}
+
+// reservedHeaders are set by the client or the transport and can't be set
+// with WithHeader.
+var reservedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Content-Length":      true,
+	"Content-Range":       true,
+	"Content-Type":        true,
+	"Content-Encoding":    true,
+	"Transfer-Encoding":   true,
+	"Expect":              true,
+	"Host":                true,
+	"Range":               true,
+}
+
+func (tp *TriparClient) setContextHeaders(req *httpclient.RequestData) error {
+	if req.Context == nil {
+		return nil
+	}
+
+	headers, _ := req.Context.Value(headersContextKey).(http.Header)
+	for key, values := range headers {
+		if reservedHeaders[key] || key == http.CanonicalHeaderKey(tp.RunAsHeader) {
+			continue
+		}
+		if _, ok := req.Headers[key]; ok {
+			// headers of the request itself, e.g. Range, take precedence
+			continue
+		}
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
+		req.Headers[key] = append([]string{}, values...)
+	}
+
+	if user := RunAsUser(req.Context); user != "" {
+		if tp.RunAsHeader == "" {
+			return ErrRunAsHeaderNotConfigured
+		}
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
+		req.Headers.Set(tp.RunAsHeader, user)
+	}
+
+	return nil
+}
+
+func (tp *TriparClient) observe(ctx context.Context, op string, path string, start time.Time, err *error) {
+	d := time.Since(start)
+	if tp.ObserveLatency != nil {
+		tp.ObserveLatency(op, d, *err)
+	}
+	if tp.ObserveContext != nil {
+		tp.ObserveContext(ctx, op, d, *err)
+	}
+	if tp.Hooks.OnOperationComplete != nil && tp.Hooks.Sampler.sample("operation", op, *err) {
+		tp.Hooks.OnOperationComplete(hookContext(ctx), OperationEvent{
+			Op:       op,
+			Path:     path,
+			Duration: d,
+			Err:      *err,
+		})
+	}
+}
+
 func (tp *TriparClient) request(req *httpclient.RequestData) (response *http.Response, err error) {
-	return tp.HTTPClient.Request(req)
+	if err := tp.checkPaths(req); err != nil {
+		return nil, err
+	}
+
+	if err := tp.confine(req); err != nil {
+		return nil, err
+	}
+
+	if err := tp.setContextHeaders(req); err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		defer tp.invalidateRequest(req)
+	}
+
+	first := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		response, err = tp.doAuthenticatedRequest(req)
+		tp.hookRequest(req, attempt, response, start, err)
+		if err == nil {
+			tp.retrySucceeded(req.Context)
+			return response, nil
+		}
+
+		event := RetryEvent{
+			Method:  req.Method,
+			Path:    tp.unroot(req.Path),
+			Cmd:     req.Params.Get("cmd"),
+			Attempt: attempt,
+			Err:     err,
+		}
+		backoff, ok := tp.shouldRetry(req, first, event)
+		if !ok {
+			return response, translateRequestError(err)
+		}
+		event.Backoff = backoff
+		tp.hookRetry(req.Context, event)
+
+		if waitErr := tp.retryWait(req.Context, req, backoff); waitErr != nil {
+			return response, translateRequestError(err)
+		}
+	}
+}
+
+func (tp *TriparClient) doRequest(req *httpclient.RequestData) (response *http.Response, err error) {
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	circuitDone, err := tp.circuitAllow(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tp.stats.request(req)
+
+	counter := transferCounterFrom(req.Context)
+	if counter != nil {
+		atomic.AddInt64(&counter.requests, 1)
+		if req.ReqContentLength > 0 {
+			atomic.AddInt64(&counter.bytesOut, req.ReqContentLength)
+		}
+	}
+
+	// the request is copied so that retries don't nest traces and throttled
+	// bodies
+	traceReq := *req
+	if err := tp.rateLimitRequest(ctx, &traceReq); err != nil {
+		circuitDone(nil)
+		return nil, err
+	}
+
+	releaseSlot, err := tp.acquireRequest(ctx)
+	if err != nil {
+		circuitDone(nil)
+		return nil, err
+	}
+
+	trace, releaseTrace := tp.stats.trace()
+	release := func() {
+		releaseTrace()
+		releaseSlot()
+	}
+	traceReq.Context = httptrace.WithClientTrace(ctx, trace)
+
+	start := time.Now()
+	response, err = tp.doRequestWithTimeout(&traceReq)
+	circuitDone(err)
+	if response != nil {
+		tp.clock.observe(start, time.Now(), response.Header.Get("Date"))
+	}
+	if err != nil {
+		release()
+
+		// httpclient closes the body of responses with an unexpected status but
+		// returns other failed responses, e.g. from post hooks, unclosed
+		if _, ok := asInvalidStatusError(err); !ok && response != nil && response.Body != nil {
+			response.Body.Close()
+		}
+		return nil, err
+	}
+
+	response.Body = &countingReadCloser{
+		ReadCloser: response.Body,
+		count:      &tp.stats.bytesIn,
+	}
+	if counter != nil {
+		response.Body = &countingReadCloser{
+			ReadCloser: response.Body,
+			count:      &counter.bytesIn,
+		}
+	}
+	response.Body = tp.rateLimitResponse(ctx, response.Body)
+	response.Body = &doneReadCloser{
+		ReadCloser: response.Body,
+		done:       release,
+	}
+
+	return response, nil
+}
+
+func (tp *TriparClient) doRequestWithTimeout(req *httpclient.RequestData) (response *http.Response, err error) {
+	if tp.DefaultTimeout <= 0 || req.Context == nil {
+		return tp.HTTPClient.Request(req)
+	}
+	if _, ok := req.Context.Deadline(); ok {
+		return tp.HTTPClient.Request(req)
+	}
+
+	ctx, timeout := newRequestTimeout(req.Context, tp.DefaultTimeout)
+	timeoutReq := *req
+	timeoutReq.Context = ctx
+
+	response, err = tp.HTTPClient.Request(&timeoutReq)
+	if err != nil {
+		timeout.stop()
+		return response, timeout.err(err)
+	}
+
+	timeout.timer.Reset(tp.DefaultTimeout)
+	response.Body = &timeoutReadCloser{
+		ReadCloser: response.Body,
+		t:          timeout,
+	}
+
+	return response, nil
 }
 
 func (tp *TriparClient) path(path string) string {
+	if tp.NormalizeWindowsPaths {
+		path = normalizeWindowsPath(path)
+	}
+	if tp.root != "" {
+		// cleaned and checked in confine
+		return tp.root + "/" + strings.TrimPrefix(path, "/")
+	}
 	if !strings.HasPrefix(path, "/") {
 		return "/" + path
 	}
@@ -102,7 +601,26 @@ func (tp *TriparClient) cmd(cmd string) (params url.Values) {
 	return params
 }
 
-func (tp *TriparClient) Stat(ctx context.Context, path string) (info Stat, err error) {
+func (tp *TriparClient) Stat(ctx context.Context, path string, options ...StatOption) (info Stat, err error) {
+	defer tp.observe(ctx, "Stat", path, time.Now(), &err)
+
+	opts := newStatOptions(options)
+
+	cache := tp.statCache.Load()
+	if cache != nil {
+		if entry, ok := cache.get(tp.statCacheKey(path)); ok {
+			if entry.notFound {
+				return Stat{}, xerrors.Errorf("stat cached error: %w", ErrNotFound)
+			}
+			info = entry.info
+			info.Path = tp.unroot(info.Path)
+			if !opts.SkipIdentity {
+				tp.resolveIdentity(ctx, &info)
+			}
+			return info, nil
+		}
+	}
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "GET",
@@ -111,17 +629,39 @@ func (tp *TriparClient) Stat(ctx context.Context, path string) (info Stat, err e
 		ExpectedStatus: []int{http.StatusOK},
 	})
 	if err != nil {
+		if cache != nil && errors.Is(err, ErrNotFound) {
+			cache.setNotFound(tp.statCacheKey(path))
+		}
 		return Stat{}, xerrors.Errorf("stat request error: %w", err)
 	}
 
-	if err := UnmarshalTriparResponse(rsp, &info); err != nil {
+	if err := tp.unmarshalResponse(rsp, &info); err != nil {
+		if cache != nil && errors.Is(err, ErrNotFound) {
+			cache.setNotFound(tp.statCacheKey(path))
+		}
 		return Stat{}, xerrors.Errorf("stat response error: %w", err)
 	}
 
+	if cache != nil {
+		cache.set(tp.statCacheKey(path), info)
+	}
+
+	info.Path = tp.unroot(info.Path)
+
+	if !opts.SkipIdentity {
+		tp.resolveIdentity(ctx, &info)
+	}
+
 	return info, nil
 }
 
 func (tp *TriparClient) DeleteDirectory(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "DeleteDirectory", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "DeleteDirectory", Path: path}) {
+		return nil
+	}
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "DELETE",
@@ -140,7 +680,23 @@ func (tp *TriparClient) DeleteDirectory(ctx context.Context, path string) (err e
 	return nil
 }
 
+// DeleteDirectoryIfExists is like DeleteDirectory but succeeds if the
+// directory does not exist. It still fails with ErrNotEmpty or
+// ErrNotADirectory.
+func (tp *TriparClient) DeleteDirectoryIfExists(ctx context.Context, path string) error {
+	if err := tp.DeleteDirectory(ctx, path); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
 func (tp *TriparClient) CreateDirectory(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "CreateDirectory", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "CreateDirectory", Path: path}) {
+		return nil
+	}
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "PUT",
@@ -160,6 +716,12 @@ func (tp *TriparClient) CreateDirectory(ctx context.Context, path string) (err e
 }
 
 func (tp *TriparClient) CreateDirectories(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "CreateDirectories", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "CreateDirectories", Path: path}) {
+		return nil
+	}
+
 	params := tp.cmd("mkdir")
 	params.Set("parents", "true")
 	rsp, err := tp.request(&httpclient.RequestData{
@@ -180,7 +742,58 @@ func (tp *TriparClient) CreateDirectories(ctx context.Context, path string) (err
 	return nil
 }
 
-func (tp *TriparClient) List(ctx context.Context, path string) (entries Entries, err error) {
+// CreateDirectoriesWithResult is like CreateDirectories but reports which
+// directories were created, parents first. Directories which were created
+// concurrently by someone else are not reported.
+func (tp *TriparClient) CreateDirectoriesWithResult(ctx context.Context, path string) (created []string, err error) {
+	// find the deepest existing ancestor, as mkdir with parents doesn't report
+	// which directories it created
+	missing := []string{}
+	full, err := tp.checkPath(tp.path(path))
+	if err != nil {
+		return nil, err
+	}
+	if full, err = tp.confinePath(full); err != nil {
+		return nil, err
+	}
+	for dir := tp.unroot(full); dir != "/"; dir = pathpkg.Dir(dir) {
+		info, err := tp.Stat(ctx, dir, StatSkipIdentity())
+		if err == nil {
+			if !info.IsDir() {
+				return nil, xerrors.Errorf("create directories %s error: %w", dir, ErrNotADirectory)
+			}
+			break
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		missing = append(missing, dir)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		err := tp.CreateDirectory(ctx, missing[i])
+		if errors.Is(err, ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return created, err
+		}
+		created = append(created, missing[i])
+	}
+
+	return created, nil
+}
+
+func (tp *TriparClient) List(ctx context.Context, path string, options ...ListOption) (entries Entries, err error) {
+	if len(options) > 0 {
+		return tp.listWithOptions(ctx, path, newListOptions(options))
+	}
+	return tp.list(ctx, path)
+}
+
+func (tp *TriparClient) list(ctx context.Context, path string) (entries Entries, err error) {
+	defer tp.observe(ctx, "List", path, time.Now(), &err)
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "GET",
@@ -192,38 +805,155 @@ func (tp *TriparClient) List(ctx context.Context, path string) (entries Entries,
 		return Entries{}, xerrors.Errorf("list request error: %w", err)
 	}
 
-	if err := UnmarshalTriparResponse(rsp, &entries); err != nil {
+	if err := tp.unmarshalResponse(rsp, &entries); err != nil {
 		return Entries{}, xerrors.Errorf("list response error: %w", err)
 	}
 
 	return entries, nil
 }
 
+func (tp *TriparClient) ListPrefix(ctx context.Context, path string, prefix string) (entries Entries, err error) {
+	// ls has no server-side filtering, so entries are filtered after listing
+	all, err := tp.List(ctx, path)
+	if err != nil {
+		return Entries{}, err
+	}
+
+	entries.Entries = []Entry{}
+	for _, entry := range all.Entries {
+		if strings.HasPrefix(entry.Name, prefix) {
+			entries.Entries = append(entries.Entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
 func (tp *TriparClient) GetObject(
 	ctx context.Context,
 	path string,
 	span *ioutils.FileSpan,
+	options ...GetOption,
+) (rd io.ReadCloser, info *Stat, err error) {
+	return tp.getObject(ctx, path, span, newGetOptions(options))
+}
+
+// GetObjectWithOptions reads the object at path like GetObject.
+//
+// Deprecated: use GetObject with GetOption values, e.g. GetChunkSize.
+func (tp *TriparClient) GetObjectWithOptions(
+	ctx context.Context,
+	path string,
+	span *ioutils.FileSpan,
+	opts *GetOptions,
+) (rd io.ReadCloser, info *Stat, err error) {
+	return tp.getObject(ctx, path, span, opts)
+}
+
+func (tp *TriparClient) getObject(
+	ctx context.Context,
+	path string,
+	span *ioutils.FileSpan,
+	opts *GetOptions,
 ) (rd io.ReadCloser, info *Stat, err error) {
+	defer tp.observe(ctx, "GetObject", path, time.Now(), &err)
+
+	ctx = tp.beginTransfer(ctx)
+
+	if opts == nil {
+		opts = &GetOptions{}
+	}
+	if opts.ChunkSize < 0 {
+		return nil, nil, xerrors.Errorf("get object invalid chunk size: %d", opts.ChunkSize)
+	}
+	chunkSize := tp.getChunkSize
+	if opts.ChunkSize > 0 {
+		chunkSize = opts.ChunkSize
+	}
+
+	transferDone := tp.stats.startTransfer()
+	if opts.Stats != nil {
+		var counter *transferCounter
+		ctx, counter = withTransferCounter(ctx)
+		statsDone := transferDone
+		transferDone = func() {
+			statsDone()
+			*opts.Stats = counter.stats()
+		}
+	}
+	defer func() {
+		if err != nil {
+			transferDone()
+		} else {
+			rd = &doneReadCloser{
+				ReadCloser: rd,
+				done:       transferDone,
+			}
+		}
+	}()
+
 	stat, err := tp.Stat(ctx, path)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("get object stat error: %w", err)
 	}
 
-	if span == nil || span.End-span.Start <= tp.getChunkSize {
-		rd, err = tp.getObjectComplete(ctx, path, span, stat)
+	if opts.CacheDir != "" {
+		rd, err = tp.getObjectCached(ctx, path, span, &stat, chunkSize, opts.CacheDir)
+	} else {
+		rd, err = tp.getObjectUncached(ctx, path, span, &stat, chunkSize)
+		if err == nil && opts.VerifyChunks {
+			offset := int64(0)
+			if span != nil {
+				offset = span.Start
+			}
+			rd = tp.newChunkVerifyReader(ctx, rd, path, offset, chunkSize)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.Decompress {
+		rd, err = decompress(rd, path, span, &stat)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("getObjectComplete error: %w", err)
+			return nil, nil, xerrors.Errorf("get object decompress error: %w", err)
 		}
-		return rd, &stat, nil
 	}
 
-	rd, err = tp.getObjectByChunks(ctx, path, span, stat)
-	if err != nil {
-		return nil, nil, xerrors.Errorf("getObjectByChunks error: %w", err)
+	if opts.SniffContentType {
+		rd, stat.ContentType, err = sniffContentType(rd, stat.ContentType)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("get object sniff content type error: %w", err)
+		}
 	}
+
 	return rd, &stat, nil
 }
 
+func (tp *TriparClient) getObjectUncached(
+	ctx context.Context,
+	path string,
+	span *ioutils.FileSpan,
+	stat *Stat,
+	chunkSize int64,
+) (rd io.ReadCloser, err error) {
+	if span == nil || span.End-span.Start <= chunkSize {
+		rd, stat.ContentType, stat.ContentEncoding, err = tp.getObjectComplete(ctx, path, span, *stat)
+		if err != nil {
+			return nil, xerrors.Errorf("getObjectComplete error: %w", err)
+		}
+	} else {
+		rd, err = tp.getObjectByChunks(ctx, path, span, *stat, chunkSize)
+		if err != nil {
+			return nil, xerrors.Errorf("getObjectByChunks error: %w", err)
+		}
+		// chunks are only requested once reading starts, and their content
+		// type is checked to be octet-stream
+		stat.ContentType = octetStream
+	}
+	return rd, nil
+}
+
 func (tp *TriparClient) getObjectResponse(
 	ctx context.Context,
 	path string,
@@ -239,14 +969,31 @@ func (tp *TriparClient) getObjectResponse(
 		req.Headers = make(http.Header)
 		req.Headers.Set("Range", fmt.Sprintf("bytes=%d-%d", span.Start, span.End))
 	}
+
+	release, err := tp.scheduleChunk(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getObject schedule error: %w", err)
+	}
 	rsp, err := tp.request(&req)
 	if err != nil {
+		release()
 		return nil, xerrors.Errorf("getObject request error: %w", err)
 	}
+	rsp.Body = &doneReadCloser{
+		ReadCloser: &throttledReadCloser{
+			Reader: tp.throttle(ctx, rsp.Body),
+			Closer: rsp.Body,
+		},
+		done: release,
+	}
 
 	ctype := rsp.Header.Get("Content-Type")
-	if !strings.HasPrefix(ctype, "application/octet-stream") {
-		return nil, xerrors.Errorf("unexpected content-type error: %w", UnmarshalTriparError(rsp))
+	if !strings.HasPrefix(ctype, octetStream) {
+		// UnmarshalTriparError closes the body
+		if err := UnmarshalTriparError(rsp); err != nil {
+			return nil, xerrors.Errorf("unexpected content-type error: %w", err)
+		}
+		return nil, xerrors.Errorf("unexpected content-type: %s", ctype)
 	}
 
 	return rsp, nil
@@ -257,12 +1004,12 @@ func (tp *TriparClient) getObjectComplete(
 	path string,
 	span *ioutils.FileSpan,
 	stat Stat,
-) (rd io.ReadCloser, err error) {
+) (rd io.ReadCloser, contentType string, contentEncoding string, err error) {
 	rsp, err := tp.getObjectResponse(ctx, path, span)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
 	}
-	return rsp.Body, nil
+	return rsp.Body, rsp.Header.Get("Content-Type"), rsp.Header.Get("Content-Encoding"), nil
 }
 
 func (tp *TriparClient) getObjectByChunks(
@@ -270,6 +1017,7 @@ func (tp *TriparClient) getObjectByChunks(
 	path string,
 	span *ioutils.FileSpan,
 	stat Stat,
+	chunkSize int64,
 ) (rd io.ReadCloser, err error) {
 	/* NOTE: we will fetch files in chunks, as Object Access API implementation
 	   seems to have a problem with (a) large files and (b) large ranges. fuck
@@ -290,9 +1038,15 @@ func (tp *TriparClient) getObjectByChunks(
 
 	nextChunk := func() error {
 		len := left
-		if len > tp.getChunkSize {
-			len = tp.getChunkSize
+		if len > chunkSize {
+			len = chunkSize
 		}
+		len, err := tp.deadlineChunkSize(ctx, len)
+		if err != nil {
+			return err
+		}
+
+		chunkStart := time.Now()
 
 		rsp, err := tp.getObjectResponse(ctx, path, &ioutils.FileSpan{Start: start, End: start + len - 1})
 		if err != nil {
@@ -304,6 +1058,9 @@ func (tp *TriparClient) getObjectByChunks(
 		if err != nil {
 			return err
 		}
+		if err := checkContentRange(rsp, start, start+len-1, rlen); err != nil {
+			return xerrors.Errorf("getObjectByChunks error: %w", err)
+		}
 
 		left -= rlen
 		start += rlen
@@ -316,10 +1073,12 @@ func (tp *TriparClient) getObjectByChunks(
 			return xerrors.Errorf("failed to copy whole response: %d != %d", n, rlen)
 		}
 
+		tp.chunkDone(ctx, "GetObject", path, start-rlen, n, chunkStart)
+
 		return nil
 	}
 
-	go func() {
+	goLabeled(ctx, "GetObject", path, func() {
 		for left > 0 {
 			if err := nextChunk(); err != nil {
 				w.CloseWithError(err)
@@ -328,12 +1087,14 @@ func (tp *TriparClient) getObjectByChunks(
 		}
 
 		w.Close()
-	}()
+	})
 
 	return r, nil
 }
 
 func (tp *TriparClient) Fsync(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "Fsync", path, time.Now(), &err)
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "POST",
@@ -356,9 +1117,185 @@ type PutPiece struct {
 	Buffer []byte
 	Read   int
 	Err    error
+
+	pooled bool
+	// flush asks the writer to write the pending pieces even if they don't
+	// fill a chunk, as the reader is waiting for a buffer.
+	flush bool
 }
 
-func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Reader) (err error) {
+type PutOptions struct {
+	// SizeHint is the expected object size in bytes, 0 if unknown. If the
+	// object fits in a single request, i.e. the hint is smaller than the
+	// BufferPool's buffers or UploadChunkSize, the reader is read into an
+	// exactly sized buffer and written with a single PUT, otherwise it is
+	// uploaded in chunks of pooled buffers. Hints larger than MaxObjectSize
+	// fail before anything is sent. Readers may return more bytes than the
+	// hint.
+	SizeHint int64
+
+	// FsyncAfterWrite makes PutObject call Fsync once the object is written.
+	// If Fsync fails the object is deleted, same as for any other failure.
+	FsyncAfterWrite bool
+
+	// VerifySize makes PutObject stat the object after it is written and fail
+	// with a *SizeMismatchError if its size differs from the number of bytes
+	// sent, e.g. because an intermediary truncated a request. The object is
+	// deleted, same as for any other failure.
+	VerifySize bool
+
+	// VerifyChunks makes PutObject read every chunk back once it is written
+	// and fail with a *ChunkChecksumError if its CRC32C differs from the
+	// CRC32C of the bytes sent. The object is deleted, same as for any other
+	// failure. It is ignored if Chunked is set.
+	VerifyChunks bool
+
+	// Stats is set to the statistics of the upload's requests when
+	// PutObject returns.
+	Stats *TransferStats
+
+	// MaxObjectSize overrides the client's MaxObjectSize, 0 means the client
+	// default.
+	MaxObjectSize int64
+
+	// CheckSpace makes PutObject check that the share has room for the
+	// object before its data is sent, by creating it and preallocating
+	// SizeHint bytes, or the size of a seekable reader. An upload which does
+	// not fit fails with ErrNoSpace after two small requests instead of after
+	// writing part of the object. The space is not kept reserved, so a
+	// concurrent writer can still fill the share, and the check is skipped on
+	// firmware without fallocate.
+	CheckSpace bool
+
+	// Chunked streams the reader in a single PUT request with chunked
+	// transfer-encoding instead of buffering it into pieces. It requires
+	// firmware which supports chunked requests and the upload can't be
+	// retried.
+	Chunked bool
+}
+
+// PutObject writes the content of reader to path. Readers which can seek,
+// like *os.File, *bytes.Reader and *strings.Reader, are sent directly with a
+// known Content-Length, other readers are copied into buffers from the
+// BufferPool first.
+func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Reader, options ...PutOption) (err error) {
+	return tp.putObject(ctx, path, reader, newPutOptions(options))
+}
+
+// getPutBuffer returns the next buffer of an upload. If the BufferPool has no
+// free buffer, wait is called before waiting for one.
+func (tp *TriparClient) getPutBuffer(ctx context.Context, opts *PutOptions, first bool, wait func()) (buffer []byte, pooled bool, err error) {
+	if first && opts.SizeHint > 0 {
+		size := tp.poolBufferSize()
+		if tp.UploadChunkSize > size {
+			size = tp.UploadChunkSize
+		}
+		if opts.SizeHint < size {
+			// one extra byte so that EOF is detected while filling the buffer
+			if err := tp.reserveBuffer(ctx, opts.SizeHint+1); err != nil {
+				return nil, false, err
+			}
+			return make([]byte, opts.SizeHint+1), false, nil
+		}
+	}
+	buffer, err = tp.getBufferOrWait(ctx, wait)
+	if err != nil {
+		return nil, false, err
+	}
+	return buffer, true, nil
+}
+
+func (tp *TriparClient) putPieceBuffer(piece *PutPiece) {
+	if piece.Buffer == nil {
+		return
+	}
+	if piece.pooled {
+		tp.putBuffer(piece.Buffer)
+	} else {
+		tp.releaseBuffer(int64(len(piece.Buffer)))
+	}
+}
+
+// PutObjectWithOptions writes the content of reader to path like PutObject.
+//
+// Deprecated: use PutObject with PutOption values, e.g. PutSizeHint.
+func (tp *TriparClient) PutObjectWithOptions(
+	ctx context.Context,
+	path string,
+	reader io.Reader,
+	opts *PutOptions,
+) (err error) {
+	return tp.putObject(ctx, path, reader, opts)
+}
+
+func (tp *TriparClient) putObject(
+	ctx context.Context,
+	path string,
+	reader io.Reader,
+	opts *PutOptions,
+) (err error) {
+	defer tp.observe(ctx, "PutObject", path, time.Now(), &err)
+	defer tp.stats.startTransfer()()
+
+	ctx = tp.beginTransfer(ctx)
+
+	if opts == nil {
+		opts = &PutOptions{}
+	}
+
+	if opts.Stats != nil {
+		var counter *transferCounter
+		ctx, counter = withTransferCounter(ctx)
+		defer func() {
+			*opts.Stats = counter.stats()
+		}()
+	}
+	if opts.SizeHint < 0 {
+		return xerrors.Errorf("put object invalid size hint: %d", opts.SizeHint)
+	}
+	if tp.planned(ctx, PlannedOperation{Op: "PutObject", Path: path, Size: opts.SizeHint}) {
+		return nil
+	}
+
+	maxSize := tp.MaxObjectSize
+	if opts.MaxObjectSize > 0 {
+		maxSize = opts.MaxObjectSize
+	}
+
+	if !opts.Chunked {
+		if src, ok := newDirectSource(reader); ok {
+			if maxSize > 0 && src.size > maxSize {
+				return &ObjectTooLargeError{Limit: maxSize}
+			}
+			if opts.CheckSpace {
+				if err := tp.checkSpace(ctx, path, src.size); err != nil {
+					return err
+				}
+			}
+			return tp.putDirect(ctx, path, reader, src, opts)
+		}
+	}
+
+	if maxSize > 0 {
+		if opts.SizeHint > maxSize {
+			return &ObjectTooLargeError{Limit: maxSize}
+		}
+		reader = &maxSizeReader{
+			reader: reader,
+			limit:  maxSize,
+		}
+	}
+
+	if opts.CheckSpace {
+		if err := tp.checkSpace(ctx, path, opts.SizeHint); err != nil {
+			return err
+		}
+	}
+
+	if opts.Chunked {
+		return tp.putChunked(ctx, path, reader, opts)
+	}
+
 	pipe := make(chan *PutPiece, 1)
 
 	pipeWriterDone := make(chan struct{})
@@ -369,21 +1306,41 @@ func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Re
 
 		// we need to drain the pipe and put the buffers back to the pool
 		for piece := range pipe {
-			tp.bufferPool.Put(piece.Buffer)
+			tp.putPieceBuffer(piece)
 		}
 
 		<-pipeWriterDone
 	}()
 
-	go func() {
+	goLabeled(ctx, "PutObject", path, func() {
 		defer close(pipe)
 		defer close(pipeWriterDone)
 
-		for {
+		// the pending pieces of a chunk larger than the free buffers are
+		// written before waiting, otherwise the upload would wait for its own
+		// buffers
+		flush := func() {
+			select {
+			case pipe <- &PutPiece{flush: true}:
+			case <-pipeReaderDone:
+			}
+		}
+
+		for first := true; ; first = false {
+			buffer, pooled, err := tp.getPutBuffer(ctx, opts, first, flush)
+			if err != nil {
+				select {
+				case pipe <- &PutPiece{Err: err}:
+				case <-pipeReaderDone:
+				}
+				return
+			}
+
 			piece := &PutPiece{
-				Buffer: tp.bufferPool.Get(),
+				Buffer: buffer,
 				Read:   0,
 				Err:    nil,
+				pooled: pooled,
 			}
 
 			// Fill the whole buffer so that we minimise the number of writes, as the
@@ -396,7 +1353,7 @@ func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Re
 			select {
 			case pipe <- piece:
 			case <-pipeReaderDone:
-				tp.bufferPool.Put(piece.Buffer)
+				tp.putPieceBuffer(piece)
 				return
 			}
 
@@ -404,46 +1361,94 @@ func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Re
 				break
 			}
 		}
-	}()
+	})
 
-	written := 0
+	written := int64(0)
 
 	defer func() {
 		if err != nil {
-			_ = tp.DeleteObject(ctx, path)
+			_ = tp.deleteObject(ctx, path)
 		}
 	}()
 
-	handlePiece := func(piece *PutPiece) error {
-		defer tp.bufferPool.Put(piece.Buffer)
+	chunkSize := tp.UploadChunkSize
 
-		if piece.Err != nil && piece.Err != io.EOF {
-			return piece.Err
+	// pieces which were received but not yet written. pendingOffset is the
+	// number of bytes of the first pending piece which were already written.
+	var pending []*PutPiece
+	pendingOffset := 0
+	pendingSize := int64(0)
+
+	defer func() {
+		for _, piece := range pending {
+			tp.putPieceBuffer(piece)
 		}
+	}()
 
-		req := &httpclient.RequestData{
-			Context:          ctx,
-			Path:             tp.path(path),
-			ExpectedStatus:   []int{http.StatusOK, http.StatusCreated},
-			ReqReader:        bytes.NewReader(piece.Buffer[:piece.Read]),
-			ReqContentLength: int64(piece.Read),
+	writeChunk := func(size int64) error {
+		// the pending pieces are kept until the chunk is written, so that
+		// appends can be retried from any offset
+		chunkReader := func(skip int64) io.Reader {
+			readers := []io.Reader{}
+			offset := pendingOffset
+			i := 0
+			for ; skip >= int64(pending[i].Read-offset); i++ {
+				skip -= int64(pending[i].Read - offset)
+				offset = 0
+			}
+			offset += int(skip)
+			for left := size - skip; left > 0; i++ {
+				piece := pending[i]
+				n := int64(piece.Read - offset)
+				if n > left {
+					n = left
+				}
+				readers = append(readers, bytes.NewReader(piece.Buffer[offset:offset+int(n)]))
+				left -= n
+				offset = 0
+			}
+			return io.MultiReader(readers...)
 		}
+
+		chunkStart := time.Now()
+
 		if written == 0 {
-			req.Method = "PUT"
+			if err := tp.putChunk(ctx, path, written, chunkReader(0), size); err != nil {
+				return err
+			}
 		} else {
-			req.Method = "POST"
-			req.Headers = make(http.Header)
-			req.Headers.Set("Range", fmt.Sprintf("bytes=%d-%d", written, written+piece.Read-1))
-		}
-		rsp, err := tp.request(req)
-		if err != nil {
-			return xerrors.Errorf("put object request error: %w", err)
+			if err := tp.appendRange(ctx, path, written, size, chunkReader); err != nil {
+				return err
+			}
 		}
-		if err := UnmarshalTriparError(rsp); err != nil {
-			return xerrors.Errorf("put object response error: %w", err)
+
+		if opts.VerifyChunks {
+			crc := crc32.New(castagnoli)
+			if _, err := io.Copy(crc, chunkReader(0)); err != nil {
+				return err
+			}
+			if err := tp.verifyChunk(ctx, path, written, size, crc.Sum32()); err != nil {
+				return err
+			}
 		}
 
-		written += piece.Read
+		tp.chunkDone(ctx, "PutObject", path, written, size, chunkStart)
+
+		written += size
+		pendingSize -= size
+
+		for left := size; left > 0; {
+			piece := pending[0]
+			n := int64(piece.Read - pendingOffset)
+			if n > left {
+				pendingOffset += int(left)
+				break
+			}
+			left -= n
+			pendingOffset = 0
+			pending = pending[1:]
+			tp.putPieceBuffer(piece)
+		}
 
 		return nil
 	}
@@ -451,16 +1456,216 @@ func (tp *TriparClient) PutObject(ctx context.Context, path string, reader io.Re
 	for {
 		piece, ok := <-pipe
 		if !ok {
-			return nil
+			break
 		}
 
-		if err := handlePiece(piece); err != nil {
+		if piece.Err != nil && piece.Err != io.EOF {
+			tp.putPieceBuffer(piece)
+			return piece.Err
+		}
+
+		flush := piece.flush
+		if !flush {
+			pending = append(pending, piece)
+			pendingSize += int64(piece.Read)
+		}
+
+		eof := piece.Err == io.EOF
+
+		if eof && written == 0 && pendingSize == 0 {
+			// empty object
+			if err := tp.putChunk(ctx, path, 0, bytes.NewReader(nil), 0); err != nil {
+				return err
+			}
+			break
+		}
+
+		for pendingSize > 0 && (eof || flush || chunkSize <= 0 || pendingSize >= chunkSize) {
+			size := pendingSize
+			if chunkSize > 0 && size > chunkSize {
+				size = chunkSize
+			}
+			size = tp.limitRequestSize(size)
+			size, err := tp.deadlineChunkSize(ctx, size)
+			if err != nil {
+				return err
+			}
+
+			if err := writeChunk(size); err != nil {
+				if isRequestTooLarge(err) && tp.lowerRequestSize(size) {
+					// the rejected write was not applied, it is repeated in
+					// smaller requests
+					continue
+				}
+				return err
+			}
+		}
+	}
+
+	return tp.putFinish(ctx, path, written, opts)
+}
+
+// checkSpace creates path and preallocates size bytes, so that an upload
+// which does not fit fails before its data is sent. The first chunk of the
+// upload replaces the object, which releases the space again.
+func (tp *TriparClient) checkSpace(ctx context.Context, path string, size int64) error {
+	if size <= 0 || !tp.caps.supported("fallocate") {
+		return nil
+	}
+
+	if err := tp.putChunk(ctx, path, 0, bytes.NewReader(nil), 0); err != nil {
+		return xerrors.Errorf("put object check space create error: %w", err)
+	}
+
+	err := tp.fallocate(ctx, path, "", 0, size)
+	tp.caps.observe("fallocate", err)
+	if errors.Is(err, ErrNotSupported) {
+		return nil
+	}
+	if err != nil {
+		_ = tp.deleteObject(ctx, path)
+		return xerrors.Errorf("put object check space error: %w", err)
+	}
+
+	return nil
+}
+
+func (tp *TriparClient) putChunked(
+	ctx context.Context,
+	path string,
+	reader io.Reader,
+	opts *PutOptions,
+) (err error) {
+	defer func() {
+		if err != nil {
+			_ = tp.deleteObject(ctx, path)
+		}
+	}()
+
+	// the length is unknown, so the body is sent with chunked
+	// transfer-encoding and has to be counted while it is read
+	var sentBytes int64
+	sent := &countingReadCloser{
+		ReadCloser: io.NopCloser(reader),
+		count:      &sentBytes,
+	}
+	var body io.ReadCloser = &countingReadCloser{
+		ReadCloser: sent,
+		count:      &tp.stats.bytesOut,
+	}
+	if counter := transferCounterFrom(ctx); counter != nil {
+		body = &countingReadCloser{
+			ReadCloser: body,
+			count:      &counter.bytesOut,
+		}
+	}
+
+	if err := tp.putChunk(ctx, path, 0, body, 0); err != nil {
+		return err
+	}
+
+	return tp.putFinish(ctx, path, atomic.LoadInt64(&sentBytes), opts)
+}
+
+// putFinish verifies and syncs an object of size bytes once it is written.
+func (tp *TriparClient) putFinish(ctx context.Context, path string, size int64, opts *PutOptions) error {
+	if opts.VerifySize {
+		if err := tp.verifySize(ctx, path, size); err != nil {
 			return err
 		}
 	}
+
+	if opts.FsyncAfterWrite {
+		if err := tp.Fsync(ctx, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tp *TriparClient) putChunk(
+	ctx context.Context,
+	path string,
+	offset int64,
+	body io.Reader,
+	size int64,
+) (err error) {
+	return tp.writeRange(ctx, path, offset, body, size, offset == 0)
+}
+
+// writeRange writes size bytes at offset. If create is set the object is
+// created or replaced with a PUT request, otherwise the range is written into
+// the existing object with a POST request.
+func (tp *TriparClient) writeRange(
+	ctx context.Context,
+	path string,
+	offset int64,
+	body io.Reader,
+	size int64,
+	create bool,
+) (err error) {
+	release, err := tp.scheduleChunk(ctx)
+	if err != nil {
+		return xerrors.Errorf("put object schedule error: %w", err)
+	}
+	defer release()
+
+	req := &httpclient.RequestData{
+		Context:          ctx,
+		Path:             tp.path(path),
+		ExpectedStatus:   []int{http.StatusOK, http.StatusCreated},
+		ReqReader:        tp.throttle(ctx, body),
+		ReqContentLength: size,
+	}
+	req.Headers = make(http.Header)
+	if create {
+		req.Method = "PUT"
+	} else {
+		req.Method = "POST"
+		req.Headers.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+size-1))
+	}
+	if tp.expectContinue {
+		req.Headers.Set("Expect", "100-continue")
+	}
+	rsp, err := tp.request(req)
+	if err != nil {
+		return xerrors.Errorf("put object request error: %w", err)
+	}
+	if err := UnmarshalTriparError(rsp); err != nil {
+		return xerrors.Errorf("put object response error: %w", err)
+	}
+
+	return nil
 }
 
+// DeleteObject deletes an object, or moves it to the trash if TrashDir is set.
 func (tp *TriparClient) DeleteObject(ctx context.Context, path string) (err error) {
+	if !tp.trashEnabled(path) {
+		return tp.deleteObject(ctx, path)
+	}
+
+	defer tp.observe(ctx, "DeleteObject", path, time.Now(), &err)
+
+	info, err := tp.Stat(ctx, path, StatSkipIdentity())
+	if err != nil {
+		return xerrors.Errorf("delete object stat error: %w", err)
+	}
+	if info.IsDir() {
+		return xerrors.Errorf("delete object %s: %w", path, ErrNotAFile)
+	}
+
+	return tp.moveToTrash(ctx, path)
+}
+
+// deleteObject deletes an object regardless of TrashDir.
+func (tp *TriparClient) deleteObject(ctx context.Context, path string) (err error) {
+	defer tp.observe(ctx, "DeleteObject", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "DeleteObject", Path: path}) {
+		return nil
+	}
+
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "DELETE",
@@ -478,9 +1683,24 @@ func (tp *TriparClient) DeleteObject(ctx context.Context, path string) (err erro
 	return nil
 }
 
+// DeleteObjectIfExists is like DeleteObject but succeeds if the object does
+// not exist.
+func (tp *TriparClient) DeleteObjectIfExists(ctx context.Context, path string) error {
+	if err := tp.DeleteObject(ctx, path); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
 func (tp *TriparClient) MoveObject(ctx context.Context, path string, nupath string) (err error) {
+	defer tp.observe(ctx, "MoveObject", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "MoveObject", Path: path, Destination: nupath}) {
+		return nil
+	}
+
 	params := tp.cmd("mv")
-	params.Set("destination", nupath)
+	params.Set("destination", tp.path(nupath))
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "POST",
@@ -500,9 +1720,21 @@ func (tp *TriparClient) MoveObject(ctx context.Context, path string, nupath stri
 }
 
 func (tp *TriparClient) CopyObject(ctx context.Context, path string, nupath string) (err error) {
+	defer tp.observe(ctx, "CopyObject", path, time.Now(), &err)
+
+	if tp.planned(ctx, PlannedOperation{Op: "CopyObject", Path: path, Destination: nupath}) {
+		return nil
+	}
+
+	return tp.copyObject(ctx, path, nupath, true)
+}
+
+// copyObject copies path to nupath. Without overwrite the copy fails with
+// ErrAlreadyExists if nupath exists.
+func (tp *TriparClient) copyObject(ctx context.Context, path string, nupath string, overwrite bool) error {
 	params := tp.cmd("cp")
-	params.Set("destination", nupath)
-	params.Set("overwrite", "true")
+	params.Set("destination", tp.path(nupath))
+	params.Set("overwrite", strconv.FormatBool(overwrite))
 	rsp, err := tp.request(&httpclient.RequestData{
 		Context:        ctx,
 		Method:         "PUT",
@@ -521,10 +1753,14 @@ func (tp *TriparClient) CopyObject(ctx context.Context, path string, nupath stri
 	return nil
 }
 
+// error responses are small JSON documents, anything larger is not read
+// completely so that a misrouted request can't exhaust memory
+const maxErrorBodySize = 64 * 1024
+
 func UnmarshalTriparError(r *http.Response) (err error) {
 	defer r.Body.Close()
 
-	body, err := ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxErrorBodySize+1))
 	if err != nil {
 		return err
 	}
@@ -533,36 +1769,51 @@ func UnmarshalTriparError(r *http.Response) (err error) {
 		return nil
 	}
 
+	if len(body) > maxErrorBodySize {
+		return xerrors.Errorf("failed to json unmarshal error response: body truncated at %d bytes: %q...", maxErrorBodySize, body[:256])
+	}
+
 	perr, err := UnmarshalError(body)
 	if err != nil {
 		return xerrors.Errorf("failed to json unmarshal error response: %w", err)
 	}
 	if perr != nil {
-		return xerrors.Errorf("tripar error: %s: %w", perr.LMsg, translateError(perr))
+		return triparError(perr)
 	}
 
 	return nil
 }
 
 func UnmarshalTriparResponse(r *http.Response, i interface{}) error {
+	body, err := readTriparResponse(r)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, &i); err != nil {
+		return xerrors.Errorf("failed to json unmarshal response: %w", err)
+	}
+
+	return nil
+}
+
+// readTriparResponse reads and closes the body of r and returns the tripar
+// error it contains, if any.
+func readTriparResponse(r *http.Response) ([]byte, error) {
 	defer r.Body.Close()
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return xerrors.Errorf("failed to read response body: %w", err)
+		return nil, xerrors.Errorf("failed to read response body: %w", err)
 	}
 
 	perr, err := UnmarshalError(body)
 	if err != nil {
-		return xerrors.Errorf("failed to json unmarshal error response: %w", err)
+		return nil, xerrors.Errorf("failed to json unmarshal error response: %w", err)
 	}
 	if perr != nil {
-		return xerrors.Errorf("tripar error: %s: %w", perr.LMsg, translateError(perr))
+		return nil, triparError(perr)
 	}
 
-	if err := json.Unmarshal(body, &i); err != nil {
-		return xerrors.Errorf("failed to json unmarshal response: %w", err)
-	}
-
-	return nil
+	return body, nil
 }
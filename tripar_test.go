@@ -11,7 +11,9 @@ import (
 	"os"
 	"strings"
 	"sync/atomic"
+	"time"
 
+	httpclient "github.com/koofr/go-httpclient"
 	ioutils "github.com/koofr/go-ioutils"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/gomega"
@@ -69,35 +71,6 @@ func (r *LongDataReader) Read(p []byte) (n int, err error) {
 	return
 }
 
-func purge(ctx context.Context, client *TriparClient, path string) (err error) {
-	entries, err := client.List(ctx, path)
-	if err != nil {
-		return err
-	}
-	for _, entry := range entries.Entries {
-		info, err := client.Stat(ctx, path+"/"+entry.Name)
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			err = purge(ctx, client, path+"/"+entry.Name)
-			if err != nil {
-				return err
-			}
-			err = client.DeleteDirectory(ctx, path+"/"+entry.Name)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = client.DeleteObject(ctx, path+"/"+entry.Name)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 var _ = Describe("TriparClient", func() {
 	var ctx context.Context
 	var cbp *countingBufferPool
@@ -146,7 +119,7 @@ var _ = Describe("TriparClient", func() {
 				Expect(err).NotTo(HaveOccurred())
 			}
 		} else {
-			err = purge(ctx, client, root)
+			err = client.Purge(ctx, root, nil)
 			Expect(err).NotTo(HaveOccurred())
 		}
 	})
@@ -257,6 +230,23 @@ var _ = Describe("TriparClient", func() {
 		})
 	})
 
+	Describe("ListPrefix", func() {
+		It("should list entries with prefix", func() {
+			Expect(client.PutObject(ctx, root+"/log-1", bytes.NewBufferString("1"))).To(Succeed())
+			Expect(client.PutObject(ctx, root+"/log-2", bytes.NewBufferString("2"))).To(Succeed())
+			Expect(client.PutObject(ctx, root+"/data", bytes.NewBufferString("3"))).To(Succeed())
+
+			entries, err := client.ListPrefix(ctx, root, "log-")
+			Expect(err).NotTo(HaveOccurred())
+
+			names := []string{}
+			for _, entry := range entries.Entries {
+				names = append(names, entry.Name)
+			}
+			Expect(names).To(ConsistOf("log-1", "log-2"))
+		})
+	})
+
 	Describe("Stat", func() {
 		It("should get object info", func() {
 			err := client.PutObject(ctx, root+"/object", bytes.NewBufferString("12345"))
@@ -326,6 +316,33 @@ var _ = Describe("TriparClient", func() {
 			Expect(fetched).To(Equal(expected))
 		})
 
+		It("should put a large object with upload chunk size larger than buffer size", func() {
+			client.UploadChunkSize = 3 * TriparBufferSize / 2
+
+			data := NewLongDataReader(4*1024*1024 + 17)
+			err := client.PutObject(ctx, root+"/large-object", data)
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := client.Stat(ctx, root+"/large-object")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Status.Size).To(Equal(int64(4*1024*1024 + 17)))
+		})
+
+		It("should put object with upload chunk size smaller than buffer size", func() {
+			client.UploadChunkSize = 2
+
+			err := client.PutObject(ctx, root+"/new-object", bytes.NewBufferString("12345"))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader, _, err := client.GetObject(ctx, root+"/new-object", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+
+			data, err := ioutil.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("12345"))
+		})
+
 		It("should remove partially written objects after a failure", func() {
 			data := NewFailingLongDataReader(4*1024*1024+17, 2*1024*1024+101)
 			err := client.PutObject(ctx, root+"/large-object", data)
@@ -559,6 +576,349 @@ var _ = Describe("TriparClient", func() {
 	})
 })
 
+var _ = Describe("WithCredentials", func() {
+	It("should authenticate the clone with different credentials", func() {
+		authorizations := make(chan string, 2)
+
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			authorizations <- r.Header.Get("Authorization")
+			return testStatResponse(5), nil
+		}))
+
+		tenantClient := client.WithCredentials("tenant", "secret")
+		Expect(tenantClient.HTTPClient.Client).To(BeIdenticalTo(client.HTTPClient.Client))
+
+		_, err := tenantClient.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authorizations).To(Equal("Basic dGVuYW50OnNlY3JldA=="))
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authorizations).To(Equal("Basic dXNlcjpwYXNz"))
+	})
+})
+
+var _ = Describe("WithRunAsUser", func() {
+	It("should set the run-as header", func() {
+		runAs := make(chan string, 2)
+
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			runAs <- r.Header.Get("X-Run-As-User")
+			return testStatResponse(5), nil
+		}))
+		client.RunAsHeader = "X-Run-As-User"
+
+		_, err := client.Stat(WithRunAsUser(context.Background(), "tenant"), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-runAs).To(Equal("tenant"))
+
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-runAs).To(Equal(""))
+	})
+
+	It("should fail without a configured run-as header", func() {
+		requests := 0
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return testStatResponse(5), nil
+		}))
+
+		_, err := client.Stat(WithRunAsUser(context.Background(), "tenant"), "/object")
+		Expect(err).To(MatchError(ErrRunAsHeaderNotConfigured))
+		Expect(requests).To(BeZero())
+	})
+})
+
+var _ = Describe("PutObject buffers", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		DeferCleanup(cancel)
+
+		fake = newFakeTripar()
+		fake.Mkdir("/root")
+
+		var err error
+		client, err = NewTriparClientWithOptions(
+			"http://tripar.example.com",
+			WithBufferPool(NewBufferPool(2, 1024)),
+			WithTransport(fake),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		client.UploadChunkSize = 3072
+	})
+
+	It("should write partial chunks if the pool has fewer buffers than a chunk", func() {
+		content := strings.Repeat("0123456789", 500)
+
+		Expect(client.PutObject(ctx, "/root/object", struct{ io.Reader }{strings.NewReader(content)})).To(Succeed())
+
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+	})
+
+	It("should not block concurrent uploads sharing the pool", func() {
+		content := strings.Repeat("0123456789", 500)
+
+		errs := make(chan error, 4)
+		for i := 0; i < 4; i++ {
+			path := fmt.Sprintf("/root/object%d", i)
+			go func() {
+				errs <- client.PutObject(ctx, path, struct{ io.Reader }{strings.NewReader(content)})
+			}()
+		}
+		for i := 0; i < 4; i++ {
+			Expect(<-errs).To(Succeed())
+		}
+
+		for i := 0; i < 4; i++ {
+			data, ok := fake.File(fmt.Sprintf("/root/object%d", i))
+			Expect(ok).To(BeTrue())
+			Expect(string(data)).To(Equal(content))
+		}
+	})
+})
+
+var _ = Describe("PutObject options", func() {
+	It("should fsync after write", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		ctx := context.Background()
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"), PutFsyncAfterWrite())).To(Succeed())
+		Expect(client.PutObject(ctx, "/root/empty", bytes.NewBufferString(""), PutFsyncAfterWrite())).To(Succeed())
+
+		Expect(fake.Requests()).To(Equal([]string{
+			"PUT /root/object",
+			"POST /root/object fsync",
+			"PUT /root/empty",
+			"POST /root/empty fsync",
+		}))
+	})
+
+	It("should stream chunked uploads in a single request", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		contentLengths := []int64{}
+		client.HTTPClient.Client.Transport = funcTransport(func(r *http.Request) (*http.Response, error) {
+			contentLengths = append(contentLengths, r.ContentLength)
+			return fake.RoundTrip(r)
+		})
+
+		data := strings.Repeat("x", 5000)
+		Expect(client.PutObject(context.Background(), "/root/object", io.LimitReader(strings.NewReader(data), 5000), PutChunked())).To(Succeed())
+
+		file, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(file)).To(Equal(data))
+		Expect(fake.Requests()).To(Equal([]string{"PUT /root/object"}))
+		Expect(contentLengths).To(Equal([]int64{0}))
+		Expect(client.Stats().BytesOut).To(Equal(int64(5000)))
+	})
+
+	It("should delete the object if a chunked upload fails", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+		fake.PutFile("/root/object", "old")
+
+		readErr := errors.New("read error")
+		err := client.PutObject(context.Background(), "/root/object", ioutils.NewErrorReader(readErr), PutChunked())
+		Expect(err).To(HaveOccurred())
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+	})
+
+	It("should not fsync by default", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		Expect(client.PutObject(context.Background(), "/root/object", bytes.NewBufferString("12345"))).To(Succeed())
+		Expect(fake.Requests()).To(Equal([]string{"PUT /root/object"}))
+	})
+})
+
+var _ = Describe("DeleteObjectIfExists", func() {
+	It("should succeed if the object does not exist", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root/dir")
+		fake.PutFile("/root/object", "12345")
+
+		ctx := context.Background()
+		Expect(client.DeleteObjectIfExists(ctx, "/root/object")).To(Succeed())
+		Expect(fake.Exists("/root/object")).To(BeFalse())
+		Expect(client.DeleteObjectIfExists(ctx, "/root/object")).To(Succeed())
+		Expect(client.DeleteObjectIfExists(ctx, "/root/dir")).To(MatchError(ErrNotAFile))
+	})
+})
+
+var _ = Describe("DeleteDirectoryIfExists", func() {
+	It("should succeed if the directory does not exist", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root/dir")
+		fake.Mkdir("/root/full/dir")
+		fake.PutFile("/root/object", "12345")
+
+		ctx := context.Background()
+		Expect(client.DeleteDirectoryIfExists(ctx, "/root/dir")).To(Succeed())
+		Expect(fake.Exists("/root/dir")).To(BeFalse())
+		Expect(client.DeleteDirectoryIfExists(ctx, "/root/dir")).To(Succeed())
+		Expect(client.DeleteDirectoryIfExists(ctx, "/root/full")).To(MatchError(ErrNotEmpty))
+		Expect(client.DeleteDirectoryIfExists(ctx, "/root/object")).To(MatchError(ErrNotADirectory))
+	})
+})
+
+var _ = Describe("CreateDirectoriesWithResult", func() {
+	It("should report created directories", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root/a")
+		fake.PutFile("/root/object", "12345")
+
+		ctx := context.Background()
+		created, err := client.CreateDirectoriesWithResult(ctx, "/root/a/b/c")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(Equal([]string{"/root/a/b", "/root/a/b/c"}))
+		Expect(fake.Exists("/root/a/b/c")).To(BeTrue())
+
+		created, err = client.CreateDirectoriesWithResult(ctx, "/root/a/b/c")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(BeEmpty())
+
+		_, err = client.CreateDirectoriesWithResult(ctx, "/root/object/d")
+		Expect(err).To(MatchError(ErrNotADirectory))
+	})
+})
+
+var _ = Describe("ObserveLatency", func() {
+	It("should observe every operation", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		ops := []string{}
+		errs := []error{}
+		client.ObserveLatency = func(op string, d time.Duration, err error) {
+			Expect(d).To(BeNumerically(">=", 0))
+			ops = append(ops, op)
+			errs = append(errs, err)
+		}
+
+		ctx := context.Background()
+		Expect(client.PutObject(ctx, "/root/object", bytes.NewBufferString("12345"))).To(Succeed())
+		_, err := client.Stat(ctx, "/root/nonexistent")
+		Expect(err).To(HaveOccurred())
+
+		Expect(ops).To(Equal([]string{"PutObject", "Stat"}))
+		Expect(errs[0]).NotTo(HaveOccurred())
+		Expect(errs[1]).To(MatchError(ErrNotFound))
+	})
+})
+
+var _ = Describe("HTTP status errors", func() {
+	It("should map statuses without a tripar error body to sentinel errors", func() {
+		status := http.StatusNotFound
+
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(status, "text/html", "<html>error</html>"), nil
+		}))
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrNotFound))
+
+		status = http.StatusInsufficientStorage
+		err = client.PutObject(context.Background(), "/object", bytes.NewBufferString("12345"))
+		Expect(err).To(MatchError(ErrNoSpace))
+
+		status = http.StatusBadGateway
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, ErrNotFound)).To(BeFalse())
+		var ise httpclient.InvalidStatusError
+		Expect(errors.As(err, &ise)).To(BeTrue())
+		Expect(ise.Got).To(Equal(http.StatusBadGateway))
+	})
+
+	It("should map authentication and permission failures", func() {
+		status := http.StatusUnauthorized
+
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(status, "text/html", "<html>error</html>"), nil
+		}))
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrUnauthorized))
+
+		status = http.StatusForbidden
+		_, err = client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrForbidden))
+	})
+
+	It("should map permission tripar errors", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(http.StatusOK, "application/json", `{
+				"error_code": 13,
+				"long_message": "Permission denied (error code 13)",
+				"short_message": "Permission denied"
+			}`), nil
+		}))
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrForbidden))
+	})
+})
+
+var _ = Describe("Error", func() {
+	It("should be available with errors.As on every operation", func() {
+		client, fake := newFakeTriparClient()
+		fake.Mkdir("/root")
+
+		ctx := context.Background()
+		expectCode := func(err error, code int) {
+			var perr *Error
+			ExpectWithOffset(1, errors.As(err, &perr)).To(BeTrue())
+			ExpectWithOffset(1, perr.Code).To(Equal(code))
+		}
+
+		_, err := client.Stat(ctx, "/root/missing")
+		expectCode(err, 2)
+		_, err = client.List(ctx, "/root/missing")
+		expectCode(err, 2)
+		_, _, err = client.GetObject(ctx, "/root/missing", nil)
+		expectCode(err, 2)
+		expectCode(client.PutObject(ctx, "/root/missing/object", bytes.NewBufferString("12345")), 2)
+		expectCode(client.DeleteObject(ctx, "/root/missing"), 2)
+		expectCode(client.DeleteDirectory(ctx, "/root/missing"), 2)
+		expectCode(client.CreateDirectory(ctx, "/root"), 17)
+		expectCode(client.MoveObject(ctx, "/root/missing", "/root/other"), 2)
+		expectCode(client.CopyObject(ctx, "/root/missing", "/root/other"), 2)
+		expectCode(client.Fsync(ctx, "/root/missing"), 2)
+	})
+})
+
+var _ = Describe("InvalidStatusError", func() {
+	It("should translate tripar errors returned with an unexpected status", func() {
+		client := newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			return testResponse(http.StatusInternalServerError, "application/json", `{
+				"error_code": 2,
+				"long_message": "The requested path was not found (error code 2)",
+				"short_message": "No such file or directory"
+			}`), nil
+		}))
+
+		_, err := client.Stat(context.Background(), "/object")
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(err.Error()).To(Equal("stat request error: tripar error: The requested path was not found (error code 2): not found"))
+
+		var perr *Error
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Code).To(Equal(2))
+	})
+})
+
 var _ = Describe("UnmarshalTriparError", func() {
 	It("should translate error", func() {
 		err := UnmarshalTriparError(&http.Response{
@@ -570,6 +930,36 @@ var _ = Describe("UnmarshalTriparError", func() {
 		})
 		Expect(err).To(MatchError(ErrNotFound))
 		Expect(err.Error()).To(Equal("tripar error: The requested path was not found (error code 2): not found"))
+
+		var perr *Error
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Code).To(Equal(2))
+	})
+
+	It("should preserve details of unknown errors", func() {
+		body := `{"error_code": 12345, "long_message": "Quota exceeded for share (error code 12345)", "short_message": "Quota exceeded", "quota": 42}`
+		err := UnmarshalTriparError(&http.Response{
+			Body: io.NopCloser(strings.NewReader(body)),
+		})
+		Expect(err.Error()).To(Equal("tripar error: Quota exceeded for share (error code 12345): Quota exceeded (code 12345)"))
+
+		var perr *Error
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Code).To(Equal(12345))
+		Expect(perr.LMsg).To(Equal("Quota exceeded for share (error code 12345)"))
+		Expect(string(perr.Raw)).To(Equal(body))
+	})
+
+	It("should truncate raw error body", func() {
+		body := `{"error_code": 12345, "long_message": "Unknown", "short_message": "Unknown", "padding": "` + strings.Repeat("x", 8*1024) + `"}`
+		err := UnmarshalTriparError(&http.Response{
+			Body: io.NopCloser(strings.NewReader(body)),
+		})
+
+		var perr *Error
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Raw).To(HaveLen(4 * 1024))
+		Expect(string(perr.Raw)).To(Equal(body[:4*1024]))
 	})
 
 	It("should return nil if response body is empty", func() {
@@ -594,6 +984,14 @@ var _ = Describe("UnmarshalTriparError", func() {
 		Expect(err).To(MatchError(bodyReadErr))
 	})
 
+	It("should not read a large response body completely", func() {
+		err := UnmarshalTriparError(&http.Response{
+			Body: io.NopCloser(NewLongDataReader(10 * 1024 * 1024)),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("body truncated at 65536 bytes"))
+	})
+
 	It("should return err if response body is not a valid json", func() {
 		err := UnmarshalTriparError(&http.Response{
 			Body: io.NopCloser(strings.NewReader(`{`)),
@@ -625,6 +1023,49 @@ var _ = Describe("UnmarshalTriparResponse", func() {
 		}))
 	})
 
+	It("should unmarshal entry metadata", func() {
+		var entries Entries
+		err := UnmarshalTriparResponse(&http.Response{
+			Body: io.NopCloser(strings.NewReader(`{
+				"entries": [
+					{"name": "file", "type": "file", "size": 5, "mtime": 1700000000.5},
+					{"name": "dir", "type": "directory"},
+					{"name": "unknown"}
+				]
+			}`)),
+		}, &entries)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries.Entries).To(HaveLen(3))
+
+		Expect(entries.Entries[0].HasMetadata()).To(BeTrue())
+		Expect(entries.Entries[0].IsDir()).To(BeFalse())
+		Expect(entries.Entries[0].Size).To(Equal(int64(5)))
+		Expect(entries.Entries[0].Mtime).To(Equal(1700000000.5))
+
+		Expect(entries.Entries[1].HasMetadata()).To(BeTrue())
+		Expect(entries.Entries[1].IsDir()).To(BeTrue())
+
+		Expect(entries.Entries[2].HasMetadata()).To(BeFalse())
+	})
+
+	It("should unmarshal inode numbers larger than int64", func() {
+		var info Stat
+		err := UnmarshalTriparResponse(&http.Response{
+			Body: io.NopCloser(strings.NewReader(`{
+				"path": "/object",
+				"status": {
+					"dev": 42,
+					"ino": 18446744073709551615,
+					"mode": 33188,
+					"size": 5
+				}
+			}`)),
+		}, &info)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Status.Dev).To(Equal(int32(42)))
+		Expect(info.Status.Ino).To(Equal(uint64(18446744073709551615)))
+	})
+
 	It("should translate error", func() {
 		err := UnmarshalTriparResponse(&http.Response{
 			Body: io.NopCloser(strings.NewReader(`{
@@ -691,6 +1132,35 @@ func (t funcTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	return t(r)
 }
 
+func newTestClient(transport http.RoundTripper) *TriparClient {
+	client, err := NewTriparClientWithOptions(
+		"http://tripar.example.com",
+		WithBasicAuth("user", "pass"),
+		WithShare("share"),
+		WithBufferPool(NewBufferPool(4, 1024)),
+		WithGetChunkSize(1024),
+		WithTransport(transport),
+	)
+	Expect(err).NotTo(HaveOccurred())
+
+	return client
+}
+
+func testResponse(status int, contentType string, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func testStatResponse(size int) *http.Response {
+	return testResponse(http.StatusOK, "application/json", fmt.Sprintf(`{
+		"path": "/object",
+		"status": {"mode": 33188, "size": %d}
+	}`, size))
+}
+
 type countingBufferPool struct {
 	upstream BufferPoolIface
 	count    int64
@@ -700,6 +1170,10 @@ func (p *countingBufferPool) GetCount() int64 {
 	return atomic.LoadInt64(&p.count)
 }
 
+func (p *countingBufferPool) BufferSize() int64 {
+	return p.upstream.(*BufferPool).BufferSize()
+}
+
 func (p *countingBufferPool) Get() []byte {
 	atomic.AddInt64(&p.count, 1)
 	return p.upstream.Get()
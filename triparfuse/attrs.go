@@ -0,0 +1,113 @@
+//go:build linux || darwin || freebsd
+
+package triparfuse
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+type cachedAttr struct {
+	stat    triparclient.Stat
+	expires time.Time
+}
+
+// attrCache caches Stat results, so that the Stat requests made while listing
+// a directory also serve the Lookup and Getattr calls which follow.
+type attrCache struct {
+	ttl time.Duration
+
+	mx    sync.Mutex
+	attrs map[string]cachedAttr
+}
+
+func newAttrCache(ttl time.Duration) *attrCache {
+	return &attrCache{
+		ttl:   ttl,
+		attrs: map[string]cachedAttr{},
+	}
+}
+
+func (c *attrCache) get(path string) (triparclient.Stat, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	attr, ok := c.attrs[path]
+	if !ok {
+		return triparclient.Stat{}, false
+	}
+	if time.Now().After(attr.expires) {
+		delete(c.attrs, path)
+		return triparclient.Stat{}, false
+	}
+	return attr.stat, true
+}
+
+func (c *attrCache) set(path string, stat triparclient.Stat) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.attrs[path] = cachedAttr{
+		stat:    stat,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate removes path and, as it may be a directory, its descendants.
+func (c *attrCache) invalidate(path string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for p := range c.attrs {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(c.attrs, p)
+		}
+	}
+}
+
+func (f *fileSystem) stat(ctx context.Context, path string) (triparclient.Stat, error) {
+	if stat, ok := f.attrs.get(path); ok {
+		return stat, nil
+	}
+
+	stat, err := f.client.Stat(ctx, path, triparclient.StatSkipIdentity())
+	if err != nil {
+		return triparclient.Stat{}, err
+	}
+
+	f.attrs.set(path, stat)
+
+	return stat, nil
+}
+
+func fillAttr(out *fuse.Attr, stat triparclient.Stat) {
+	status := stat.Status
+
+	out.Mode = uint32(status.Mode)
+	out.Size = uint64(status.Size)
+	out.Blocks = uint64(status.Blocks)
+	out.Blksize = uint32(status.Blksize)
+	out.Nlink = uint32(status.Nlink)
+	out.Uid = uint32(status.Uid)
+	out.Gid = uint32(status.Gid)
+
+	atime := status.AccessTime()
+	mtime := status.ModTime()
+	ctime := status.ChangeTime()
+	out.SetTimes(&atime, &mtime, &ctime)
+}
+
+func fileType(stat triparclient.Stat) uint32 {
+	if stat.IsDir() {
+		return syscall.S_IFDIR
+	}
+	return syscall.S_IFREG
+}
@@ -0,0 +1,126 @@
+//go:build linux || darwin || freebsd
+
+// Package triparfuse mounts a share with FUSE, for ad-hoc access to an
+// appliance without NFS or SMB exports.
+package triparfuse
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+const (
+	DefaultAttrTimeout     = time.Second
+	DefaultReadAhead       = 1024 * 1024
+	DefaultWriteBufferSize = 4 * 1024 * 1024
+)
+
+type Options struct {
+	// AttrTimeout is how long attributes and directory entries are cached by
+	// the kernel and the file system, DefaultAttrTimeout if 0.
+	AttrTimeout time.Duration
+
+	// ReadAhead is the number of bytes read with a single request when a read
+	// misses the read-ahead buffer, DefaultReadAhead if 0. Reads larger than
+	// ReadAhead are not split.
+	ReadAhead int64
+
+	// WriteBufferSize is the number of sequentially written bytes buffered
+	// before they are written with a single request, DefaultWriteBufferSize
+	// if 0.
+	WriteBufferSize int64
+
+	// MountOptions are passed to go-fuse.
+	MountOptions fuse.MountOptions
+}
+
+func (o *Options) withDefaults() *Options {
+	opts := Options{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.AttrTimeout == 0 {
+		opts.AttrTimeout = DefaultAttrTimeout
+	}
+	if opts.ReadAhead == 0 {
+		opts.ReadAhead = DefaultReadAhead
+	}
+	if opts.WriteBufferSize == 0 {
+		opts.WriteBufferSize = DefaultWriteBufferSize
+	}
+	return &opts
+}
+
+type fileSystem struct {
+	client *triparclient.TriparClient
+	opts   *Options
+	attrs  *attrCache
+}
+
+// NewRoot returns the root node of a file system backed by client, for
+// mounting with fs.Mount.
+func NewRoot(client *triparclient.TriparClient, opts *Options) fs.InodeEmbedder {
+	opts = opts.withDefaults()
+
+	return &node{
+		fsys: &fileSystem{
+			client: client,
+			opts:   opts,
+			attrs:  newAttrCache(opts.AttrTimeout),
+		},
+	}
+}
+
+// Mount mounts the share at mountpoint. The returned server is already
+// serving, call Unmount to unmount it.
+func Mount(mountpoint string, client *triparclient.TriparClient, opts *Options) (*fuse.Server, error) {
+	opts = opts.withDefaults()
+
+	mountOpts := opts.MountOptions
+	if mountOpts.Name == "" {
+		mountOpts.Name = "tripar"
+	}
+
+	return fs.Mount(mountpoint, NewRoot(client, opts), &fs.Options{
+		MountOptions: mountOpts,
+		EntryTimeout: &opts.AttrTimeout,
+		AttrTimeout:  &opts.AttrTimeout,
+	})
+}
+
+// errno maps client errors to errnos.
+func errno(err error) syscall.Errno {
+	switch {
+	case err == nil:
+		return fs.OK
+	case errors.Is(err, triparclient.ErrNotFound):
+		return syscall.ENOENT
+	case errors.Is(err, triparclient.ErrAlreadyExists):
+		return syscall.EEXIST
+	case errors.Is(err, triparclient.ErrNotADirectory):
+		return syscall.ENOTDIR
+	case errors.Is(err, triparclient.ErrNotAFile):
+		return syscall.EISDIR
+	case errors.Is(err, triparclient.ErrNotEmpty):
+		return syscall.ENOTEMPTY
+	case errors.Is(err, triparclient.ErrForbidden), errors.Is(err, triparclient.ErrUnauthorized):
+		return syscall.EACCES
+	case errors.Is(err, triparclient.ErrNoSpace):
+		return syscall.ENOSPC
+	case errors.Is(err, triparclient.ErrNotSupported):
+		return syscall.ENOTSUP
+	case errors.Is(err, triparclient.ErrBadRange):
+		return syscall.EINVAL
+	case errors.Is(err, context.Canceled):
+		return syscall.EINTR
+	default:
+		return syscall.EIO
+	}
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package triparfuse_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	triparclient "github.com/koofr/go-triparclient"
+	"github.com/koofr/go-triparclient/internal/triparfake"
+	. "github.com/koofr/go-triparclient/triparfuse"
+)
+
+var _ = Describe("Mount", func() {
+	var fake *triparfake.Fake
+	var server *fuse.Server
+	var mnt string
+
+	BeforeEach(func() {
+		fake = triparfake.New()
+		fake.Mkdir("/root")
+
+		client, err := triparclient.NewTriparClient("http://tripar.example.com", "user", "pass", "share", triparclient.NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+
+		mnt, err = os.MkdirTemp("", "triparfuse")
+		Expect(err).NotTo(HaveOccurred())
+
+		server, err = Mount(mnt, client, &Options{
+			ReadAhead:       16,
+			WriteBufferSize: 32,
+			MountOptions: fuse.MountOptions{
+				// mount without fusermount when running as root
+				DirectMount: os.Geteuid() == 0,
+			},
+		})
+		if err != nil {
+			os.Remove(mnt)
+			Skip("FUSE is not available: " + err.Error())
+		}
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			Expect(server.Unmount()).To(Succeed())
+		}
+		os.Remove(mnt)
+	})
+
+	It("should read and write files", func() {
+		content := strings.Repeat("0123456789", 10)
+
+		Expect(os.WriteFile(filepath.Join(mnt, "root/object"), []byte(content), 0644)).To(Succeed())
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+
+		read, err := os.ReadFile(filepath.Join(mnt, "root/object"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(read)).To(Equal(content))
+
+		f, err := os.OpenFile(filepath.Join(mnt, "root/object"), os.O_RDWR, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteAt([]byte("abc"), 98)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		data, _ = fake.File("/root/object")
+		Expect(string(data)).To(Equal(content[:98] + "abc"))
+	})
+
+	It("should list, create, rename and delete", func() {
+		fake.PutFile("/root/object", "12345")
+
+		Expect(os.Mkdir(filepath.Join(mnt, "root/dir"), 0755)).To(Succeed())
+		Expect(fake.Exists("/root/dir")).To(BeTrue())
+
+		entries, err := os.ReadDir(filepath.Join(mnt, "root"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Name()).To(Equal("dir"))
+		Expect(entries[0].IsDir()).To(BeTrue())
+		Expect(entries[1].Name()).To(Equal("object"))
+
+		info, err := os.Stat(filepath.Join(mnt, "root/object"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(5)))
+
+		Expect(os.Rename(filepath.Join(mnt, "root/object"), filepath.Join(mnt, "root/dir/moved"))).To(Succeed())
+		Expect(fake.Exists("/root/dir/moved")).To(BeTrue())
+
+		Expect(os.Remove(filepath.Join(mnt, "root/dir/moved"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(mnt, "root/dir"))).To(Succeed())
+		Expect(fake.Exists("/root/dir")).To(BeFalse())
+
+		_, err = os.Stat(filepath.Join(mnt, "root/dir"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,188 @@
+//go:build linux || darwin || freebsd
+
+package triparfuse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	ioutils "github.com/koofr/go-ioutils"
+)
+
+// handle is an open file. Reads are served from a read-ahead buffer filled
+// with ranged GET requests and sequential writes are buffered and written
+// with ranged POST requests.
+type handle struct {
+	fsys *fileSystem
+	path string
+
+	mx       sync.Mutex
+	readBuf  []byte
+	readOff  int64
+	readEOF  bool
+	writeBuf []byte
+	writeOff int64
+}
+
+var (
+	_ fs.FileReader   = (*handle)(nil)
+	_ fs.FileWriter   = (*handle)(nil)
+	_ fs.FileFlusher  = (*handle)(nil)
+	_ fs.FileFsyncer  = (*handle)(nil)
+	_ fs.FileReleaser = (*handle)(nil)
+)
+
+func newHandle(fsys *fileSystem, path string) *handle {
+	return &handle{
+		fsys: fsys,
+		path: path,
+	}
+}
+
+// buffered reports whether dest can be read from the read-ahead buffer.
+func (h *handle) buffered(off int64, size int) bool {
+	end := h.readOff + int64(len(h.readBuf))
+	if off < h.readOff || off > end {
+		return false
+	}
+	return off+int64(size) <= end || h.readEOF
+}
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	// reads have to see buffered writes
+	if err := h.flush(ctx); err != nil {
+		return nil, errno(err)
+	}
+
+	if !h.buffered(off, len(dest)) {
+		if err := h.readAhead(ctx, off, len(dest)); err != nil {
+			return nil, errno(err)
+		}
+	}
+
+	start := off - h.readOff
+	end := start + int64(len(dest))
+	if end > int64(len(h.readBuf)) {
+		end = int64(len(h.readBuf))
+	}
+
+	return fuse.ReadResultData(h.readBuf[start:end]), fs.OK
+}
+
+func (h *handle) readAhead(ctx context.Context, off int64, size int) error {
+	// a new buffer is allocated for every read-ahead, as go-fuse may still
+	// be sending the previous one
+	h.readBuf = nil
+	h.readOff = off
+	h.readEOF = false
+
+	stat, err := h.fsys.stat(ctx, h.path)
+	if err != nil {
+		return err
+	}
+
+	n := int64(size)
+	if n < h.fsys.opts.ReadAhead {
+		n = h.fsys.opts.ReadAhead
+	}
+	if off+n >= stat.Status.Size {
+		n = stat.Status.Size - off
+		h.readEOF = true
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	rd, _, err := h.fsys.client.GetObject(ctx, h.path, &ioutils.FileSpan{
+		Start: off,
+		End:   off + n - 1,
+	})
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	buf := bytes.NewBuffer(make([]byte, 0, n))
+	if _, err := io.Copy(buf, rd); err != nil {
+		return err
+	}
+	h.readBuf = buf.Bytes()
+
+	return nil
+}
+
+func (h *handle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.readBuf = nil
+	h.readEOF = false
+
+	if len(h.writeBuf) > 0 && off != h.writeOff+int64(len(h.writeBuf)) {
+		if err := h.flush(ctx); err != nil {
+			return 0, errno(err)
+		}
+	}
+	if len(h.writeBuf) == 0 {
+		h.writeOff = off
+	}
+
+	h.writeBuf = append(h.writeBuf, data...)
+
+	if int64(len(h.writeBuf)) >= h.fsys.opts.WriteBufferSize {
+		if err := h.flush(ctx); err != nil {
+			return 0, errno(err)
+		}
+	}
+
+	return uint32(len(data)), fs.OK
+}
+
+func (h *handle) flush(ctx context.Context) error {
+	if len(h.writeBuf) == 0 {
+		return nil
+	}
+
+	err := h.fsys.client.WriteAt(ctx, h.path, h.writeOff, bytes.NewReader(h.writeBuf), int64(len(h.writeBuf)))
+	h.fsys.attrs.invalidate(h.path)
+	if err != nil {
+		return err
+	}
+
+	h.writeBuf = h.writeBuf[:0]
+
+	return nil
+}
+
+func (h *handle) Flush(ctx context.Context) syscall.Errno {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	return errno(h.flush(ctx))
+}
+
+func (h *handle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if err := h.flush(ctx); err != nil {
+		return errno(err)
+	}
+
+	return errno(h.fsys.client.Fsync(ctx, h.path))
+}
+
+func (h *handle) Release(ctx context.Context) syscall.Errno {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	return errno(h.flush(ctx))
+}
@@ -0,0 +1,244 @@
+//go:build linux || darwin || freebsd
+
+package triparfuse
+
+import (
+	"bytes"
+	"context"
+	pathpkg "path"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+// renameNoReplace is RENAME_NOREPLACE from renameat2.
+const renameNoReplace = 0x1
+
+type node struct {
+	fs.Inode
+
+	fsys *fileSystem
+}
+
+var (
+	_ fs.NodeGetattrer = (*node)(nil)
+	_ fs.NodeSetattrer = (*node)(nil)
+	_ fs.NodeLookuper  = (*node)(nil)
+	_ fs.NodeReaddirer = (*node)(nil)
+	_ fs.NodeMkdirer   = (*node)(nil)
+	_ fs.NodeCreater   = (*node)(nil)
+	_ fs.NodeUnlinker  = (*node)(nil)
+	_ fs.NodeRmdirer   = (*node)(nil)
+	_ fs.NodeRenamer   = (*node)(nil)
+	_ fs.NodeOpener    = (*node)(nil)
+)
+
+func (n *node) path() string {
+	return "/" + n.Path(nil)
+}
+
+func (n *node) childPath(name string) string {
+	return pathpkg.Join(n.path(), name)
+}
+
+func (n *node) newChild(ctx context.Context, stat triparclient.Stat, out *fuse.EntryOut) *fs.Inode {
+	fillAttr(&out.Attr, stat)
+	out.SetEntryTimeout(n.fsys.opts.AttrTimeout)
+	out.SetAttrTimeout(n.fsys.opts.AttrTimeout)
+
+	return n.NewInode(ctx, &node{fsys: n.fsys}, fs.StableAttr{Mode: fileType(stat)})
+}
+
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	stat, err := n.fsys.stat(ctx, n.path())
+	if err != nil {
+		return errno(err)
+	}
+
+	fillAttr(&out.Attr, stat)
+	out.SetTimeout(n.fsys.opts.AttrTimeout)
+
+	return fs.OK
+}
+
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	path := n.path()
+	client := n.fsys.client
+
+	// buffered writes must land before the size or times change
+	if h, ok := f.(*handle); ok {
+		if errno := h.Flush(ctx); errno != fs.OK {
+			return errno
+		}
+	}
+
+	n.fsys.attrs.invalidate(path)
+	stat, err := n.fsys.stat(ctx, path)
+	if err != nil {
+		return errno(err)
+	}
+
+	if size, ok := in.GetSize(); ok {
+		if err := client.Truncate(ctx, path, int64(size)); err != nil {
+			return errno(err)
+		}
+	}
+
+	uid, uidOk := in.GetUID()
+	gid, gidOk := in.GetGID()
+	if uidOk || gidOk {
+		if !uidOk {
+			uid = uint32(stat.Status.Uid)
+		}
+		if !gidOk {
+			gid = uint32(stat.Status.Gid)
+		}
+		if err := client.Chown(ctx, path, int32(uid), int32(gid)); err != nil {
+			return errno(err)
+		}
+	}
+
+	if mode, ok := in.GetMode(); ok {
+		if err := client.Chmod(ctx, path, int32(mode)); err != nil {
+			return errno(err)
+		}
+	}
+
+	atime, atimeOk := in.GetATime()
+	mtime, mtimeOk := in.GetMTime()
+	if atimeOk || mtimeOk {
+		if !atimeOk {
+			atime = stat.Status.AccessTime()
+		}
+		if !mtimeOk {
+			mtime = stat.Status.ModTime()
+		}
+		if err := client.Utime(ctx, path, atime, mtime); err != nil {
+			return errno(err)
+		}
+	}
+
+	n.fsys.attrs.invalidate(path)
+
+	return n.Getattr(ctx, f, out)
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	stat, err := n.fsys.stat(ctx, n.childPath(name))
+	if err != nil {
+		return nil, errno(err)
+	}
+
+	return n.newChild(ctx, stat, out), fs.OK
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	dir := n.path()
+
+	entries, err := n.fsys.client.List(ctx, dir)
+	if err != nil {
+		return nil, errno(err)
+	}
+
+	list := make([]fuse.DirEntry, 0, len(entries.Entries))
+	for _, entry := range entries.Entries {
+		mode := uint32(syscall.S_IFREG)
+		if entry.HasMetadata() {
+			if entry.IsDir() {
+				mode = syscall.S_IFDIR
+			}
+		} else {
+			stat, err := n.fsys.stat(ctx, pathpkg.Join(dir, entry.Name))
+			if err != nil {
+				if errno(err) == syscall.ENOENT {
+					// deleted since listing
+					continue
+				}
+				return nil, errno(err)
+			}
+			mode = fileType(stat)
+		}
+
+		list = append(list, fuse.DirEntry{
+			Name: entry.Name,
+			Mode: mode,
+		})
+	}
+
+	return fs.NewListDirStream(list), fs.OK
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	path := n.childPath(name)
+
+	if err := n.fsys.client.CreateDirectory(ctx, path); err != nil {
+		return nil, errno(err)
+	}
+
+	n.fsys.attrs.invalidate(path)
+	stat, err := n.fsys.stat(ctx, path)
+	if err != nil {
+		return nil, errno(err)
+	}
+
+	return n.newChild(ctx, stat, out), fs.OK
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	path := n.childPath(name)
+
+	if err := n.fsys.client.PutObject(ctx, path, bytes.NewReader(nil)); err != nil {
+		return nil, nil, 0, errno(err)
+	}
+
+	n.fsys.attrs.invalidate(path)
+	stat, err := n.fsys.stat(ctx, path)
+	if err != nil {
+		return nil, nil, 0, errno(err)
+	}
+
+	return n.newChild(ctx, stat, out), newHandle(n.fsys, path), 0, fs.OK
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	path := n.childPath(name)
+	defer n.fsys.attrs.invalidate(path)
+
+	return errno(n.fsys.client.DeleteObject(ctx, path))
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	path := n.childPath(name)
+	defer n.fsys.attrs.invalidate(path)
+
+	return errno(n.fsys.client.DeleteDirectory(ctx, path))
+}
+
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	src := n.childPath(name)
+	dst := pathpkg.Join("/"+newParent.EmbeddedInode().Path(nil), newName)
+	defer n.fsys.attrs.invalidate(src)
+	defer n.fsys.attrs.invalidate(dst)
+
+	if flags&renameNoReplace != 0 {
+		return errno(n.fsys.client.Rename(ctx, src, dst, nil))
+	}
+
+	return errno(n.fsys.client.MoveObject(ctx, src, dst))
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	path := n.path()
+
+	if flags&syscall.O_TRUNC != 0 && flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		if err := n.fsys.client.PutObject(ctx, path, bytes.NewReader(nil)); err != nil {
+			return nil, 0, errno(err)
+		}
+		n.fsys.attrs.invalidate(path)
+	}
+
+	return newHandle(n.fsys, path), 0, fs.OK
+}
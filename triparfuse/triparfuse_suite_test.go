@@ -0,0 +1,13 @@
+package triparfuse_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+func TestTriparFUSE(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TriparFUSE Suite")
+}
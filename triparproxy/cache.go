@@ -0,0 +1,181 @@
+package triparproxy
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+func cacheKey(path string, etag string) string {
+	sum := sha256.Sum256([]byte(path + "\n" + etag))
+	return hex.EncodeToString(sum[:])
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+type cacheFill struct {
+	done chan struct{}
+	err  error
+}
+
+// diskCache keeps whole objects in files named by their keys and evicts the
+// least recently used ones once the total size exceeds maxSize. Concurrent
+// misses for the same key share one download.
+type diskCache struct {
+	dir     string
+	maxSize int64
+
+	mx      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	fills   map[string]*cacheFill
+}
+
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("triparproxy: cache dir error: %w", err)
+	}
+
+	c := &diskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		fills:   make(map[string]*cacheFill),
+	}
+
+	// reuse the files of a previous run, oldest first
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("triparproxy: cache dir error: %w", err)
+	}
+	infos := []os.FileInfo{}
+	for _, entry := range dirEntries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if filepath.Ext(info.Name()) == ".tmp" {
+			os.Remove(filepath.Join(dir, info.Name()))
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		c.add(info.Name(), info.Size())
+	}
+	c.evict()
+
+	return c, nil
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// open returns the cached file for key, calling fill to download it first
+// if it is not cached.
+func (c *diskCache) open(
+	ctx context.Context,
+	key string,
+	size int64,
+	fill func(ctx context.Context, w io.Writer) (int64, error),
+) (*os.File, error) {
+	for {
+		c.mx.Lock()
+		if elem, ok := c.entries[key]; ok {
+			c.lru.MoveToBack(elem)
+			// opened while locked so it can't be evicted in between
+			file, err := os.Open(c.path(key))
+			c.mx.Unlock()
+			return file, err
+		}
+		if f, ok := c.fills[key]; ok {
+			c.mx.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if f.err != nil {
+				return nil, f.err
+			}
+			continue
+		}
+		f := &cacheFill{done: make(chan struct{})}
+		c.fills[key] = f
+		c.mx.Unlock()
+
+		f.err = c.fill(ctx, key, size, fill)
+
+		c.mx.Lock()
+		delete(c.fills, key)
+		if f.err == nil {
+			c.add(key, size)
+			c.evict()
+		}
+		c.mx.Unlock()
+		close(f.done)
+
+		if f.err != nil {
+			return nil, f.err
+		}
+	}
+}
+
+func (c *diskCache) fill(
+	ctx context.Context,
+	key string,
+	size int64,
+	fill func(ctx context.Context, w io.Writer) (int64, error),
+) error {
+	file, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("triparproxy: cache file error: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	n, err := fill(ctx, file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("triparproxy: object changed while downloading: %d of %d bytes", n, size)
+	}
+
+	return os.Rename(file.Name(), c.path(key))
+}
+
+func (c *diskCache) add(key string, size int64) {
+	c.entries[key] = c.lru.PushBack(&cacheEntry{key: key, size: size})
+	c.size += size
+}
+
+func (c *diskCache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		elem := c.lru.Front()
+		entry := elem.Value.(*cacheEntry)
+		c.lru.Remove(elem)
+		delete(c.entries, entry.key)
+		c.size -= entry.size
+		// open files stay readable until they are closed
+		os.Remove(c.path(entry.key))
+	}
+}
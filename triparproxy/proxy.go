@@ -0,0 +1,218 @@
+// Package triparproxy serves objects of a share over HTTP through a local
+// disk cache, so that several application instances can share one warm cache
+// node in front of a slow link to the appliance.
+package triparproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"strings"
+
+	ioutils "github.com/koofr/go-ioutils"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+// DefaultCacheSize is used if Options.CacheSize is 0.
+const DefaultCacheSize = 1 << 30
+
+type Options struct {
+	// Prefixes limits the served paths, e.g. "/public". Requests for other
+	// paths get 404. All paths are served if it is empty.
+	Prefixes []string
+
+	// CacheDir is where cached objects are stored. Files found there when
+	// the handler is created are reused.
+	CacheDir string
+
+	// CacheSize is the number of bytes kept in CacheDir. The least recently
+	// used objects are evicted once it is exceeded.
+	CacheSize int64
+
+	// MaxCachedObjectSize is the size of the largest object which is cached.
+	// Larger objects are streamed from the appliance with ranged requests.
+	// CacheSize is used if it is 0.
+	MaxCachedObjectSize int64
+}
+
+// Handler serves GET and HEAD requests for objects. Objects are downloaded
+// into the cache completely on first access and range, If-None-Match and
+// If-Modified-Since requests are answered from the cached copy. The ETag is
+// derived from the object's size and mtime, so a modified object is
+// downloaded again.
+type Handler struct {
+	client *triparclient.TriparClient
+	opts   Options
+	cache  *diskCache
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func NewHandler(client *triparclient.TriparClient, opts Options) (*Handler, error) {
+	if opts.CacheDir == "" {
+		return nil, fmt.Errorf("triparproxy: CacheDir is required")
+	}
+	if opts.CacheSize == 0 {
+		opts.CacheSize = DefaultCacheSize
+	}
+	if opts.MaxCachedObjectSize == 0 || opts.MaxCachedObjectSize > opts.CacheSize {
+		opts.MaxCachedObjectSize = opts.CacheSize
+	}
+	prefixes := make([]string, len(opts.Prefixes))
+	for i, prefix := range opts.Prefixes {
+		prefixes[i] = strings.TrimSuffix(pathpkg.Clean("/"+prefix), "/")
+	}
+	opts.Prefixes = prefixes
+
+	cache, err := newDiskCache(opts.CacheDir, opts.CacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		client: client,
+		opts:   opts,
+		cache:  cache,
+	}, nil
+}
+
+func (h *Handler) allowed(path string) bool {
+	if len(h.opts.Prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range h.opts.Prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := pathpkg.Clean("/" + r.URL.Path)
+	if !h.allowed(path) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+
+	info, err := h.client.Stat(ctx, path, triparclient.StatSkipIdentity())
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	size := info.Status.Size
+	modTime := info.Status.ModTime()
+	etag := fmt.Sprintf(`"%x-%x"`, size, modTime.UnixNano())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	// conditional requests don't need the content
+	if match := r.Header.Get("If-None-Match"); match == etag || match == "*" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var content io.ReadSeeker
+	if size <= h.opts.MaxCachedObjectSize {
+		file, err := h.cache.open(ctx, cacheKey(path, etag), size, func(ctx context.Context, w io.Writer) (int64, error) {
+			return h.client.GetObjectTo(ctx, path, w)
+		})
+		if err != nil {
+			h.error(w, r, err)
+			return
+		}
+		defer file.Close()
+		content = file
+	} else {
+		object := &objectReader{ctx: ctx, client: h.client, path: path, size: size}
+		defer object.Close()
+		content = object
+	}
+
+	http.ServeContent(w, r, pathpkg.Base(path), modTime, content)
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, triparclient.ErrNotFound), errors.Is(err, triparclient.ErrNotADirectory):
+		http.NotFound(w, r)
+	case errors.Is(err, triparclient.ErrForbidden), errors.Is(err, triparclient.ErrUnauthorized):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	case errors.Is(err, context.Canceled):
+		// the client went away
+	default:
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}
+}
+
+// objectReader is an io.ReadSeeker for objects which are not cached. Every
+// Read after a Seek starts a new ranged GetObject request.
+type objectReader struct {
+	ctx    context.Context
+	client *triparclient.TriparClient
+	path   string
+	size   int64
+	offset int64
+	rd     io.ReadCloser
+}
+
+func (o *objectReader) Read(p []byte) (n int, err error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.rd == nil {
+		o.rd, _, err = o.client.GetObject(o.ctx, o.path, &ioutils.FileSpan{
+			Start: o.offset,
+			End:   o.size - 1,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err = o.rd.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("triparproxy: negative offset")
+	}
+	if offset != o.offset {
+		o.Close()
+		o.offset = offset
+	}
+	return offset, nil
+}
+
+func (o *objectReader) Close() error {
+	if o.rd == nil {
+		return nil
+	}
+	err := o.rd.Close()
+	o.rd = nil
+	return err
+}
@@ -0,0 +1,153 @@
+package triparproxy_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	triparclient "github.com/koofr/go-triparclient"
+	"github.com/koofr/go-triparclient/internal/triparfake"
+	. "github.com/koofr/go-triparclient/triparproxy"
+)
+
+var _ = Describe("Handler", func() {
+	var fake *triparfake.Fake
+	var client *triparclient.TriparClient
+	var dir string
+	var server *httptest.Server
+
+	newServer := func(opts Options) {
+		opts.CacheDir = dir
+		handler, err := NewHandler(client, opts)
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(handler)
+		DeferCleanup(server.Close)
+	}
+
+	BeforeEach(func() {
+		fake = triparfake.New()
+		fake.Mkdir("/public")
+		fake.Mkdir("/private")
+		fake.PutFile("/public/object", "0123456789")
+		fake.PutFile("/private/object", "secret")
+
+		var err error
+		client, err = triparclient.NewTriparClient("http://tripar.example.com", "user", "pass", "share", triparclient.NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+
+		dir, err = os.MkdirTemp("", "triparproxy")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	do := func(method string, path string, headers ...string) (*http.Response, string) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return rsp, string(data)
+	}
+
+	gets := func(path string) (n int) {
+		for _, req := range fake.Requests() {
+			if req == "GET "+path {
+				n++
+			}
+		}
+		return n
+	}
+
+	It("should serve objects and ranges from the cache", func() {
+		newServer(Options{})
+
+		rsp, body := do("GET", "/public/object")
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("0123456789"))
+		Expect(rsp.Header.Get("ETag")).NotTo(BeEmpty())
+
+		rsp, body = do("GET", "/public/object", "Range", "bytes=2-4")
+		Expect(rsp.StatusCode).To(Equal(http.StatusPartialContent))
+		Expect(body).To(Equal("234"))
+
+		Expect(gets("/public/object")).To(Equal(1))
+	})
+
+	It("should support ETag revalidation", func() {
+		newServer(Options{})
+
+		rsp, _ := do("GET", "/public/object")
+		etag := rsp.Header.Get("ETag")
+
+		rsp, _ = do("GET", "/public/object", "If-None-Match", etag)
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotModified))
+
+		fake.PutFile("/public/object", "changed")
+		rsp, body := do("GET", "/public/object", "If-None-Match", etag)
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("changed"))
+		Expect(rsp.Header.Get("ETag")).NotTo(Equal(etag))
+	})
+
+	It("should only serve configured prefixes", func() {
+		newServer(Options{Prefixes: []string{"/public/"}})
+
+		rsp, _ := do("GET", "/private/object")
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+		rsp, _ = do("GET", "/public/missing")
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+		rsp, _ = do("PUT", "/public/object")
+		Expect(rsp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should evict least recently used objects", func() {
+		fake.PutFile("/public/a", strings.Repeat("a", 10))
+		fake.PutFile("/public/b", strings.Repeat("b", 10))
+		newServer(Options{CacheSize: 25})
+
+		do("GET", "/public/object")
+		do("GET", "/public/a")
+		do("GET", "/public/object")
+		do("GET", "/public/b")
+
+		do("GET", "/public/object")
+		do("GET", "/public/a")
+		Expect(gets("/public/object")).To(Equal(1))
+		Expect(gets("/public/a")).To(Equal(2))
+	})
+
+	It("should stream objects larger than the cache", func() {
+		newServer(Options{MaxCachedObjectSize: 5})
+
+		rsp, body := do("GET", "/public/object", "Range", "bytes=7-")
+		Expect(rsp.StatusCode).To(Equal(http.StatusPartialContent))
+		Expect(body).To(Equal("789"))
+		_, body = do("GET", "/public/object")
+		Expect(body).To(Equal("0123456789"))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should reuse cached files after a restart", func() {
+		newServer(Options{})
+		do("GET", "/public/object")
+
+		newServer(Options{})
+		_, body := do("GET", "/public/object")
+		Expect(body).To(Equal("0123456789"))
+		Expect(gets("/public/object")).To(Equal(1))
+	})
+})
@@ -0,0 +1,13 @@
+package triparproxy_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+func TestTriparProxy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TriparProxy Suite")
+}
@@ -0,0 +1,484 @@
+// Package triparqueue is a persistent queue of share operations. Jobs are
+// stored as files, so that pending work survives restarts, and are processed
+// with bounded concurrency and retries. Jobs which keep failing are moved to
+// a dead letter directory for inspection.
+package triparqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+type Op string
+
+const (
+	// OpPut uploads the local file LocalPath to Path. The file must not be
+	// modified or removed until the job is done.
+	OpPut Op = "put"
+	// OpCopy copies Path to Destination.
+	OpCopy Op = "copy"
+	// OpDelete deletes the object at Path. Missing objects are not an error,
+	// so that retries are idempotent.
+	OpDelete Op = "delete"
+)
+
+const (
+	DefaultConcurrency = 4
+	DefaultMaxAttempts = 5
+	DefaultRetryDelay  = time.Second
+)
+
+var ErrInvalidJob = errors.New("invalid job")
+
+type Job struct {
+	ID          string `json:"id"`
+	Op          Op     `json:"op"`
+	Path        string `json:"path"`
+	Destination string `json:"destination,omitempty"`
+	LocalPath   string `json:"localPath,omitempty"`
+
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueuedAt"`
+	NextAttempt time.Time `json:"nextAttempt"`
+}
+
+type Options struct {
+	// Dir holds the queue. Pending jobs are stored in Dir/pending and dead
+	// letters in Dir/dead.
+	Dir string
+
+	// Concurrency is the number of jobs processed at the same time,
+	// DefaultConcurrency if 0.
+	Concurrency int
+
+	// MaxAttempts is the number of attempts after which a job is moved to
+	// the dead letters, DefaultMaxAttempts if 0.
+	MaxAttempts int
+
+	// RetryDelay is the delay before the second attempt, which doubles for
+	// every further attempt. DefaultRetryDelay if 0.
+	RetryDelay time.Duration
+
+	// OnDone is called after every attempt, with the error if it failed.
+	OnDone func(job Job, err error)
+}
+
+type Queue struct {
+	client *triparclient.TriparClient
+	opts   Options
+
+	mx       sync.Mutex
+	jobs     map[string]*Job
+	inFlight map[string]bool
+	wake     chan struct{}
+}
+
+// Open opens the queue in opts.Dir, creating it if needed, and loads the
+// pending jobs. Jobs which were being processed when the process stopped are
+// attempted again.
+func Open(client *triparclient.TriparClient, opts Options) (*Queue, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("triparqueue: Dir is required")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+
+	q := &Queue{
+		client:   client,
+		opts:     opts,
+		jobs:     map[string]*Job{},
+		inFlight: map[string]bool{},
+		wake:     make(chan struct{}, 1),
+	}
+
+	for _, dir := range []string{q.pendingDir(), q.deadDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("triparqueue: create dir error: %w", err)
+		}
+	}
+
+	jobs, err := readJobs(q.pendingDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		q.jobs[job.ID] = job
+	}
+
+	return q, nil
+}
+
+func (q *Queue) pendingDir() string {
+	return filepath.Join(q.opts.Dir, "pending")
+}
+
+func (q *Queue) deadDir() string {
+	return filepath.Join(q.opts.Dir, "dead")
+}
+
+func newJobID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	// zero padded, so that ids sort in enqueue order
+	return fmt.Sprintf("%020d-%s", now.UnixNano(), hex.EncodeToString(suffix)), nil
+}
+
+func validate(job *Job) error {
+	switch job.Op {
+	case OpPut:
+		if job.Path == "" || job.LocalPath == "" {
+			return fmt.Errorf("triparqueue: put needs Path and LocalPath: %w", ErrInvalidJob)
+		}
+	case OpCopy:
+		if job.Path == "" || job.Destination == "" {
+			return fmt.Errorf("triparqueue: copy needs Path and Destination: %w", ErrInvalidJob)
+		}
+	case OpDelete:
+		if job.Path == "" {
+			return fmt.Errorf("triparqueue: delete needs Path: %w", ErrInvalidJob)
+		}
+	default:
+		return fmt.Errorf("triparqueue: unknown op %q: %w", job.Op, ErrInvalidJob)
+	}
+	return nil
+}
+
+// Enqueue stores a job and returns its ID. Only Op, Path, Destination and
+// LocalPath are used. The job is stored before Enqueue returns.
+func (q *Queue) Enqueue(job Job) (id string, err error) {
+	if err := validate(&job); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	job.ID, err = newJobID(now)
+	if err != nil {
+		return "", fmt.Errorf("triparqueue: job id error: %w", err)
+	}
+	job.Attempts = 0
+	job.LastError = ""
+	job.EnqueuedAt = now
+	job.NextAttempt = now
+
+	if err := writeJob(q.pendingDir(), &job); err != nil {
+		return "", err
+	}
+
+	q.mx.Lock()
+	q.jobs[job.ID] = &job
+	q.mx.Unlock()
+
+	q.notify()
+
+	return job.ID, nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the jobs which are not done, in enqueue order.
+func (q *Queue) Pending() []Job {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sortJobs(jobs)
+	return jobs
+}
+
+// DeadLetters returns the jobs which failed MaxAttempts times.
+func (q *Queue) DeadLetters() ([]Job, error) {
+	jobs, err := readJobs(q.deadDir())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Job, len(jobs))
+	for i, job := range jobs {
+		result[i] = *job
+	}
+	return result, nil
+}
+
+// Requeue moves a dead letter back to the pending jobs, with its attempts
+// reset.
+func (q *Queue) Requeue(id string) error {
+	deadPath := filepath.Join(q.deadDir(), id+".json")
+	job, err := readJob(deadPath)
+	if err != nil {
+		return err
+	}
+
+	job.Attempts = 0
+	job.NextAttempt = time.Now()
+	if err := writeJob(q.pendingDir(), job); err != nil {
+		return err
+	}
+	if err := os.Remove(deadPath); err != nil {
+		return fmt.Errorf("triparqueue: remove dead letter error: %w", err)
+	}
+
+	q.mx.Lock()
+	q.jobs[job.ID] = job
+	q.mx.Unlock()
+
+	q.notify()
+
+	return nil
+}
+
+// Run processes jobs until ctx is done. Jobs interrupted by the cancellation
+// are attempted again by the next Run.
+func (q *Queue) Run(ctx context.Context) error {
+	sem := make(chan struct{}, q.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		job, wait := q.next()
+
+		if job != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				q.release(job.ID)
+				return ctx.Err()
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				q.process(ctx, job)
+			}()
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-q.wake:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// idleWait is how long Run waits for new jobs if none are scheduled.
+const idleWait = time.Minute
+
+// next claims the oldest job which is due, or returns how long to wait for
+// the next one.
+func (q *Queue) next() (*Job, time.Duration) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	now := time.Now()
+	var due *Job
+	wait := idleWait
+
+	for id, job := range q.jobs {
+		if q.inFlight[id] {
+			continue
+		}
+		if job.NextAttempt.After(now) {
+			if d := job.NextAttempt.Sub(now); d < wait {
+				wait = d
+			}
+			continue
+		}
+		if due == nil || id < due.ID {
+			due = job
+		}
+	}
+
+	if due == nil {
+		return nil, wait
+	}
+
+	q.inFlight[due.ID] = true
+	job := *due
+	return &job, 0
+}
+
+func (q *Queue) release(id string) {
+	q.mx.Lock()
+	delete(q.inFlight, id)
+	q.mx.Unlock()
+}
+
+func (q *Queue) process(ctx context.Context, job *Job) {
+	defer q.notify()
+	defer q.release(job.ID)
+
+	err := q.execute(ctx, job)
+	if ctx.Err() != nil {
+		// interrupted, not failed
+		return
+	}
+
+	if q.opts.OnDone != nil {
+		q.opts.OnDone(*job, err)
+	}
+
+	if err == nil {
+		q.mx.Lock()
+		delete(q.jobs, job.ID)
+		q.mx.Unlock()
+		os.Remove(filepath.Join(q.pendingDir(), job.ID+".json"))
+		return
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	job.NextAttempt = time.Now().Add(q.opts.RetryDelay << (job.Attempts - 1))
+
+	if job.Attempts >= q.opts.MaxAttempts || errors.Is(err, ErrInvalidJob) {
+		if writeJob(q.deadDir(), job) == nil {
+			os.Remove(filepath.Join(q.pendingDir(), job.ID+".json"))
+			q.mx.Lock()
+			delete(q.jobs, job.ID)
+			q.mx.Unlock()
+			return
+		}
+	}
+
+	// the attempt is stored so that the backoff continues after a restart
+	writeJob(q.pendingDir(), job)
+
+	q.mx.Lock()
+	q.jobs[job.ID] = job
+	q.mx.Unlock()
+}
+
+func (q *Queue) execute(ctx context.Context, job *Job) error {
+	if err := validate(job); err != nil {
+		return err
+	}
+
+	switch job.Op {
+	case OpPut:
+		file, err := os.Open(job.LocalPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return q.client.PutObject(ctx, job.Path, file, triparclient.PutSizeHint(info.Size()))
+
+	case OpCopy:
+		return q.client.CopyObject(ctx, job.Path, job.Destination)
+
+	default:
+		return q.client.DeleteObjectIfExists(ctx, job.Path)
+	}
+}
+
+func writeJob(dir string, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, job.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("triparqueue: write job error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, job.ID+".json"))
+	}
+	if err != nil {
+		return fmt.Errorf("triparqueue: write job error: %w", err)
+	}
+
+	return nil
+}
+
+func readJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("triparqueue: read job error: %w", err)
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("triparqueue: read job %s error: %w", path, err)
+	}
+	return job, nil
+}
+
+func readJobs(dir string) ([]*Job, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("triparqueue: read dir error: %w", err)
+	}
+
+	jobs := []*Job{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			// temporary files of interrupted writes
+			continue
+		}
+		job, err := readJob(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+}
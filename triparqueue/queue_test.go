@@ -0,0 +1,133 @@
+package triparqueue_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	triparclient "github.com/koofr/go-triparclient"
+	"github.com/koofr/go-triparclient/internal/triparfake"
+	. "github.com/koofr/go-triparclient/triparqueue"
+)
+
+var _ = Describe("Queue", func() {
+	var fake *triparfake.Fake
+	var client *triparclient.TriparClient
+	var dir string
+
+	BeforeEach(func() {
+		fake = triparfake.New()
+		fake.Mkdir("/root")
+
+		var err error
+		client, err = triparclient.NewTriparClient("http://tripar.example.com", "user", "pass", "share", triparclient.NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+
+		dir, err = os.MkdirTemp("", "triparqueue")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	run := func(q *Queue) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- q.Run(ctx)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+	}
+
+	It("should process jobs", func() {
+		local := filepath.Join(dir, "upload")
+		Expect(os.WriteFile(local, []byte("12345"), 0o644)).To(Succeed())
+		fake.PutFile("/root/old", "old")
+
+		q, err := Open(client, Options{Dir: filepath.Join(dir, "queue")})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = q.Enqueue(Job{Op: OpPut, Path: "/root/a", LocalPath: local})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.Enqueue(Job{Op: OpCopy, Path: "/root/old", Destination: "/root/b"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.Enqueue(Job{Op: OpDelete, Path: "/root/old"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.Enqueue(Job{Op: OpDelete, Path: "/root/missing"})
+		Expect(err).NotTo(HaveOccurred())
+
+		q2, err := Open(client, Options{Dir: filepath.Join(dir, "queue"), Concurrency: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q2.Pending()).To(HaveLen(4))
+		Expect(q2.Pending()[0].Op).To(Equal(OpPut))
+
+		run(q2)
+		Eventually(q2.Pending).Should(BeEmpty())
+
+		data, _ := fake.File("/root/a")
+		Expect(string(data)).To(Equal("12345"))
+		data, _ = fake.File("/root/b")
+		Expect(string(data)).To(Equal("old"))
+		Expect(fake.Exists("/root/old")).To(BeFalse())
+
+		entries, err := os.ReadDir(filepath.Join(dir, "queue", "pending"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should retry and dead letter failing jobs", func() {
+		attempts := make(chan error, 10)
+		q, err := Open(client, Options{
+			Dir:         filepath.Join(dir, "queue"),
+			MaxAttempts: 3,
+			RetryDelay:  time.Millisecond,
+			OnDone: func(job Job, err error) {
+				attempts <- err
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		id, err := q.Enqueue(Job{Op: OpCopy, Path: "/root/missing", Destination: "/root/b"})
+		Expect(err).NotTo(HaveOccurred())
+
+		run(q)
+		for i := 0; i < 3; i++ {
+			Eventually(attempts).Should(Receive(MatchError(triparclient.ErrNotFound)))
+		}
+		Eventually(q.Pending).Should(BeEmpty())
+
+		dead, err := q.DeadLetters()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dead).To(HaveLen(1))
+		Expect(dead[0].ID).To(Equal(id))
+		Expect(dead[0].Attempts).To(Equal(3))
+		Expect(dead[0].LastError).To(ContainSubstring("not found"))
+
+		fake.PutFile("/root/missing", "found")
+		Expect(q.Requeue(id)).To(Succeed())
+		Eventually(attempts).Should(Receive(BeNil()))
+		Eventually(q.Pending).Should(BeEmpty())
+		Expect(fake.Exists("/root/b")).To(BeTrue())
+
+		dead, err = q.DeadLetters()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dead).To(BeEmpty())
+	})
+
+	It("should reject invalid jobs", func() {
+		q, err := Open(client, Options{Dir: filepath.Join(dir, "queue")})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = q.Enqueue(Job{Op: OpCopy, Path: "/root/a"})
+		Expect(err).To(MatchError(ErrInvalidJob))
+		_, err = q.Enqueue(Job{Op: "move", Path: "/root/a"})
+		Expect(err).To(MatchError(ErrInvalidJob))
+	})
+})
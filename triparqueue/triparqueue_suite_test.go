@@ -0,0 +1,13 @@
+package triparqueue_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+func TestTriparQueue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TriparQueue Suite")
+}
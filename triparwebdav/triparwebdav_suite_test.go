@@ -0,0 +1,13 @@
+package triparwebdav_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+func TestTriparWebDAV(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TriparWebDAV Suite")
+}
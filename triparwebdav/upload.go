@@ -0,0 +1,106 @@
+package triparwebdav
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	pathpkg "path"
+	"time"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+func bytesReader(p []byte) io.Reader {
+	return bytes.NewReader(p)
+}
+
+// upload is a new or truncated file. Writes are streamed through a pipe to
+// PutObject, which uploads them with the client's chunk pipeline. It only
+// supports sequential writes.
+type upload struct {
+	fs   *FileSystem
+	name string
+
+	pw      *io.PipeWriter
+	done    chan error
+	written int64
+	closed  bool
+	err     error
+}
+
+func newUpload(fsys *FileSystem, ctx context.Context, name string) *upload {
+	pr, pw := io.Pipe()
+
+	u := &upload{
+		fs:   fsys,
+		name: name,
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := fsys.client.PutObject(ctx, name, pr)
+		// unblock writes if the upload fails before reading everything
+		pr.CloseWithError(err)
+		u.done <- err
+	}()
+
+	return u
+}
+
+func (u *upload) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: u.name, Err: fs.ErrPermission}
+}
+
+func (u *upload) Write(p []byte) (int, error) {
+	if u.closed {
+		return 0, &fs.PathError{Op: "write", Path: u.name, Err: fs.ErrClosed}
+	}
+
+	n, err := u.pw.Write(p)
+	u.written += int64(n)
+	if err != nil {
+		return n, osError("write", u.name, err)
+	}
+	return n, nil
+}
+
+func (u *upload) Seek(offset int64, whence int) (int64, error) {
+	// webdav.Handler seeks to find the size, which is allowed as long as the
+	// position does not change
+	switch {
+	case whence == io.SeekCurrent && offset == 0,
+		whence == io.SeekEnd && offset == 0,
+		whence == io.SeekStart && offset == u.written:
+		return u.written, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: u.name, Err: fs.ErrInvalid}
+}
+
+func (u *upload) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: u.name, Err: triparclient.ErrNotADirectory}
+}
+
+func (u *upload) Stat() (fs.FileInfo, error) {
+	return &fileInfo{
+		name:    pathpkg.Base(u.name),
+		size:    u.written,
+		mode:    0644,
+		modTime: time.Now(),
+	}, nil
+}
+
+// Close finishes the upload and returns its error.
+func (u *upload) Close() error {
+	if u.closed {
+		return u.err
+	}
+	u.closed = true
+
+	u.pw.Close()
+	if err := <-u.done; err != nil {
+		u.err = osError("close", u.name, err)
+	}
+	return u.err
+}
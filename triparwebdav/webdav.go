@@ -0,0 +1,313 @@
+// Package triparwebdav exposes a TriparClient as a webdav.FileSystem, so the
+// share can be served with webdav.Handler and mounted by desktop clients.
+package triparwebdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"time"
+
+	ioutils "github.com/koofr/go-ioutils"
+	"golang.org/x/net/webdav"
+
+	triparclient "github.com/koofr/go-triparclient"
+)
+
+// FileSystem implements webdav.FileSystem on top of a TriparClient. PROPFIND
+// is backed by List and Stat, reads by ranged GetObject requests and writes
+// by PutObject for new or truncated files and ranged WriteAt requests
+// otherwise.
+type FileSystem struct {
+	client *triparclient.TriparClient
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+func NewFileSystem(client *triparclient.TriparClient) *FileSystem {
+	return &FileSystem{
+		client: client,
+	}
+}
+
+// osError maps client errors to the os errors webdav.Handler translates to
+// HTTP statuses.
+func osError(op string, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, triparclient.ErrNotFound):
+		err = fs.ErrNotExist
+	case errors.Is(err, triparclient.ErrAlreadyExists):
+		err = fs.ErrExist
+	case errors.Is(err, triparclient.ErrForbidden), errors.Is(err, triparclient.ErrUnauthorized):
+		err = fs.ErrPermission
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return osError("mkdir", name, f.client.CreateDirectory(ctx, name))
+}
+
+func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	info, err := f.client.Stat(ctx, name, triparclient.StatSkipIdentity())
+	exists := err == nil
+	if err != nil && !errors.Is(err, triparclient.ErrNotFound) {
+		return nil, osError("open", name, err)
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		if !exists {
+			return nil, osError("open", name, err)
+		}
+		if info.IsDir() {
+			return &dir{fs: f, ctx: ctx, name: name, info: info}, nil
+		}
+		return &file{fs: f, ctx: ctx, name: name, info: info}, nil
+	}
+
+	if exists && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, osError("open", name, triparclient.ErrAlreadyExists)
+	}
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, osError("open", name, err)
+	}
+	if exists && info.IsDir() {
+		return nil, osError("open", name, triparclient.ErrNotAFile)
+	}
+
+	if !exists || flag&os.O_TRUNC != 0 {
+		return newUpload(f, ctx, name), nil
+	}
+
+	return &file{fs: f, ctx: ctx, name: name, info: info, writable: true}, nil
+}
+
+func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return osError("remove", name, f.client.DeleteTree(ctx, name))
+}
+
+func (f *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	return osError("rename", oldName, f.client.MoveObject(ctx, oldName, newName))
+}
+
+func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := f.client.Stat(ctx, name, triparclient.StatSkipIdentity())
+	if err != nil {
+		return nil, osError("stat", name, err)
+	}
+
+	return newFileInfo(name, info), nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	stat    triparclient.Stat
+}
+
+func newFileInfo(name string, info triparclient.Stat) *fileInfo {
+	mode := os.FileMode(info.Status.Mode & 0777)
+	if info.IsDir() {
+		mode |= os.ModeDir
+	}
+
+	return &fileInfo{
+		name:    pathpkg.Base(name),
+		size:    info.Status.Size,
+		mode:    mode,
+		modTime: info.Status.ModTime(),
+		stat:    info,
+	}
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() os.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+
+// Sys returns the triparclient.Stat the info was created from.
+func (i *fileInfo) Sys() interface{} { return i.stat }
+
+// file is an object opened for reading or for ranged writes. Reads open a
+// GetObject reader at the current offset, which is reopened after seeking.
+type file struct {
+	fs       *FileSystem
+	ctx      context.Context
+	name     string
+	info     triparclient.Stat
+	writable bool
+
+	offset int64
+	reader io.ReadCloser
+}
+
+func (f *file) closeReader() error {
+	if f.reader == nil {
+		return nil
+	}
+	err := f.reader.Close()
+	f.reader = nil
+	return err
+}
+
+func (f *file) Read(p []byte) (n int, err error) {
+	if f.offset >= f.info.Status.Size {
+		return 0, io.EOF
+	}
+
+	if f.reader == nil {
+		rd, _, err := f.fs.client.GetObject(f.ctx, f.name, &ioutils.FileSpan{
+			Start: f.offset,
+			End:   f.info.Status.Size - 1,
+		})
+		if err != nil {
+			return 0, osError("read", f.name, err)
+		}
+		f.reader = rd
+	}
+
+	n, err = f.reader.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) Write(p []byte) (n int, err error) {
+	if !f.writable {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+
+	if err := f.closeReader(); err != nil {
+		return 0, err
+	}
+
+	if err := f.fs.client.WriteAt(f.ctx, f.name, f.offset, bytesReader(p), int64(len(p))); err != nil {
+		return 0, osError("write", f.name, err)
+	}
+
+	f.offset += int64(len(p))
+	if f.offset > f.info.Status.Size {
+		f.info.Status.Size = f.offset
+	}
+	return len(p), nil
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Status.Size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset != f.offset {
+		if err := f.closeReader(); err != nil {
+			return 0, err
+		}
+		f.offset = offset
+	}
+
+	return f.offset, nil
+}
+
+func (f *file) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: triparclient.ErrNotADirectory}
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return newFileInfo(f.name, f.info), nil
+}
+
+func (f *file) Close() error {
+	return f.closeReader()
+}
+
+// dir is an opened directory. Its entries are listed on the first Readdir.
+type dir struct {
+	fs   *FileSystem
+	ctx  context.Context
+	name string
+	info triparclient.Stat
+
+	entries []fs.FileInfo
+	listed  bool
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: triparclient.ErrNotAFile}
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: d.name, Err: triparclient.ErrNotAFile}
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.entries = nil
+		d.listed = false
+		return 0, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *dir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.listed {
+		entries, err := d.fs.client.List(d.ctx, d.name)
+		if err != nil {
+			return nil, osError("readdir", d.name, err)
+		}
+		for _, entry := range entries.Entries {
+			name := pathpkg.Join(d.name, entry.Name)
+			info, err := d.fs.client.Stat(d.ctx, name, triparclient.StatSkipIdentity())
+			if errors.Is(err, triparclient.ErrNotFound) {
+				// deleted since listing
+				continue
+			}
+			if err != nil {
+				return nil, osError("readdir", name, err)
+			}
+			d.entries = append(d.entries, newFileInfo(name, info))
+		}
+		d.listed = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return newFileInfo(d.name, d.info), nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package triparwebdav_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/webdav"
+
+	triparclient "github.com/koofr/go-triparclient"
+	"github.com/koofr/go-triparclient/internal/triparfake"
+	. "github.com/koofr/go-triparclient/triparwebdav"
+)
+
+var _ = Describe("FileSystem", func() {
+	var ctx context.Context
+	var fake *triparfake.Fake
+	var fsys *FileSystem
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = triparfake.New()
+		fake.Mkdir("/root")
+
+		client, err := triparclient.NewTriparClient("http://tripar.example.com", "user", "pass", "share", triparclient.NewBufferPool(4, 1024), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		client.HTTPClient.Client = &http.Client{Transport: fake}
+
+		fsys = NewFileSystem(client)
+		server = httptest.NewServer(&webdav.Handler{
+			FileSystem: fsys,
+			LockSystem: webdav.NewMemLS(),
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	do := func(method string, path string, body string, headers ...string) (*http.Response, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return rsp, string(data)
+	}
+
+	It("should put and get files", func() {
+		content := strings.Repeat("0123456789", 500)
+
+		rsp, _ := do("PUT", "/root/object", content)
+		Expect(rsp.StatusCode).To(Equal(http.StatusCreated))
+		data, ok := fake.File("/root/object")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(content))
+
+		rsp, body := do("GET", "/root/object", "")
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(content))
+
+		rsp, body = do("GET", "/root/object", "", "Range", "bytes=2000-2004")
+		Expect(rsp.StatusCode).To(Equal(http.StatusPartialContent))
+		Expect(body).To(Equal("01234"))
+	})
+
+	It("should list directories", func() {
+		fake.Mkdir("/root/dir")
+		fake.PutFile("/root/object", "12345")
+
+		rsp, body := do("PROPFIND", "/root/", "", "Depth", "1")
+		Expect(rsp.StatusCode).To(Equal(http.StatusMultiStatus))
+		Expect(body).To(ContainSubstring("<D:href>/root/dir/</D:href>"))
+		Expect(body).To(ContainSubstring("<D:href>/root/object</D:href>"))
+		Expect(body).To(ContainSubstring("<D:getcontentlength>5</D:getcontentlength>"))
+	})
+
+	It("should create, move and delete", func() {
+		rsp, _ := do("MKCOL", "/root/dir", "")
+		Expect(rsp.StatusCode).To(Equal(http.StatusCreated))
+		fake.PutFile("/root/dir/object", "12345")
+
+		rsp, _ = do("MOVE", "/root/dir", "", "Destination", server.URL+"/root/moved")
+		Expect(rsp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(fake.Exists("/root/moved/object")).To(BeTrue())
+
+		rsp, _ = do("DELETE", "/root/moved", "")
+		Expect(rsp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(fake.Exists("/root/moved")).To(BeFalse())
+
+		rsp, _ = do("GET", "/root/moved/object", "")
+		Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should write ranges into existing files", func() {
+		fake.PutFile("/root/object", "0123456789")
+
+		f, err := fsys.OpenFile(ctx, "/root/object", os.O_RDWR, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Seek(8, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write([]byte("abcd"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		data, _ := fake.File("/root/object")
+		Expect(string(data)).To(Equal("01234567abcd"))
+	})
+
+	It("should map errors", func() {
+		_, err := fsys.Stat(ctx, "/root/missing")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		fake.Mkdir("/root/dir")
+		Expect(os.IsExist(fsys.Mkdir(ctx, "/root/dir", 0755))).To(BeTrue())
+
+		_, err = fsys.OpenFile(ctx, "/root/dir", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		Expect(os.IsExist(err)).To(BeTrue())
+	})
+})
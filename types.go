@@ -2,6 +2,11 @@ package triparclient
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Status struct {
@@ -11,18 +16,106 @@ type Status struct {
 	Ctime   float64 `json:"ctime"`
 	Dev     int32   `json:"dev"`
 	Gid     int32   `json:"gid"`
-	//	Ino     int64   `json:"ino"`
-	Mode  int32   `json:"mode"`
-	Mtime float64 `json:"mtime"`
-	Nlink int32   `json:"nlink"`
-	Rdev  int32   `json:"rdev"`
-	Size  int64   `json:"size"`
-	Uid   int32   `json:"uid"`
+	Ino     uint64  `json:"ino"`
+	Mode    int32   `json:"mode" tripar:"required"`
+	Mtime   float64 `json:"mtime"`
+	Nlink   int32   `json:"nlink"`
+	Rdev    int32   `json:"rdev"`
+	Size    int64   `json:"size" tripar:"required"`
+	Uid     int32   `json:"uid"`
+
+	// exact times in nanoseconds, parsed from the response without the
+	// precision loss of float64
+	atimeNsec int64
+	mtimeNsec int64
+	ctimeNsec int64
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+	type status Status
+	if err := json.Unmarshal(data, (*status)(s)); err != nil {
+		return err
+	}
+
+	times := struct {
+		Atime json.Number `json:"atime"`
+		Mtime json.Number `json:"mtime"`
+		Ctime json.Number `json:"ctime"`
+	}{}
+	if err := json.Unmarshal(data, &times); err != nil {
+		return err
+	}
+
+	s.atimeNsec = parseUnixNsec(times.Atime)
+	s.mtimeNsec = parseUnixNsec(times.Mtime)
+	s.ctimeNsec = parseUnixNsec(times.Ctime)
+
+	return nil
+}
+
+// parseUnixNsec parses decimal seconds, e.g. "1700000000.123456789", to
+// nanoseconds. It returns 0 for other formats, which fall back to the float
+// fields.
+func parseUnixNsec(n json.Number) int64 {
+	secs, frac, _ := strings.Cut(string(n), ".")
+	if secs == "" || strings.HasPrefix(secs, "-") || strings.ContainsAny(frac, "eE+-") {
+		return 0
+	}
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return 0
+	}
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+	frac += strings.Repeat("0", 9-len(frac))
+	nsec, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return sec*1e9 + nsec
+}
+
+func statusTime(nsec int64, secs float64) time.Time {
+	if nsec != 0 {
+		return time.Unix(0, nsec)
+	}
+	// seconds and fraction are converted separately, as secs*1e9 loses
+	// precision
+	sec := math.Floor(secs)
+	return time.Unix(int64(sec), int64(math.Round((secs-sec)*1e9)))
+}
+
+// AccessTime returns Atime with the precision provided by the appliance.
+func (s Status) AccessTime() time.Time {
+	return statusTime(s.atimeNsec, s.Atime)
+}
+
+// ModTime returns Mtime with the precision provided by the appliance.
+func (s Status) ModTime() time.Time {
+	return statusTime(s.mtimeNsec, s.Mtime)
+}
+
+// ChangeTime returns Ctime with the precision provided by the appliance.
+func (s Status) ChangeTime() time.Time {
+	return statusTime(s.ctimeNsec, s.Ctime)
 }
 
 type Stat struct {
-	Path   string `json:"path"`
-	Status Status `json:"status"`
+	Path   string `json:"path" tripar:"required"`
+	Status Status `json:"status" tripar:"required"`
+
+	UserName  string `json:"-"`
+	GroupName string `json:"-"`
+
+	// ContentType is the object's content type as returned by GetObject. The
+	// appliance always returns application/octet-stream, unless
+	// GetSniffContentType is used.
+	ContentType string `json:"-"`
+
+	// ContentEncoding is the object's Content-Encoding as returned by
+	// GetObject, if any.
+	ContentEncoding string `json:"-"`
 }
 
 func (s Stat) IsDir() bool {
@@ -30,21 +123,62 @@ func (s Stat) IsDir() bool {
 }
 
 type Entries struct {
-	Entries []Entry `json:"entries"`
+	Entries []Entry `json:"entries" tripar:"required"`
 }
 
+const (
+	EntryTypeFile      = "file"
+	EntryTypeDirectory = "directory"
+)
+
 type Entry struct {
-	Name string `json:"name"`
+	Name string `json:"name" tripar:"required"`
+
+	// Type, Size and Mtime are only populated by firmware whose ls response
+	// includes them. Older firmware returns names only, see HasMetadata.
+	Type  string  `json:"type,omitempty"`
+	Size  int64   `json:"size,omitempty"`
+	Mtime float64 `json:"mtime,omitempty"`
+}
+
+func (e Entry) HasMetadata() bool {
+	return e.Type != ""
 }
 
+func (e *Entry) setMetadata(info Stat) {
+	e.Type = EntryTypeFile
+	if info.IsDir() {
+		e.Type = EntryTypeDirectory
+	}
+	e.Size = info.Status.Size
+	e.Mtime = info.Status.Mtime
+}
+
+func (e Entry) IsDir() bool {
+	switch e.Type {
+	case EntryTypeDirectory, "dir", "d":
+		return true
+	default:
+		return false
+	}
+}
+
+// maxRawErrorSize bounds Error.Raw so that unexpectedly large error
+// responses aren't kept in memory with the error.
+const maxRawErrorSize = 4 * 1024
+
 type Error struct {
 	Code int    `json:"error_code"`
 	LMsg string `json:"long_message"`
 	SMsg string `json:"short_message"`
+
+	// Raw is the error response body, truncated to 4KB, for diagnosing
+	// errors the client doesn't know about.
+	Raw []byte `json:"-"`
 }
 
 func (e *Error) Error() string {
-	return e.SMsg
+	return fmt.Sprintf("%s (code %d)", e.SMsg, e.Code)
 }
 
 func UnmarshalError(data []byte) (*Error, error) {
@@ -62,9 +196,16 @@ func UnmarshalError(data []byte) (*Error, error) {
 		return nil, nil
 	}
 
+	raw := data
+	if len(raw) > maxRawErrorSize {
+		raw = raw[:maxRawErrorSize]
+	}
+	raw = append([]byte{}, raw...)
+
 	return &Error{
 		Code: *required.Code,
 		LMsg: *required.LMsg,
 		SMsg: *required.SMsg,
+		Raw:  raw,
 	}, nil
 }
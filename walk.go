@@ -0,0 +1,171 @@
+package triparclient
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+const DefaultWalkConcurrency = 4
+
+type WalkEntry struct {
+	Path  string
+	Entry Entry
+	IsDir bool
+}
+
+type walkOptions struct {
+	concurrency int
+	maxDepth    int
+}
+
+func joinPath(dir string, name string) string {
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+func (tp *TriparClient) entryIsDir(ctx context.Context, path string, entry Entry) (bool, error) {
+	if entry.HasMetadata() {
+		return entry.IsDir(), nil
+	}
+
+	info, err := tp.Stat(ctx, path, StatSkipIdentity())
+	if err != nil {
+		return false, err
+	}
+
+	return info.IsDir(), nil
+}
+
+// walk lists the tree under root with at most opts.concurrency concurrent
+// requests. fn is called for every descendant, never concurrently, and may
+// return fs.SkipDir to skip a directory's contents.
+func (tp *TriparClient) walk(ctx context.Context, root string, opts walkOptions, fn func(entry WalkEntry) error) error {
+	concurrency := opts.concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultWalkConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	var firstErr error
+
+	fail := func(err error) {
+		mx.Lock()
+		defer mx.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	listDir := func(dir string) ([]WalkEntry, error) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		entries, err := tp.List(ctx, dir)
+		<-sem
+		if err != nil {
+			return nil, err
+		}
+
+		// entries without metadata are stat-ed concurrently, sharing the
+		// walk's limit
+		if err := tp.statEntries(ctx, "ListRecursive", dir, entries.Entries, sem); err != nil {
+			return nil, err
+		}
+
+		walkEntries := make([]WalkEntry, 0, len(entries.Entries))
+		for _, entry := range entries.Entries {
+			walkEntries = append(walkEntries, WalkEntry{
+				Path:  joinPath(dir, entry.Name),
+				Entry: entry,
+				IsDir: entry.IsDir(),
+			})
+		}
+
+		return walkEntries, nil
+	}
+
+	var walkDir func(dir string, depth int)
+	walkDir = func(dir string, depth int) {
+		defer wg.Done()
+
+		entries, err := listDir(dir)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		for _, entry := range entries {
+			mx.Lock()
+			if firstErr != nil {
+				mx.Unlock()
+				return
+			}
+			err := fn(entry)
+			mx.Unlock()
+
+			if err == fs.SkipDir {
+				continue
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			if entry.IsDir && (opts.maxDepth <= 0 || depth < opts.maxDepth) {
+				wg.Add(1)
+				path := entry.Path
+				goLabeled(ctx, "ListRecursive", path, func() {
+					walkDir(path, depth+1)
+				})
+			}
+		}
+	}
+
+	wg.Add(1)
+	goLabeled(ctx, "ListRecursive", root, func() {
+		walkDir(root, 1)
+	})
+
+	wg.Wait()
+
+	return firstErr
+}
+
+type ListRecursiveOptions struct {
+	// MaxDepth limits how deep the listing descends, 1 lists only the direct
+	// children of the path. 0 means unlimited.
+	MaxDepth int
+
+	// Concurrency is the number of concurrent requests, DefaultWalkConcurrency
+	// if 0.
+	Concurrency int
+}
+
+// ListRecursive calls fn for every descendant of path. Entries are streamed
+// as directories are listed, so their order is not defined.
+func (tp *TriparClient) ListRecursive(
+	ctx context.Context,
+	path string,
+	opts *ListRecursiveOptions,
+	fn func(entry WalkEntry) error,
+) (err error) {
+	if opts == nil {
+		opts = &ListRecursiveOptions{}
+	}
+
+	return tp.walk(ctx, path, walkOptions{
+		concurrency: opts.Concurrency,
+		maxDepth:    opts.MaxDepth,
+	}, fn)
+}
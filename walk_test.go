@@ -0,0 +1,130 @@
+package triparclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	. "github.com/koofr/go-triparclient"
+)
+
+var _ = Describe("ListRecursive", func() {
+	var ctx context.Context
+	var client *TriparClient
+	var fake *fakeTripar
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client, fake = newFakeTriparClient()
+
+		fake.Mkdir("/root/a/b")
+		fake.Mkdir("/root/c")
+		fake.PutFile("/root/file", "1")
+		fake.PutFile("/root/a/file", "2")
+		fake.PutFile("/root/a/b/file", "3")
+	})
+
+	list := func(opts *ListRecursiveOptions) ([]string, error) {
+		var mx sync.Mutex
+		paths := []string{}
+		err := client.ListRecursive(ctx, "/root", opts, func(entry WalkEntry) error {
+			mx.Lock()
+			defer mx.Unlock()
+			paths = append(paths, entry.Path)
+			return nil
+		})
+		return paths, err
+	}
+
+	It("should list all descendants", func() {
+		paths, err := list(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(ConsistOf(
+			"/root/a",
+			"/root/a/b",
+			"/root/a/b/file",
+			"/root/a/file",
+			"/root/c",
+			"/root/file",
+		))
+	})
+
+	It("should limit depth", func() {
+		paths, err := list(&ListRecursiveOptions{MaxDepth: 1, Concurrency: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(ConsistOf("/root/a", "/root/c", "/root/file"))
+	})
+
+	It("should skip directories", func() {
+		paths := []string{}
+		err := client.ListRecursive(ctx, "/root", nil, func(entry WalkEntry) error {
+			paths = append(paths, entry.Path)
+			if entry.Path == "/root/a" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(ConsistOf("/root/a", "/root/c", "/root/file"))
+	})
+
+	It("should stop on callback error", func() {
+		callbackErr := errors.New("callback error")
+		err := client.ListRecursive(ctx, "/root", nil, func(entry WalkEntry) error {
+			return callbackErr
+		})
+		Expect(err).To(MatchError(callbackErr))
+	})
+
+	It("should fail for a non-existent path", func() {
+		err := client.ListRecursive(ctx, "/nonexistent", nil, func(entry WalkEntry) error {
+			return nil
+		})
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should stat entries concurrently within the walk's limit", func() {
+		var inFlight, maxInFlight, maxStats, stats int32
+		client = newTestClient(funcTransport(func(r *http.Request) (*http.Response, error) {
+			record := func(counter *int32, max *int32) func() {
+				n := atomic.AddInt32(counter, 1)
+				for {
+					m := atomic.LoadInt32(max)
+					if n <= m || atomic.CompareAndSwapInt32(max, m, n) {
+						break
+					}
+				}
+				return func() { atomic.AddInt32(counter, -1) }
+			}
+			defer record(&inFlight, &maxInFlight)()
+			if r.URL.Query().Get("cmd") == "stat" {
+				defer record(&stats, &maxStats)()
+			}
+			time.Sleep(5 * time.Millisecond)
+			return fake.RoundTrip(r)
+		}))
+		for i := 0; i < 20; i++ {
+			fake.PutFile(fmt.Sprintf("/root/c/%02d", i), "x")
+		}
+
+		sizes := []int64{}
+		err := client.ListRecursive(ctx, "/root/c", &ListRecursiveOptions{Concurrency: 4}, func(entry WalkEntry) error {
+			sizes = append(sizes, entry.Entry.Size)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sizes).To(HaveLen(20))
+		Expect(sizes).To(HaveEach(int64(1)))
+
+		Expect(atomic.LoadInt32(&maxStats)).To(BeNumerically(">", 1))
+		Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", 4))
+	})
+})